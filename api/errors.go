@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dhruvthak3r/Probe/internal/validation"
+)
+
+const (
+	ErrCodeInvalidRequest           = "invalid_request"
+	ErrCodeValidationFailed         = "validation_failed"
+	ErrCodeUnknownLocation          = "unknown_location"
+	ErrCodeNotFound                 = "not_found"
+	ErrCodeMonitorNotFound          = "monitor_not_found"
+	ErrCodeHeartbeatNotFound        = "heartbeat_not_found"
+	ErrCodeMaintenanceNotFound      = "maintenance_window_not_found"
+	ErrCodeInvalidMaintenanceWindow = "invalid_maintenance_window"
+	ErrCodeMethodNotAllowed         = "method_not_allowed"
+	ErrCodeUnauthorized             = "unauthorized"
+	ErrCodeForbidden                = "forbidden"
+	ErrCodeAPIKeyNotFound           = "api_key_not_found"
+	ErrCodeOrganizationNotFound     = "organization_not_found"
+	ErrCodeQuotaExceeded            = "quota_exceeded"
+	ErrCodeMemberNotFound           = "member_not_found"
+	ErrCodeMemberExists             = "member_exists"
+	ErrCodeLastOwner                = "last_owner"
+	ErrCodeRevisionNotFound         = "revision_not_found"
+	ErrCodeCheckTimeout             = "check_timeout"
+	ErrCodeMonitorInMaintenance     = "monitor_in_maintenance"
+	ErrCodeInternal                 = "internal_error"
+)
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, ErrorEnvelope{Error: ErrorBody{Code: code, Message: message}})
+}
+
+func writeValidationError(w http.ResponseWriter, errs validation.Errors) {
+	writeJSON(w, http.StatusBadRequest, ErrorEnvelope{Error: ErrorBody{
+		Code:    ErrCodeValidationFailed,
+		Message: "request payload failed validation",
+		Details: errs,
+	}})
+}
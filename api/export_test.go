@@ -0,0 +1,110 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dhruvthak3r/Probe/internal/importer"
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+)
+
+func TestMonitorCurlRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		monitor func(m *MonitorDetail)
+		want    CreateMonitorPayload
+	}{
+		{
+			name: "post with a json body",
+			monitor: func(m *MonitorDetail) {
+				m.HttpMethod = "POST"
+				m.RequestHeaders = map[string][]string{"x-trace": {"a", "b"}}
+				m.RequestBody = ptr(`{"ping": true}`)
+			},
+			want: CreateMonitorPayload{
+				HttpMethod:     "POST",
+				Url:            "https://api.example.com/health",
+				RequestHeaders: map[string][]string{"Content-Type": {"application/json"}, "X-Trace": {"a", "b"}},
+				RequestBody:    `{"ping": true}`,
+			},
+		},
+		{
+			name: "body of a get is not sent",
+			monitor: func(m *MonitorDetail) {
+				m.RequestBody = ptr(`{"ping": true}`)
+			},
+			want: CreateMonitorPayload{HttpMethod: "GET", Url: "https://api.example.com/health"},
+		},
+		{
+			name: "head",
+			monitor: func(m *MonitorDetail) {
+				m.HttpMethod = "HEAD"
+			},
+			want: CreateMonitorPayload{HttpMethod: "HEAD", Url: "https://api.example.com/health"},
+		},
+		{
+			name: "put keeps its content type",
+			monitor: func(m *MonitorDetail) {
+				m.HttpMethod = "PUT"
+				m.RequestHeaders = map[string][]string{"Content-Type": {"text/plain"}}
+				m.RequestBody = ptr("line one\nline two")
+			},
+			want: CreateMonitorPayload{
+				HttpMethod:     "PUT",
+				Url:            "https://api.example.com/health",
+				RequestHeaders: map[string][]string{"Content-Type": {"text/plain"}},
+				RequestBody:    "line one\nline two",
+			},
+		},
+		{
+			name: "quoting, credentials, timeout and insecure",
+			monitor: func(m *MonitorDetail) {
+				m.HttpMethod = "PATCH"
+				m.Url = "https://api.example.com/search?q=it's&lang=en"
+				m.RequestHeaders = map[string][]string{"Authorization": {`Bearer "a b"`}}
+				m.RequestBody = ptr(`{"path": "$HOME", "note": "it's"}`)
+				m.AuthUsername = ptr("probe user")
+				m.HasAuthPassword = true
+				m.SkipTLSVerify = true
+				m.ConnectionTimeout = ptr(int64(10))
+			},
+			want: CreateMonitorPayload{
+				HttpMethod:        "PATCH",
+				Url:               "https://api.example.com/search?q=it's&lang=en",
+				RequestHeaders:    map[string][]string{"Authorization": {`Bearer "a b"`}, "Content-Type": {"application/json"}},
+				RequestBody:       `{"path": "$HOME", "note": "it's"}`,
+				AuthUsername:      "probe user",
+				SkipTLSVerify:     true,
+				ConnectionTimeout: 10,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+			tt.monitor(&m)
+
+			cmd := importer.Curl(monitorRequest(m))
+			req, warnings, err := importer.ParseCurl(cmd)
+			if err != nil {
+				t.Fatalf("ParseCurl(%s) error = %v", cmd, err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("ParseCurl(%s) warnings = %q", cmd, warnings)
+			}
+
+			got := importedPayload(req, ImportOptions{FrequencySecs: 60})
+			want := tt.want
+			want.Name = got.Name
+			want.FrequencySecs = 60
+			want.MonitorType = monitor.MonitorTypeHTTP
+			if len(got.RequestHeaders) == 0 && len(want.RequestHeaders) == 0 {
+				got.RequestHeaders, want.RequestHeaders = nil, nil
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("importedPayload(%s) =\n%+v\nwant\n%+v", cmd, got, want)
+			}
+		})
+	}
+}
@@ -2,43 +2,139 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	db "github.com/dhruvthak3r/Probe/config"
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+	"github.com/dhruvthak3r/Probe/internal/validation"
+	"gopkg.in/yaml.v3"
 )
 
 type App struct {
 	DB *db.DB
+	// AdminAPIKey is accepted with the admin scope without being stored,
+	// so the first real keys can be created.
+	AdminAPIKey string
 }
 
 type CreateMonitorPayload struct {
-	Name                string              `json:"name"`
-	Url                 string              `json:"url"`
-	FrequencySecs       int                 `json:"frequency_secs"`
-	ResponseFormat      string              `json:"response_format"`
-	HttpMethod          string              `json:"http_method"`
-	ConnectionTimeout   int                 `json:"connection_timeout"`
-	RequestHeaders      map[string][]string `json:"request_headers"`
-	ResponseHeaders     map[string][]string `json:"response_headers"`
-	AcceptedStatusCodes []int               `json:"accepted_status_codes"`
-	RequestBody         string              `json:"request_body"`
+	Name                 string              `json:"name"`
+	Url                  string              `json:"url"`
+	FrequencySecs        int                 `json:"frequency_secs"`
+	ResponseFormat       string              `json:"response_format"`
+	HttpMethod           string              `json:"http_method"`
+	ConnectionTimeout    int                 `json:"connection_timeout"`
+	RequestHeaders       map[string][]string `json:"request_headers"`
+	ResponseHeaders      map[string][]string `json:"response_headers"`
+	AcceptedStatusCodes  []int               `json:"accepted_status_codes"`
+	RequestBody          string              `json:"request_body"`
+	MonitorType          string              `json:"monitor_type"`
+	ResponsePattern      string              `json:"response_pattern"`
+	ResponseSchema       string              `json:"response_schema"`
+	TLSMode              string              `json:"tls_mode"`
+	AuthUsername         string              `json:"auth_username"`
+	AuthPassword         string              `json:"auth_password"`
+	SkipTLSVerify        bool                `json:"skip_tls_verify"`
+	RequiredCapabilities []string            `json:"required_capabilities"`
+	ExpectedResult       string              `json:"expected_result"`
+	GraceSeconds         int                 `json:"grace_seconds"`
+	PayloadEncoding      string              `json:"payload_encoding"`
+	Locations            []string            `json:"locations"`
+	QuorumFailures       int                 `json:"quorum_failures"`
+	JitterPercent        int                 `json:"jitter_percent"`
+	CronExpression       string              `json:"cron_expression"`
+	Timezone             string              `json:"timezone"`
+	BusinessHours        *BusinessHours      `json:"business_hours"`
+	OffPeakFrequencySecs int                 `json:"off_peak_frequency_secs"`
+	Tags                 []string            `json:"tags"`
+	Group                string              `json:"group"`
+	Slug                 string              `json:"slug"`
+}
+
+type MaintenanceWindowPayload struct {
+	WindowID       int      `json:"window_id"`
+	Name           string   `json:"name"`
+	Mode           string   `json:"mode"`
+	StartsAt       string   `json:"starts_at"`
+	EndsAt         string   `json:"ends_at"`
+	CronExpression string   `json:"cron_expression"`
+	DurationSecs   int      `json:"duration_secs"`
+	Timezone       string   `json:"timezone"`
+	MonitorIDs     []int    `json:"monitor_ids"`
+	Tags           []string `json:"tags"`
+}
+
+type APIKeyPayload struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at"`
+	UserID    *int64   `json:"user_id"`
+}
+
+type OrganizationPayload struct {
+	Name             string `json:"name"`
+	MaxMonitors      int    `json:"max_monitors"`
+	MinFrequencySecs int    `json:"min_frequency_secs"`
+	OwnerEmail       string `json:"owner_email"`
+	OwnerName        string `json:"owner_name"`
+}
+
+type UpdateOrganizationPayload struct {
+	Name             *string `json:"name,omitempty"`
+	MaxMonitors      *int    `json:"max_monitors,omitempty"`
+	MinFrequencySecs *int    `json:"min_frequency_secs,omitempty"`
+}
+
+type MemberPayload struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+type BusinessHours struct {
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
 }
 
 type UpdateMonitorPayload struct {
-	MonitorID           int                  `json:"monitor_id"`
-	Name                *string              `json:"name,omitempty"`
-	Url                 *string              `json:"url,omitempty"`
-	FrequencySecs       *int                 `json:"frequency_secs,omitempty"`
-	ResponseFormat      *string              `json:"response_format,omitempty"`
-	HttpMethod          *string              `json:"http_method,omitempty"`
-	ConnectionTimeout   *int                 `json:"connection_timeout,omitempty"`
-	RequestHeaders      *map[string][]string `json:"request_headers,omitempty"`
-	ResponseHeaders     *map[string][]string `json:"response_headers,omitempty"`
-	AcceptedStatusCodes *[]int               `json:"accepted_status_codes,omitempty"`
-	RequestBody         *string              `json:"request_body,omitempty"`
+	MonitorID            int                  `json:"monitor_id"`
+	Name                 *string              `json:"name,omitempty"`
+	Url                  *string              `json:"url,omitempty"`
+	FrequencySecs        *int                 `json:"frequency_secs,omitempty"`
+	ResponseFormat       *string              `json:"response_format,omitempty"`
+	HttpMethod           *string              `json:"http_method,omitempty"`
+	ConnectionTimeout    *int                 `json:"connection_timeout,omitempty"`
+	RequestHeaders       *map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders      *map[string][]string `json:"response_headers,omitempty"`
+	AcceptedStatusCodes  *[]int               `json:"accepted_status_codes,omitempty"`
+	RequestBody          *string              `json:"request_body,omitempty"`
+	MonitorType          *string              `json:"monitor_type,omitempty"`
+	ResponsePattern      *string              `json:"response_pattern,omitempty"`
+	ResponseSchema       *string              `json:"response_schema,omitempty"`
+	TLSMode              *string              `json:"tls_mode,omitempty"`
+	AuthUsername         *string              `json:"auth_username,omitempty"`
+	AuthPassword         *string              `json:"auth_password,omitempty"`
+	SkipTLSVerify        *bool                `json:"skip_tls_verify,omitempty"`
+	RequiredCapabilities *[]string            `json:"required_capabilities,omitempty"`
+	ExpectedResult       *string              `json:"expected_result,omitempty"`
+	GraceSeconds         *int                 `json:"grace_seconds,omitempty"`
+	PayloadEncoding      *string              `json:"payload_encoding,omitempty"`
+	Locations            *[]string            `json:"locations,omitempty"`
+	QuorumFailures       *int                 `json:"quorum_failures,omitempty"`
+	JitterPercent        *int                 `json:"jitter_percent,omitempty"`
+	CronExpression       *string              `json:"cron_expression,omitempty"`
+	Timezone             *string              `json:"timezone,omitempty"`
+	BusinessHours        *BusinessHours       `json:"business_hours,omitempty"`
+	OffPeakFrequencySecs *int                 `json:"off_peak_frequency_secs,omitempty"`
+	Tags                 *[]string            `json:"tags,omitempty"`
+	Group                *string              `json:"group,omitempty"`
+	Slug                 *string              `json:"slug,omitempty"`
 }
 
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
@@ -48,44 +144,71 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 func (a *App) CreateMonitorhandler(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var payload CreateMonitorPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
 		return
 	}
 
-	if err := InsertMonitorToDB(r.Context(), a.DB, payload); err != nil {
+	created, err := InsertMonitorToDB(r.Context(), a.DB, orgID(r), payload)
+	if errors.Is(err, ErrQuotaExceeded) {
+		writeError(w, http.StatusForbidden, ErrCodeQuotaExceeded, err.Error())
+		return
+	}
+	if errors.Is(err, ErrUnknownLocation) {
+		writeError(w, http.StatusBadRequest, ErrCodeUnknownLocation, "locations must reference registered agent locations")
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
 		log.Printf("error inserting to db %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
 	}
 
 	log.Println("inserting to db")
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]any{
-		"message": "monitor created successfully",
-	})
+	response := map[string]any{
+		"message":    "monitor created successfully",
+		"monitor_id": created.MonitorID,
+	}
+	if created.HeartbeatPath != "" {
+		response["heartbeat_path"] = created.HeartbeatPath
+	}
+
+	writeJSON(w, http.StatusCreated, response)
 }
 
 func (a *App) UpdateMonitorHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var payload UpdateMonitorPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
 		return
 	}
 
+	if r.PathValue("id") != "" {
+		monitorID, ok := monitorIDFromRequest(w, r)
+		if !ok {
+			return
+		}
+		payload.MonitorID = monitorID
+	}
+
 	if payload.MonitorID <= 0 {
-		http.Error(w, "monitor_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "monitor_id is required")
 		return
 	}
 
@@ -98,84 +221,320 @@ func (a *App) UpdateMonitorHandler(w http.ResponseWriter, r *http.Request) {
 		payload.RequestHeaders == nil &&
 		payload.ResponseHeaders == nil &&
 		payload.AcceptedStatusCodes == nil &&
-		payload.RequestBody == nil {
-		http.Error(w, "no fields provided for update", http.StatusBadRequest)
+		payload.RequestBody == nil &&
+		payload.MonitorType == nil &&
+		payload.ResponsePattern == nil &&
+		payload.ResponseSchema == nil &&
+		payload.TLSMode == nil &&
+		payload.AuthUsername == nil &&
+		payload.AuthPassword == nil &&
+		payload.SkipTLSVerify == nil &&
+		payload.RequiredCapabilities == nil &&
+		payload.ExpectedResult == nil &&
+		payload.GraceSeconds == nil &&
+		payload.PayloadEncoding == nil &&
+		payload.Locations == nil &&
+		payload.QuorumFailures == nil &&
+		payload.JitterPercent == nil &&
+		payload.CronExpression == nil &&
+		payload.Timezone == nil &&
+		payload.BusinessHours == nil &&
+		payload.OffPeakFrequencySecs == nil &&
+		payload.Tags == nil &&
+		payload.Group == nil &&
+		payload.Slug == nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "no fields provided for update")
 		return
 	}
 
-	if err := UpdateMonitorInDB(r.Context(), a.DB, payload); err != nil {
+	err := UpdateMonitorInDB(r.Context(), a.DB, orgID(r), payload)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if errors.Is(err, ErrUnknownLocation) {
+		writeError(w, http.StatusBadRequest, ErrCodeUnknownLocation, "locations must reference registered agent locations")
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
 		log.Printf("error updating monitor %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
 	}
 
-	log.Println("updating to db")
+	m, err := GetMonitor(r.Context(), a.DB, orgID(r), payload.MonitorID)
+	if err != nil {
+		log.Printf("error fetching updated monitor=%d: %v", payload.MonitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]any{
+	writeJSON(w, http.StatusOK, map[string]any{
 		"message": "monitor updated successfully",
+		"monitor": m,
 	})
 
 }
 
 func (a *App) GetAllMonitorsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusBadRequest)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	monitors, err := GetAllMonitors(r.Context(), a.DB)
+	query := r.URL.Query()
+	filter := MonitorFilter{
+		Tags:   query["tag"],
+		Group:  query.Get("group"),
+		Status: query.Get("status"),
+		Search: query.Get("q"),
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if v := query.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "active must be true or false")
+			return
+		}
+		filter.Active = &active
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	monitors, nextCursor, err := GetAllMonitors(r.Context(), a.DB, orgID(r), filter)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
 	if err != nil {
 		log.Printf("error fetching monitors: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	var nextCursorValue any
+	if nextCursor != nil {
+		nextCursorValue = *nextCursor
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"next_cursor": nextCursorValue,
+		"count":       len(monitors),
+		"monitors":    monitors,
+	})
+}
+
+// maxMonitorsFileBytes bounds the size of a synced monitors file.
+const maxMonitorsFileBytes = 10 << 20
+
+func (a *App) ExportMonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "json" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "format must be yaml or json")
+		return
+	}
+
+	file, err := ExportMonitors(r.Context(), a.DB, orgID(r))
+	if err != nil {
+		log.Printf("error exporting monitors: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, http.StatusOK, file)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		log.Printf("error encoding monitors as yaml: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
-		"monitors": monitors,
+	w.Write(data)
+}
+
+func (a *App) SyncMonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var dryRun, prune bool
+
+	if v := query.Get("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+	if v := query.Get("prune"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "prune must be true or false")
+			return
+		}
+		prune = parsed
+	}
+
+	// JSON is valid YAML, so one decoder reads both.
+	var file MonitorsFile
+	dec := yaml.NewDecoder(http.MaxBytesReader(w, r.Body, maxMonitorsFileBytes))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid monitors file: "+err.Error())
+		return
+	}
+
+	plan, err := SyncMonitors(r.Context(), a.DB, orgID(r), &file, dryRun, prune)
+	if errors.Is(err, ErrQuotaExceeded) {
+		writeError(w, http.StatusForbidden, ErrCodeQuotaExceeded, err.Error())
+		return
+	}
+	if errors.Is(err, ErrUnknownLocation) {
+		writeError(w, http.StatusBadRequest, ErrCodeUnknownLocation, "locations must reference registered agent locations")
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
+		log.Printf("error syncing monitors: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (a *App) PreviewImportHandler(w http.ResponseWriter, r *http.Request) {
+	a.importMonitors(w, r, true)
+}
+
+func (a *App) ImportMonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	a.importMonitors(w, r, false)
+}
+
+// importMonitors reads the document to import from the raw request body, in
+// the format given by ?format.
+func (a *App) importMonitors(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	query := r.URL.Query()
+	opts := ImportOptions{
+		Format:        query.Get("format"),
+		FrequencySecs: 60,
+		Group:         query.Get("group"),
+		Tags:          query["tag"],
+		ServerURL:     query.Get("server_url"),
+		Operations:    query["operation"],
+	}
+	if opts.Format == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "format is required")
+		return
+	}
+	if v := query.Get("frequency_secs"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "frequency_secs must be a positive integer")
+			return
+		}
+		opts.FrequencySecs = secs
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMonitorsFileBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := ImportMonitors(r.Context(), a.DB, orgID(r), data, opts, dryRun)
+	if errors.Is(err, ErrQuotaExceeded) {
+		writeError(w, http.StatusForbidden, ErrCodeQuotaExceeded, err.Error())
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
+		log.Printf("error importing monitors: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, result)
+}
+
+func (a *App) GetMonitorGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := GetMonitorGroups(r.Context(), a.DB, orgID(r))
+	if err != nil {
+		log.Printf("error fetching monitor groups: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"groups": groups,
 	})
 }
 
 func (a *App) GetResultsBetweenTimestampsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	monitorIDStr := r.URL.Query().Get("monitor_id")
-	if monitorIDStr == "" {
-		http.Error(w, "monitor_id is required", http.StatusBadRequest)
-		return
-	}
-	monitorID, err := strconv.Atoi(monitorIDStr)
-	if err != nil || monitorID <= 0 {
-		http.Error(w, "monitor_id must be a positive integer", http.StatusBadRequest)
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
 	fromTSStr := r.URL.Query().Get("from_ts")
 	toTSStr := r.URL.Query().Get("to_ts")
 	if fromTSStr == "" || toTSStr == "" {
-		http.Error(w, "from_ts and to_ts are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts and to_ts are required")
 		return
 	}
 
 	fromTS, err := parseTimestamp(fromTSStr)
 	if err != nil {
-		http.Error(w, "from_ts must be unix seconds or RFC3339", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be unix seconds or RFC3339")
 		return
 	}
 
 	toTS, err := parseTimestamp(toTSStr)
 	if err != nil {
-		http.Error(w, "to_ts must be unix seconds or RFC3339", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "to_ts must be unix seconds or RFC3339")
 		return
 	}
 
 	if fromTS.After(toTS) {
-		http.Error(w, "from_ts must be before or equal to to_ts", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be before or equal to to_ts")
 		return
 	}
 
@@ -184,7 +543,7 @@ func (a *App) GetResultsBetweenTimestampsHandler(w http.ResponseWriter, r *http.
 	if limitStr != "" {
 		parsedLimit, err := strconv.Atoi(limitStr)
 		if err != nil || parsedLimit <= 0 {
-			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "limit must be a positive integer")
 			return
 		}
 		limit = parsedLimit
@@ -195,16 +554,18 @@ func (a *App) GetResultsBetweenTimestampsHandler(w http.ResponseWriter, r *http.
 	if cursorStr != "" {
 		parsedCursor, err := strconv.ParseInt(cursorStr, 10, 64)
 		if err != nil || parsedCursor <= 0 {
-			http.Error(w, "cursor must be a positive integer", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "cursor must be a positive integer")
 			return
 		}
 		cursor = parsedCursor
 	}
 
-	results, nextCursor, err := GetResultsBetweenTimestamps(r.Context(), a.DB, monitorID, fromTS, toTS, cursor, limit)
+	location := r.URL.Query().Get("location")
+
+	results, nextCursor, err := GetResultsBetweenTimestamps(r.Context(), a.DB, orgID(r), monitorID, location, fromTS, toTS, cursor, limit)
 	if err != nil {
 		log.Printf("error fetching results for monitor_id=%d from %s to %s: %v", monitorID, fromTS.UTC().Format(time.RFC3339), toTS.UTC().Format(time.RFC3339), err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
 		return
 	}
 
@@ -213,10 +574,9 @@ func (a *App) GetResultsBetweenTimestampsHandler(w http.ResponseWriter, r *http.
 		nextCursorValue = *nextCursor
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	writeJSON(w, http.StatusOK, map[string]any{
 		"monitor_id":  monitorID,
+		"location":    location,
 		"from_ts":     fromTS.UTC().Format(time.RFC3339),
 		"to_ts":       toTS.UTC().Format(time.RFC3339),
 		"limit":       limit,
@@ -229,56 +589,51 @@ func (a *App) GetResultsBetweenTimestampsHandler(w http.ResponseWriter, r *http.
 
 func (a *App) GetMetricsBetweenTimestampsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	monitorIDStr := r.URL.Query().Get("monitor_id")
-	if monitorIDStr == "" {
-		http.Error(w, "monitor_id is required", http.StatusBadRequest)
-		return
-	}
-	monitorID, err := strconv.Atoi(monitorIDStr)
-	if err != nil || monitorID <= 0 {
-		http.Error(w, "monitor_id must be a positive integer", http.StatusBadRequest)
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
 	fromTSStr := r.URL.Query().Get("from_ts")
 	toTSStr := r.URL.Query().Get("to_ts")
 	if fromTSStr == "" || toTSStr == "" {
-		http.Error(w, "from_ts and to_ts are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts and to_ts are required")
 		return
 	}
 
 	fromTS, err := parseTimestamp(fromTSStr)
 	if err != nil {
-		http.Error(w, "from_ts must be unix seconds or RFC3339", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be unix seconds or RFC3339")
 		return
 	}
 
 	toTS, err := parseTimestamp(toTSStr)
 	if err != nil {
-		http.Error(w, "to_ts must be unix seconds or RFC3339", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "to_ts must be unix seconds or RFC3339")
 		return
 	}
 
 	if fromTS.After(toTS) {
-		http.Error(w, "from_ts must be before or equal to to_ts", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be before or equal to to_ts")
 		return
 	}
 
-	metrics, err := GetMetricsBetweenTimestamps(r.Context(), a.DB, monitorID, fromTS, toTS)
+	location := r.URL.Query().Get("location")
+
+	metrics, err := GetMetricsBetweenTimestamps(r.Context(), a.DB, orgID(r), monitorID, location, fromTS, toTS)
 	if err != nil {
 		log.Printf("error fetching metrics for monitor_id=%d from %s to %s: %v", monitorID, fromTS.UTC().Format(time.RFC3339), toTS.UTC().Format(time.RFC3339), err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	writeJSON(w, http.StatusOK, map[string]any{
 		"monitor_id": monitorID,
+		"location":   location,
 		"from_ts":    fromTS.UTC().Format(time.RFC3339),
 		"to_ts":      toTS.UTC().Format(time.RFC3339),
 		"count":      len(metrics),
@@ -286,33 +641,914 @@ func (a *App) GetMetricsBetweenTimestampsHandler(w http.ResponseWriter, r *http.
 	})
 }
 
-func (a *App) SuspendMonitorHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+func (a *App) GetVerdictsBetweenTimestampsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	monitorIDStr := r.URL.Query().Get("monitor_id")
-	if monitorIDStr == "" {
-		http.Error(w, "monitor_id is required", http.StatusBadRequest)
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
 		return
 	}
-	monitorID, err := strconv.Atoi(monitorIDStr)
-	if err != nil || monitorID <= 0 {
-		http.Error(w, "monitor_id must be a positive integer", http.StatusBadRequest)
+
+	fromTSStr := r.URL.Query().Get("from_ts")
+	toTSStr := r.URL.Query().Get("to_ts")
+	if fromTSStr == "" || toTSStr == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts and to_ts are required")
+		return
+	}
+
+	fromTS, err := parseTimestamp(fromTSStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be unix seconds or RFC3339")
+		return
+	}
+
+	toTS, err := parseTimestamp(toTSStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "to_ts must be unix seconds or RFC3339")
+		return
+	}
+
+	if fromTS.After(toTS) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "from_ts must be before or equal to to_ts")
+		return
+	}
+
+	verdicts, err := GetVerdictsBetweenTimestamps(r.Context(), a.DB, orgID(r), monitorID, fromTS, toTS)
+	if err != nil {
+		log.Printf("error fetching verdicts for monitor_id=%d from %s to %s: %v", monitorID, fromTS.UTC().Format(time.RFC3339), toTS.UTC().Format(time.RFC3339), err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor_id": monitorID,
+		"from_ts":    fromTS.UTC().Format(time.RFC3339),
+		"to_ts":      toTS.UTC().Format(time.RFC3339),
+		"count":      len(verdicts),
+		"verdicts":   verdicts,
+	})
+}
+
+func (a *App) GetLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	locations, err := GetLocations(r.Context(), a.DB)
+	if err != nil {
+		log.Printf("error fetching locations: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"locations": locations,
+	})
+}
+
+func (a *App) SuspendMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete && r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	err = SuspendMonitor(r.Context(), a.DB, monitorID)
+	err := SuspendMonitor(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
 	if err != nil {
-		log.Printf("error suspending monitor=%d,err=%d", monitorID, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		log.Printf("error suspending monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
-	json.NewEncoder(w).Encode(map[string]any{
-		"message": "monitor suspended successfully",
+}
+
+func (a *App) HeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	event := monitor.HeartbeatSuccess
+	switch r.PathValue("event") {
+	case "":
+	case monitor.HeartbeatStart:
+		event = monitor.HeartbeatStart
+	case monitor.HeartbeatFail:
+		event = monitor.HeartbeatFail
+	default:
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "unknown heartbeat event")
+		return
+	}
+
+	err := monitor.RecordHeartbeat(r.Context(), a.DB, r.PathValue("token"), event)
+	if errors.Is(err, monitor.ErrHeartbeatNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeHeartbeatNotFound, "heartbeat not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error recording heartbeat event=%s: %v", event, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "heartbeat recorded",
+	})
+}
+
+func (a *App) GetSchedulerLeaderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	leader, err := GetSchedulerLeader(r.Context(), a.DB)
+	if err != nil {
+		log.Printf("error fetching scheduler leader: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"leader": leader,
+	})
+}
+
+func (a *App) GetSchedulerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	window := 60
+	if v := r.URL.Query().Get("window"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > 3600 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "window must be between 1 and 3600 seconds")
+			return
+		}
+		window = parsed
+	}
+
+	stats, err := GetSchedulerStats(r.Context(), a.DB, orgID(r), window)
+	if err != nil {
+		log.Printf("error fetching scheduler stats: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *App) CreateMaintenanceWindowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload MaintenanceWindowPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	windowID, err := InsertMaintenanceWindow(r.Context(), a.DB, orgID(r), payload)
+	if errors.Is(err, ErrInvalidMaintenanceWindow) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidMaintenanceWindow, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("error inserting maintenance window: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"message":   "maintenance window created successfully",
+		"window_id": windowID,
 	})
 }
+
+func (a *App) GetMaintenanceWindowsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	windows, err := GetMaintenanceWindows(r.Context(), a.DB, orgID(r))
+	if err != nil {
+		log.Printf("error fetching maintenance windows: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"maintenance_windows": windows,
+	})
+}
+
+func (a *App) UpdateMaintenanceWindowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload MaintenanceWindowPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	if id := r.PathValue("id"); id != "" {
+		windowID, err := strconv.Atoi(id)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "window id must be a positive integer")
+			return
+		}
+		payload.WindowID = windowID
+	}
+
+	if payload.WindowID <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "window_id is required")
+		return
+	}
+
+	err := UpdateMaintenanceWindow(r.Context(), a.DB, orgID(r), payload)
+	if errors.Is(err, ErrInvalidMaintenanceWindow) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidMaintenanceWindow, err.Error())
+		return
+	}
+	if errors.Is(err, ErrMaintenanceWindowNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMaintenanceNotFound, "maintenance window not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error updating maintenance window: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "maintenance window updated successfully",
+	})
+}
+
+func (a *App) DeleteMaintenanceWindowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	windowID, err := strconv.Atoi(idParam(r, "window_id"))
+	if err != nil || windowID <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "window_id must be a positive integer")
+		return
+	}
+
+	err = DeleteMaintenanceWindow(r.Context(), a.DB, orgID(r), windowID)
+	if errors.Is(err, ErrMaintenanceWindowNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMaintenanceNotFound, "maintenance window not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting maintenance window=%d: %v", windowID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload APIKeyPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	created, key, err := InsertAPIKey(r.Context(), a.DB, orgID(r), payload)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
+		log.Printf("error inserting api key: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"message": "api key created, store it now as it can not be shown again",
+		"key":     key,
+		"api_key": created,
+	})
+}
+
+func (a *App) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := GetAPIKeys(r.Context(), a.DB, orgID(r))
+	if err != nil {
+		log.Printf("error fetching api keys: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"api_keys": keys,
+	})
+}
+
+func (a *App) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || keyID <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "key id must be a positive integer")
+		return
+	}
+
+	err = DeleteAPIKey(r.Context(), a.DB, orgID(r), keyID)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeAPIKeyNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting api key=%d: %v", keyID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload OrganizationPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	org, key, err := InsertOrganization(r.Context(), a.DB, payload)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
+		log.Printf("error inserting organization: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"message":      "organization created, store the owner key now as it can not be shown again",
+		"organization": org,
+		"owner_key":    key,
+	})
+}
+
+func (a *App) GetOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	orgs, err := GetOrganizations(r.Context(), a.DB)
+	if err != nil {
+		log.Printf("error fetching organizations: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"organizations": orgs,
+	})
+}
+
+func (a *App) UpdateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "org id must be a positive integer")
+		return
+	}
+
+	var payload UpdateOrganizationPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	err = UpdateOrganization(r.Context(), a.DB, id, payload)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if errors.Is(err, ErrOrganizationNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeOrganizationNotFound, "organization not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error updating org_id=%d: %v", id, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "organization updated successfully",
+	})
+}
+
+func (a *App) GetCurrentOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	org, err := GetOrganization(r.Context(), a.DB, orgID(r))
+	if errors.Is(err, ErrOrganizationNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeOrganizationNotFound, "organization not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error fetching organization: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, org)
+}
+
+func (a *App) GetMembersHandler(w http.ResponseWriter, r *http.Request) {
+	members, err := GetMembers(r.Context(), a.DB, orgID(r))
+	if err != nil {
+		log.Printf("error fetching members: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"members": members,
+	})
+}
+
+func (a *App) AddMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MemberPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	userID, err := AddMember(r.Context(), a.DB, orgID(r), payload)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if errors.Is(err, ErrMemberExists) {
+		writeError(w, http.StatusConflict, ErrCodeMemberExists, "user is already a member of the organization")
+		return
+	}
+	if err != nil {
+		log.Printf("error adding member: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"message": "member added successfully",
+		"user_id": userID,
+	})
+}
+
+func memberIDFromRequest(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "user id must be a positive integer")
+		return 0, false
+	}
+	return userID, true
+}
+
+func writeMemberError(w http.ResponseWriter, err error) {
+	var verrs validation.Errors
+	switch {
+	case errors.As(err, &verrs):
+		writeValidationError(w, verrs)
+	case errors.Is(err, ErrMemberNotFound):
+		writeError(w, http.StatusNotFound, ErrCodeMemberNotFound, "member not found")
+	case errors.Is(err, ErrLastOwner):
+		writeError(w, http.StatusConflict, ErrCodeLastOwner, "organization needs at least one owner")
+	default:
+		log.Printf("error changing membership: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+	}
+}
+
+func (a *App) UpdateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := memberIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Role string `json:"role"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	if err := UpdateMemberRole(r.Context(), a.DB, orgID(r), userID, payload.Role); err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "member updated successfully",
+	})
+}
+
+func (a *App) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := memberIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := RemoveMember(r.Context(), a.DB, orgID(r), userID); err != nil {
+		writeMemberError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeJSON rejects unknown fields, so a misspelled option is reported
+// instead of silently falling back to its default.
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// idParam reads the {id} path segment of versioned routes, falling back to
+// the query parameter used by the legacy routes.
+func idParam(r *http.Request, queryKey string) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get(queryKey)
+}
+
+func monitorIDFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	monitorIDStr := idParam(r, "monitor_id")
+	if monitorIDStr == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "monitor_id is required")
+		return 0, false
+	}
+	monitorID, err := strconv.Atoi(monitorIDStr)
+	if err != nil || monitorID <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "monitor_id must be a positive integer")
+		return 0, false
+	}
+	return monitorID, true
+}
+
+func (a *App) GetMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	m, err := GetMonitor(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error fetching monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor": m,
+	})
+}
+
+func (a *App) GetMonitorCurlHandler(w http.ResponseWriter, r *http.Request) {
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	command, err := MonitorCurl(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if errors.Is(err, ErrNotHTTPMonitor) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("error exporting monitor=%d as curl: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor_id": monitorID,
+		"command":    command,
+	})
+}
+
+func (a *App) ResumeMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := ResumeMonitor(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error resuming monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "monitor resumed successfully",
+	})
+}
+
+func (a *App) DeleteMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := DeleteMonitor(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AuditFilter{ResourceType: query.Get("resource_type"), Limit: 20}
+
+	if v := query.Get("resource_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "resource_id must be a positive integer")
+			return
+		}
+		filter.ResourceID = id
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "cursor must be a positive integer")
+			return
+		}
+		filter.Cursor = cursor
+	}
+
+	entries, nextCursor, err := GetAuditLog(r.Context(), a.DB, orgID(r), filter)
+	if err != nil {
+		log.Printf("error fetching audit log: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	var nextCursorValue any
+	if nextCursor != nil {
+		nextCursorValue = *nextCursor
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"limit":       filter.Limit,
+		"next_cursor": nextCursorValue,
+		"count":       len(entries),
+		"entries":     entries,
+	})
+}
+
+func revisionFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	revision, err := strconv.Atoi(r.PathValue("revision"))
+	if err != nil || revision <= 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "revision must be a positive integer")
+		return 0, false
+	}
+	return revision, true
+}
+
+func (a *App) GetMonitorRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := GetMonitorRevisions(r.Context(), a.DB, orgID(r), monitorID)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error fetching revisions for monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor_id": monitorID,
+		"revisions":  revisions,
+	})
+}
+
+func (a *App) GetMonitorRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	revision, ok := revisionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rev, err := GetMonitorRevision(r.Context(), a.DB, orgID(r), monitorID, revision)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if errors.Is(err, ErrRevisionNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeRevisionNotFound, "revision not found")
+		return
+	}
+	if err != nil {
+		log.Printf("error fetching revision %d of monitor=%d: %v", revision, monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rev)
+}
+
+func (a *App) RollbackMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	revision, ok := revisionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := RollbackMonitor(r.Context(), a.DB, orgID(r), monitorID, revision)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if errors.Is(err, ErrRevisionNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeRevisionNotFound, "revision not found")
+		return
+	}
+	if errors.Is(err, ErrUnknownLocation) {
+		writeError(w, http.StatusConflict, ErrCodeUnknownLocation, "the revision references locations that are no longer registered")
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if err != nil {
+		log.Printf("error rolling back monitor=%d to revision %d: %v", monitorID, revision, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":  "monitor rolled back successfully",
+		"revision": revision,
+	})
+}
+
+func (a *App) RunMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	monitorID, ok := monitorIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var persist bool
+	if v := r.URL.Query().Get("persist"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "persist must be true or false")
+			return
+		}
+		persist = parsed
+	}
+
+	result, err := RunMonitor(r.Context(), a.DB, orgID(r), monitorID, persist)
+	if errors.Is(err, ErrMonitorNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeMonitorNotFound, "monitor not found")
+		return
+	}
+	if errors.Is(err, ErrMonitorPaused) {
+		writeError(w, http.StatusConflict, ErrCodeMonitorInMaintenance, err.Error())
+		return
+	}
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if writeCheckError(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("error running monitor=%d: %v", monitorID, err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *App) TestMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateMonitorPayload
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid request payload: "+err.Error())
+		return
+	}
+
+	result, err := TestMonitor(r.Context(), payload)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		writeValidationError(w, verrs)
+		return
+	}
+	if writeCheckError(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("error testing monitor: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeCheckError writes the response for errors shared by checks run from
+// the API and reports whether it did.
+func writeCheckError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, ErrHeartbeatNotRunnable):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+	case errors.Is(err, ErrCheckTimedOut):
+		writeError(w, http.StatusGatewayTimeout, ErrCodeCheckTimeout, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -1,13 +1,31 @@
 package api
 
-import "net/http"
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// EnableCORS only answers cross-origin requests from the given origins; "*"
+// allows every origin.
+func EnableCORS(allowedOrigins []string, next http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
 
-func EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
 
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if origin != "" && (allowed[origin] || allowed["*"]) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		}
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -16,3 +34,68 @@ func EnableCORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+type apiKeyContextKey struct{}
+
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+// orgID is the organization every query of an authenticated request is
+// scoped to.
+func orgID(r *http.Request) int64 {
+	if key := APIKeyFromContext(r.Context()); key != nil {
+		return key.OrgID
+	}
+	return 0
+}
+
+func presentedAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(auth)
+	}
+	return ""
+}
+
+func (a *App) authenticate(ctx context.Context, key string) (*APIKey, error) {
+	if a.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(HashAPIKey(a.AdminAPIKey))) == 1 {
+		return &APIKey{OrgID: DefaultOrgID, Name: "bootstrap", Prefix: displayPrefix(key), Scopes: []string{ScopePlatform}}, nil
+	}
+	return AuthenticateAPIKey(ctx, a.DB, key)
+}
+
+// RequireScope rejects requests without a valid API key granting scope and
+// stores the key in the request context for the handler.
+func (a *App) RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		presented := presentedAPIKey(r)
+		if presented == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="probe"`)
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "api key required")
+			return
+		}
+
+		key, err := a.authenticate(r.Context(), presented)
+		if errors.Is(err, ErrInvalidAPIKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="probe", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid or expired api key")
+			return
+		}
+		if err != nil {
+			log.Printf("error authenticating api key: %v", err)
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+			return
+		}
+
+		if !key.Allows(scope) {
+			writeError(w, http.StatusForbidden, ErrCodeForbidden, "api key lacks the "+scope+" scope")
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	}
+}
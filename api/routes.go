@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+const APIPrefix = "/api/v1"
+
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// NewRouter requires an API key with the read scope for lookups, write for
+// changes, admin for managing keys and members, and platform for managing
+// organizations. Heartbeat pings stay open, as cron jobs only know their
+// token.
+func NewRouter(a *App) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	read := func(h http.HandlerFunc) http.HandlerFunc { return a.RequireScope(ScopeRead, h) }
+	write := func(h http.HandlerFunc) http.HandlerFunc { return a.RequireScope(ScopeWrite, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return a.RequireScope(ScopeAdmin, h) }
+	platform := func(h http.HandlerFunc) http.HandlerFunc { return a.RequireScope(ScopePlatform, h) }
+
+	mux.HandleFunc("GET "+APIPrefix+"/monitors", read(a.GetAllMonitorsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors", write(a.CreateMonitorhandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitor-groups", read(a.GetMonitorGroupsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/export", read(a.ExportMonitorsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/sync", write(a.SyncMonitorsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/import/preview", read(a.PreviewImportHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/import", write(a.ImportMonitorsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/test", write(a.TestMonitorHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}", read(a.GetMonitorHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/curl", read(a.GetMonitorCurlHandler))
+	mux.HandleFunc("PATCH "+APIPrefix+"/monitors/{id}", write(a.UpdateMonitorHandler))
+	mux.HandleFunc("DELETE "+APIPrefix+"/monitors/{id}", write(a.DeleteMonitorHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/{id}/suspend", write(a.SuspendMonitorHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/{id}/resume", write(a.ResumeMonitorHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/{id}/run", write(a.RunMonitorHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/results", read(a.GetResultsBetweenTimestampsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/metrics", read(a.GetMetricsBetweenTimestampsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/verdicts", read(a.GetVerdictsBetweenTimestampsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/revisions", read(a.GetMonitorRevisionsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/monitors/{id}/revisions/{revision}", read(a.GetMonitorRevisionHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/monitors/{id}/revisions/{revision}/rollback", write(a.RollbackMonitorHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/locations", read(a.GetLocationsHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/scheduler/leader", read(a.GetSchedulerLeaderHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/scheduler/stats", read(a.GetSchedulerStatsHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/maintenance-windows", read(a.GetMaintenanceWindowsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/maintenance-windows", write(a.CreateMaintenanceWindowHandler))
+	mux.HandleFunc("PUT "+APIPrefix+"/maintenance-windows/{id}", write(a.UpdateMaintenanceWindowHandler))
+	mux.HandleFunc("DELETE "+APIPrefix+"/maintenance-windows/{id}", write(a.DeleteMaintenanceWindowHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/api-keys", admin(a.GetAPIKeysHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/api-keys", admin(a.CreateAPIKeyHandler))
+	mux.HandleFunc("DELETE "+APIPrefix+"/api-keys/{id}", admin(a.DeleteAPIKeyHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/audit-log", admin(a.GetAuditLogHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/org", read(a.GetCurrentOrganizationHandler))
+	mux.HandleFunc("GET "+APIPrefix+"/members", read(a.GetMembersHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/members", admin(a.AddMemberHandler))
+	mux.HandleFunc("PATCH "+APIPrefix+"/members/{id}", admin(a.UpdateMemberHandler))
+	mux.HandleFunc("DELETE "+APIPrefix+"/members/{id}", admin(a.RemoveMemberHandler))
+
+	mux.HandleFunc("GET "+APIPrefix+"/orgs", platform(a.GetOrganizationsHandler))
+	mux.HandleFunc("POST "+APIPrefix+"/orgs", platform(a.CreateOrganizationHandler))
+	mux.HandleFunc("PATCH "+APIPrefix+"/orgs/{id}", platform(a.UpdateOrganizationHandler))
+
+	mux.HandleFunc(APIPrefix+"/", notFoundOrMethodNotAllowed(mux))
+
+	mux.HandleFunc("/heartbeat/{token}", a.HeartbeatHandler)
+	mux.HandleFunc("/heartbeat/{token}/{event}", a.HeartbeatHandler)
+
+	mux.HandleFunc("/create-monitor", deprecated(APIPrefix+"/monitors", write(a.CreateMonitorhandler)))
+	mux.HandleFunc("/update-monitor", deprecated(APIPrefix+"/monitors/{id}", write(a.UpdateMonitorHandler)))
+	mux.HandleFunc("/suspend-monitor", deprecated(APIPrefix+"/monitors/{id}/suspend", write(a.SuspendMonitorHandler)))
+	mux.HandleFunc("/resume-monitor", deprecated(APIPrefix+"/monitors/{id}/resume", write(a.ResumeMonitorHandler)))
+	mux.HandleFunc("/delete-monitor", deprecated(APIPrefix+"/monitors/{id}", write(a.DeleteMonitorHandler)))
+	mux.HandleFunc("/get-monitor", deprecated(APIPrefix+"/monitors/{id}", read(a.GetMonitorHandler)))
+	mux.HandleFunc("/get-all-monitors", deprecated(APIPrefix+"/monitors", read(a.GetAllMonitorsHandler)))
+	mux.HandleFunc("/get-results", deprecated(APIPrefix+"/monitors/{id}/results", read(a.GetResultsBetweenTimestampsHandler)))
+	mux.HandleFunc("/get-metrics", deprecated(APIPrefix+"/monitors/{id}/metrics", read(a.GetMetricsBetweenTimestampsHandler)))
+	mux.HandleFunc("/get-verdicts", deprecated(APIPrefix+"/monitors/{id}/verdicts", read(a.GetVerdictsBetweenTimestampsHandler)))
+	mux.HandleFunc("/get-locations", deprecated(APIPrefix+"/locations", read(a.GetLocationsHandler)))
+	mux.HandleFunc("/get-scheduler-leader", deprecated(APIPrefix+"/scheduler/leader", read(a.GetSchedulerLeaderHandler)))
+	mux.HandleFunc("/get-scheduler-stats", deprecated(APIPrefix+"/scheduler/stats", read(a.GetSchedulerStatsHandler)))
+	mux.HandleFunc("/create-maintenance-window", deprecated(APIPrefix+"/maintenance-windows", write(a.CreateMaintenanceWindowHandler)))
+	mux.HandleFunc("/get-maintenance-windows", deprecated(APIPrefix+"/maintenance-windows", read(a.GetMaintenanceWindowsHandler)))
+	mux.HandleFunc("/update-maintenance-window", deprecated(APIPrefix+"/maintenance-windows/{id}", write(a.UpdateMaintenanceWindowHandler)))
+	mux.HandleFunc("/delete-maintenance-window", deprecated(APIPrefix+"/maintenance-windows/{id}", write(a.DeleteMaintenanceWindowHandler)))
+
+	mux.HandleFunc("/{$}", HomeHandler)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "route not found")
+	})
+
+	return mux
+}
+
+// deprecated marks a legacy verb-style route, pointing clients at the
+// versioned resource that replaces it.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// notFoundOrMethodNotAllowed answers requests under the API prefix that no
+// versioned route matched, telling a wrong method apart from an unknown path.
+func notFoundOrMethodNotAllowed(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+
+		for _, method := range routeMethods {
+			if method == r.Method {
+				continue
+			}
+
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); strings.HasPrefix(pattern, method+" ") {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "route not found")
+	}
+}
@@ -1,20 +1,70 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dhruvthak3r/Probe/config"
+	"github.com/dhruvthak3r/Probe/internal/importer"
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+	resultq "github.com/dhruvthak3r/Probe/internal/mq"
+	"github.com/dhruvthak3r/Probe/internal/validation"
 )
 
+type CreatedMonitor struct {
+	MonitorID     int64  `json:"monitor_id"`
+	HeartbeatPath string `json:"heartbeat_path,omitempty"`
+}
+
+type Location struct {
+	LocationID   int     `json:"location_id"`
+	Name         string  `json:"name"`
+	RegisteredAt string  `json:"registered_at"`
+	LastSeenAt   *string `json:"last_seen_at"`
+}
+
+type MonitorVerdict struct {
+	VerdictID         int64  `json:"verdict_id"`
+	MonitorID         int    `json:"monitor_id"`
+	CycleID           string `json:"cycle_id"`
+	Status            string `json:"status"`
+	FailedLocations   int    `json:"failed_locations"`
+	ReportedLocations int    `json:"reported_locations"`
+	TotalLocations    int    `json:"total_locations"`
+	Quorum            int    `json:"quorum"`
+	Reason            string `json:"reason"`
+	DecidedAt         string `json:"decided_at"`
+}
+
+var ErrUnknownLocation = errors.New("unknown location")
+
 type MonitorSummary struct {
-	MonitorID   int    `json:"monitor_id"`
-	MonitorName string `json:"monitor_name"`
-	Url         string `json:"url"`
+	MonitorID     int      `json:"monitor_id"`
+	Slug          string   `json:"slug"`
+	MonitorName   string   `json:"monitor_name"`
+	Url           string   `json:"url"`
+	MonitorType   string   `json:"monitor_type"`
+	Group         string   `json:"group"`
+	Tags          []string `json:"tags"`
+	IsActive      bool     `json:"is_active"`
+	CurrentStatus *string  `json:"current_status"`
+	LastCheckedAt *string  `json:"last_checked_at"`
 }
 
 type MonitorResult struct {
@@ -29,8 +79,12 @@ type MonitorResult struct {
 	FirstByteTime    int64   `json:"first_byte_time"`
 	DownloadTime     int64   `json:"download_time"`
 	ResponseTime     int64   `json:"response_time"`
+	HandshakeTime    int64   `json:"handshake_time"`
+	RoundTripTime    int64   `json:"round_trip_time"`
 	Throughput       float64 `json:"throughput"`
 	Reason           string  `json:"reason"`
+	Location         string  `json:"location"`
+	InMaintenance    bool    `json:"in_maintenance"`
 	CreatedAt        string  `json:"created_at"`
 }
 
@@ -42,7 +96,10 @@ type MonitorMetrics struct {
 	FirstByteTime    int64   `json:"first_byte_time"`
 	DownloadTime     int64   `json:"download_time"`
 	ResponseTime     int64   `json:"response_time"`
+	HandshakeTime    int64   `json:"handshake_time"`
+	RoundTripTime    int64   `json:"round_trip_time"`
 	Throughput       float64 `json:"throughput"`
+	Location         string  `json:"location"`
 	CreatedAt        string  `json:"created_at"`
 }
 
@@ -70,34 +127,229 @@ func parseTimestamp(ts string) (time.Time, error) {
 	return time.Time{}, err
 }
 
-func InsertMonitorToDB(ctx context.Context, db *config.DB, payload CreateMonitorPayload) error {
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (hours *BusinessHours) validationFields() *validation.BusinessHours {
+	if hours == nil {
+		return nil
+	}
+	return &validation.BusinessHours{Days: hours.Days, Start: hours.Start, End: hours.End}
+}
+
+// validationFields leaves out empty enum fields, which fall back to their
+// defaults on insert.
+func (p *CreateMonitorPayload) validationFields() validation.Monitor {
+	return validation.Monitor{
+		Name:                 &p.Name,
+		Url:                  &p.Url,
+		FrequencySecs:        &p.FrequencySecs,
+		ResponseFormat:       optionalString(p.ResponseFormat),
+		HttpMethod:           optionalString(p.HttpMethod),
+		ConnectionTimeout:    &p.ConnectionTimeout,
+		RequestHeaders:       &p.RequestHeaders,
+		ResponseHeaders:      &p.ResponseHeaders,
+		AcceptedStatusCodes:  &p.AcceptedStatusCodes,
+		RequestBody:          &p.RequestBody,
+		MonitorType:          optionalString(p.MonitorType),
+		ResponsePattern:      &p.ResponsePattern,
+		ResponseSchema:       &p.ResponseSchema,
+		TLSMode:              optionalString(p.TLSMode),
+		AuthUsername:         &p.AuthUsername,
+		AuthPassword:         &p.AuthPassword,
+		RequiredCapabilities: &p.RequiredCapabilities,
+		ExpectedResult:       &p.ExpectedResult,
+		GraceSeconds:         &p.GraceSeconds,
+		PayloadEncoding:      optionalString(p.PayloadEncoding),
+		Locations:            &p.Locations,
+		QuorumFailures:       &p.QuorumFailures,
+		JitterPercent:        &p.JitterPercent,
+		CronExpression:       &p.CronExpression,
+		Timezone:             &p.Timezone,
+		BusinessHours:        p.BusinessHours.validationFields(),
+		OffPeakFrequencySecs: &p.OffPeakFrequencySecs,
+		Tags:                 &p.Tags,
+		Group:                &p.Group,
+		Slug:                 &p.Slug,
+	}
+}
+
+func (p *UpdateMonitorPayload) validationFields() validation.Monitor {
+	return validation.Monitor{
+		Name:                 p.Name,
+		Url:                  p.Url,
+		FrequencySecs:        p.FrequencySecs,
+		ResponseFormat:       p.ResponseFormat,
+		HttpMethod:           p.HttpMethod,
+		ConnectionTimeout:    p.ConnectionTimeout,
+		RequestHeaders:       p.RequestHeaders,
+		ResponseHeaders:      p.ResponseHeaders,
+		AcceptedStatusCodes:  p.AcceptedStatusCodes,
+		RequestBody:          p.RequestBody,
+		MonitorType:          p.MonitorType,
+		ResponsePattern:      p.ResponsePattern,
+		ResponseSchema:       p.ResponseSchema,
+		TLSMode:              p.TLSMode,
+		AuthUsername:         p.AuthUsername,
+		AuthPassword:         p.AuthPassword,
+		RequiredCapabilities: p.RequiredCapabilities,
+		ExpectedResult:       p.ExpectedResult,
+		GraceSeconds:         p.GraceSeconds,
+		PayloadEncoding:      p.PayloadEncoding,
+		Locations:            p.Locations,
+		QuorumFailures:       p.QuorumFailures,
+		JitterPercent:        p.JitterPercent,
+		CronExpression:       p.CronExpression,
+		Timezone:             p.Timezone,
+		BusinessHours:        p.BusinessHours.validationFields(),
+		OffPeakFrequencySecs: p.OffPeakFrequencySecs,
+		Tags:                 p.Tags,
+		Group:                p.Group,
+		Slug:                 p.Slug,
+	}
+}
+
+func businessHoursColumns(hours *BusinessHours) (sql.NullString, sql.NullString, sql.NullString) {
+	if hours == nil || len(hours.Days) == 0 {
+		return sql.NullString{}, sql.NullString{}, sql.NullString{}
+	}
+	return nullString(strings.ToLower(strings.Join(hours.Days, ","))), nullString(hours.Start), nullString(hours.End)
+}
+
+func nullPositiveInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v > 0}
+}
+
+func InsertMonitorToDB(ctx context.Context, db *config.DB, orgID int64, payload CreateMonitorPayload) (*CreatedMonitor, error) {
+
+	if err := validation.ValidateMonitor(payload.validationFields(), false); err != nil {
+		return nil, err
+	}
 
 	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %v\n", err)
+		return nil, fmt.Errorf("error starting transaction: %v\n", err)
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO monitor (monitor_name,url,frequency_seconds,response_format,http_method,connection_timeout,request_body) VALUES (?,?,?,?,?,?,?)`
+	quota, err := lockOrgQuota(ctx, tx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := quota.checkMonitorCount(ctx, tx, orgID); err != nil {
+		return nil, err
+	}
+	if err := quota.checkFrequency(&payload.FrequencySecs, &payload.OffPeakFrequencySecs, &payload.CronExpression); err != nil {
+		return nil, err
+	}
+
+	monitorType := payload.MonitorType
+	if monitorType == "" {
+		monitorType = monitor.MonitorTypeHTTP
+	}
+
+	if err := checkEgress(ctx, monitorType, payload.TLSMode, payload.Url); err != nil {
+		return nil, err
+	}
+
+	responseFormat := payload.ResponseFormat
+	if responseFormat == "" {
+		responseFormat = "string"
+	}
+
+	httpMethod := strings.ToUpper(payload.HttpMethod)
+	if httpMethod == "" {
+		httpMethod = http.MethodGet
+	}
+
+	tlsMode := payload.TLSMode
+	if tlsMode == "" {
+		tlsMode = "none"
+	}
+
+	payloadEncoding := payload.PayloadEncoding
+	if payloadEncoding == "" {
+		payloadEncoding = monitor.PayloadEncodingText
+	}
+
+	quorumFailures := payload.QuorumFailures
+	if quorumFailures <= 0 {
+		quorumFailures = 1
+	}
+
+	timezone := payload.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	windowDays, windowStart, windowEnd := businessHoursColumns(payload.BusinessHours)
+
+	slug, err := newMonitorSlug(ctx, tx, orgID, payload.Slug, payload.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var heartbeatToken sql.NullString
+	if monitorType == monitor.MonitorTypeHeartbeat {
+		token, err := monitor.NewHeartbeatToken()
+		if err != nil {
+			return nil, fmt.Errorf("error generating heartbeat token: %v", err)
+		}
+		heartbeatToken = sql.NullString{String: token, Valid: true}
+		payload.Url = monitor.HeartbeatPath(token)
+	}
+
+	query := `INSERT INTO monitor (org_id,monitor_name,url,frequency_seconds,response_format,http_method,connection_timeout,request_body,monitor_type,response_pattern,tls_mode,auth_username,auth_password,required_capabilities,expected_result,heartbeat_token,grace_seconds,payload_encoding,quorum_failures,jitter_percent,cron_expression,timezone,window_days,window_start,window_end,off_peak_frequency_seconds,group_path,slug,skip_tls_verify,response_schema) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`
 	values := []interface{}{
+		orgID,
 		payload.Name,
 		payload.Url,
 		payload.FrequencySecs,
-		payload.ResponseFormat,
-		payload.HttpMethod,
+		responseFormat,
+		httpMethod,
 		payload.ConnectionTimeout,
 		payload.RequestBody,
+		monitorType,
+		nullString(payload.ResponsePattern),
+		tlsMode,
+		nullString(payload.AuthUsername),
+		nullString(payload.AuthPassword),
+		nullString(strings.Join(payload.RequiredCapabilities, ",")),
+		nullString(payload.ExpectedResult),
+		heartbeatToken,
+		payload.GraceSeconds,
+		payloadEncoding,
+		quorumFailures,
+		monitor.ClampJitter(payload.JitterPercent),
+		nullString(payload.CronExpression),
+		timezone,
+		windowDays,
+		windowStart,
+		windowEnd,
+		nullPositiveInt(payload.OffPeakFrequencySecs),
+		monitor.NormalizeGroup(payload.Group),
+		slug,
+		payload.SkipTLSVerify,
+		nullString(payload.ResponseSchema),
 	}
 
 	res, err := tx.ExecContext(ctx, query, values...)
 
 	if err != nil {
-		return fmt.Errorf("error inserting monitor: %v\n", err)
+		return nil, fmt.Errorf("error inserting monitor: %v\n", err)
 	}
 
 	newMonitorID, err := res.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("error getting last insert id: %v\n", err)
+		return nil, fmt.Errorf("error getting last insert id: %v\n", err)
 	}
 
 	codes := payload.AcceptedStatusCodes
@@ -106,23 +358,81 @@ func InsertMonitorToDB(ctx context.Context, db *config.DB, payload CreateMonitor
 	}
 
 	if err := InsertAcceptedStatusCodes(ctx, tx, newMonitorID, codes); err != nil {
-		return fmt.Errorf("error inserting accepted status codes: %v\n", err)
+		return nil, fmt.Errorf("error inserting accepted status codes: %v\n", err)
 	}
 
 	if err := InsertHeaders(ctx, tx, newMonitorID, payload.RequestHeaders, "monitor_request_headers"); err != nil {
-		return fmt.Errorf("error inserting request headers: %v\n", err)
+		return nil, fmt.Errorf("error inserting request headers: %v\n", err)
 	}
 
 	if err := InsertHeaders(ctx, tx, newMonitorID, payload.ResponseHeaders, "monitor_response_headers"); err != nil {
-		return fmt.Errorf("error inserting response headers: %v\n", err)
+		return nil, fmt.Errorf("error inserting response headers: %v\n", err)
+	}
+
+	if err := InsertMonitorLocations(ctx, tx, newMonitorID, payload.Locations); err != nil {
+		return nil, err
+	}
+
+	if err := InsertMonitorTags(ctx, tx, newMonitorID, payload.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := setFirstRun(ctx, tx, newMonitorID); err != nil {
+		return nil, err
+	}
+
+	created := &CreatedMonitor{MonitorID: newMonitorID}
+
+	if heartbeatToken.Valid {
+		heartbeatDeadline := `UPDATE monitor SET next_run_at = DATE_ADD(NOW(), INTERVAL frequency_seconds + grace_seconds SECOND) WHERE monitor_id = ?`
+		if _, err := tx.ExecContext(ctx, heartbeatDeadline, newMonitorID); err != nil {
+			return nil, fmt.Errorf("error setting heartbeat deadline: %v", err)
+		}
+		created.HeartbeatPath = payload.Url
+	}
+
+	after, err := getMonitor(ctx, tx, orgID, int(newMonitorID))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordMonitorChange(ctx, tx, orgID, "create", newMonitorID, nil, after, nil); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("error committing transaction: %v\n", err)
+		return nil, fmt.Errorf("error committing transaction: %v\n", err)
 	}
 
-	return nil
+	return created, nil
+
+}
+
+// setFirstRun sets when a monitor that was just created, rescheduled or
+// resumed is checked first, so a cron or business-hours monitor waits for its
+// schedule instead of running right away.
+func setFirstRun(ctx context.Context, tx *sql.Tx, monitorID int64) error {
+	var m monitor.Monitor
+	query := `SELECT monitor_id, monitor_type, frequency_seconds, jitter_percent, cron_expression, timezone, window_days, window_start, window_end, off_peak_frequency_seconds
+	FROM monitor WHERE monitor_id = ?`
+	err := tx.QueryRowContext(ctx, query, monitorID).Scan(&m.ID, &m.Type, &m.FrequencySecs, &m.JitterPercent, &m.CronExpression, &m.Timezone, &m.WindowDays, &m.WindowStart, &m.WindowEnd, &m.OffPeakFrequencySecs)
+	if err != nil {
+		return fmt.Errorf("error reading schedule of monitor_id=%d: %v", monitorID, err)
+	}
+	if m.Type == monitor.MonitorTypeHeartbeat {
+		return nil
+	}
+
+	sched, err := m.Schedule()
+	if err != nil {
+		return fmt.Errorf("error reading schedule of monitor_id=%d: %v", monitorID, err)
+	}
 
+	delay := sched.FirstRun(time.Now())
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	if _, err := tx.ExecContext(ctx, `UPDATE monitor SET next_run_at = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE monitor_id = ?`, seconds, monitorID); err != nil {
+		return fmt.Errorf("error scheduling monitor_id=%d: %v", monitorID, err)
+	}
+	return nil
 }
 
 func InsertHeaders(ctx context.Context, tx *sql.Tx, monitorID int64, headers map[string][]string, tableName string) error {
@@ -174,13 +484,144 @@ func InsertAcceptedStatusCodes(ctx context.Context, tx *sql.Tx, newMonitorID int
 	return nil
 }
 
-func UpdateMonitorInDB(ctx context.Context, db *config.DB, payload UpdateMonitorPayload) error {
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a monitor name into the slug used when none is given.
+func slugify(name string) string {
+	slug := nonSlugChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(slug) > 80 {
+		slug = slug[:80]
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "monitor"
+	}
+	return slug
+}
+
+func checkSlugFree(ctx context.Context, tx *sql.Tx, orgID int64, slug string, monitorID int) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM monitor WHERE org_id = ? AND slug = ? AND monitor_id <> ?`, orgID, slug, monitorID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error checking slug %q: %v", slug, err)
+	}
+	return validation.Errors{{Field: "slug", Message: "is already used by another monitor"}}
+}
+
+// newMonitorSlug returns the slug for a new monitor: the requested one when
+// it is free, or one derived from the name, numbered until it is unique.
+func newMonitorSlug(ctx context.Context, tx *sql.Tx, orgID int64, requested string, name string) (string, error) {
+	if requested != "" {
+		return requested, checkSlugFree(ctx, tx, orgID, requested, 0)
+	}
+
+	base := slugify(name)
+	rows, err := tx.QueryContext(ctx, `SELECT slug FROM monitor WHERE org_id = ? AND (slug = ? OR slug LIKE ?)`, orgID, base, escapeLike(base)+"-%")
+	if err != nil {
+		return "", fmt.Errorf("error looking up slugs: %v", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", fmt.Errorf("error scanning slugs: %v", err)
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating slugs: %v", err)
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// checkEgress rejects targets the egress policy would block when the
+// monitor runs, reported against the url field.
+func checkEgress(ctx context.Context, monitorType string, tlsMode string, rawURL string) error {
+	if err := monitor.Egress.CheckTarget(ctx, monitorType, tlsMode, rawURL); err != nil {
+		return validation.Errors{{Field: "url", Message: err.Error()}}
+	}
+	return nil
+}
+
+func UpdateMonitorInDB(ctx context.Context, db *config.DB, orgID int64, payload UpdateMonitorPayload) error {
+	return updateMonitor(ctx, db, orgID, payload, "update")
+}
+
+func updateMonitor(ctx context.Context, db *config.DB, orgID int64, payload UpdateMonitorPayload, action string) error {
 	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %v", err)
 	}
 	defer tx.Rollback()
 
+	var monitorType, url, tlsMode string
+	err = tx.QueryRowContext(ctx, `SELECT monitor_type, url, tls_mode FROM monitor WHERE monitor_id = ? AND org_id = ? FOR UPDATE`, payload.MonitorID, orgID).Scan(&monitorType, &url, &tlsMode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMonitorNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up monitor_id=%d: %v", payload.MonitorID, err)
+	}
+
+	// A url is checked against the monitor's type, so when either changes
+	// the other is taken from the stored monitor.
+	fields := payload.validationFields()
+	if payload.Url != nil || payload.MonitorType != nil {
+		if fields.MonitorType == nil {
+			fields.MonitorType = &monitorType
+		}
+		if fields.Url == nil {
+			fields.Url = &url
+		}
+	}
+	if err := validation.ValidateMonitor(fields, true); err != nil {
+		return err
+	}
+
+	before, err := getMonitor(ctx, tx, orgID, payload.MonitorID)
+	if err != nil {
+		return err
+	}
+
+	// Heartbeat monitors are pinged on a generated path instead of checking
+	// a url, so converting to or from one swaps the token for the url.
+	wasHeartbeat := monitorType == monitor.MonitorTypeHeartbeat
+	toHeartbeat := payload.MonitorType != nil && *payload.MonitorType == monitor.MonitorTypeHeartbeat && !wasHeartbeat
+	fromHeartbeat := payload.MonitorType != nil && *payload.MonitorType != monitor.MonitorTypeHeartbeat && wasHeartbeat
+
+	if payload.Url != nil || payload.MonitorType != nil || payload.TLSMode != nil {
+		if payload.Url != nil {
+			url = *payload.Url
+		}
+		if payload.MonitorType != nil {
+			monitorType = *payload.MonitorType
+		}
+		if payload.TLSMode != nil {
+			tlsMode = *payload.TLSMode
+		}
+		if err := checkEgress(ctx, monitorType, tlsMode, url); err != nil {
+			return err
+		}
+	}
+
+	quota, err := orgQuota(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+	if err := quota.checkFrequency(payload.FrequencySecs, payload.OffPeakFrequencySecs, payload.CronExpression); err != nil {
+		return err
+	}
+
 	setParts := make([]string, 0, 8)
 	args := make([]interface{}, 0, 9)
 
@@ -188,7 +629,7 @@ func UpdateMonitorInDB(ctx context.Context, db *config.DB, payload UpdateMonitor
 		setParts = append(setParts, "monitor_name = ?")
 		args = append(args, *payload.Name)
 	}
-	if payload.Url != nil {
+	if payload.Url != nil && !toHeartbeat {
 		setParts = append(setParts, "url = ?")
 		args = append(args, *payload.Url)
 	}
@@ -202,7 +643,7 @@ func UpdateMonitorInDB(ctx context.Context, db *config.DB, payload UpdateMonitor
 	}
 	if payload.HttpMethod != nil {
 		setParts = append(setParts, "http_method = ?")
-		args = append(args, *payload.HttpMethod)
+		args = append(args, strings.ToUpper(*payload.HttpMethod))
 	}
 	if payload.ConnectionTimeout != nil {
 		setParts = append(setParts, "connection_timeout = ?")
@@ -212,20 +653,117 @@ func UpdateMonitorInDB(ctx context.Context, db *config.DB, payload UpdateMonitor
 		setParts = append(setParts, "request_body = ?")
 		args = append(args, *payload.RequestBody)
 	}
+	if payload.MonitorType != nil {
+		setParts = append(setParts, "monitor_type = ?")
+		args = append(args, *payload.MonitorType)
+	}
+	if payload.ResponsePattern != nil {
+		setParts = append(setParts, "response_pattern = ?")
+		args = append(args, nullString(*payload.ResponsePattern))
+	}
+	if payload.ResponseSchema != nil {
+		setParts = append(setParts, "response_schema = ?")
+		args = append(args, nullString(*payload.ResponseSchema))
+	}
+	if payload.TLSMode != nil {
+		setParts = append(setParts, "tls_mode = ?")
+		args = append(args, *payload.TLSMode)
+	}
+	if payload.AuthUsername != nil {
+		setParts = append(setParts, "auth_username = ?")
+		args = append(args, nullString(*payload.AuthUsername))
+	}
+	if payload.AuthPassword != nil {
+		setParts = append(setParts, "auth_password = ?")
+		args = append(args, nullString(*payload.AuthPassword))
+	}
+	if payload.SkipTLSVerify != nil {
+		setParts = append(setParts, "skip_tls_verify = ?")
+		args = append(args, *payload.SkipTLSVerify)
+	}
+	if payload.RequiredCapabilities != nil {
+		setParts = append(setParts, "required_capabilities = ?")
+		args = append(args, nullString(strings.Join(*payload.RequiredCapabilities, ",")))
+	}
+	if payload.ExpectedResult != nil {
+		setParts = append(setParts, "expected_result = ?")
+		args = append(args, nullString(*payload.ExpectedResult))
+	}
+	if payload.GraceSeconds != nil {
+		setParts = append(setParts, "grace_seconds = ?")
+		args = append(args, *payload.GraceSeconds)
+	}
+	if payload.PayloadEncoding != nil {
+		setParts = append(setParts, "payload_encoding = ?")
+		args = append(args, *payload.PayloadEncoding)
+	}
+	if payload.QuorumFailures != nil {
+		setParts = append(setParts, "quorum_failures = ?")
+		args = append(args, *payload.QuorumFailures)
+	}
+	if payload.JitterPercent != nil {
+		setParts = append(setParts, "jitter_percent = ?")
+		args = append(args, monitor.ClampJitter(*payload.JitterPercent))
+	}
+	if payload.CronExpression != nil {
+		setParts = append(setParts, "cron_expression = ?")
+		args = append(args, nullString(*payload.CronExpression))
+	}
+	if payload.Timezone != nil {
+		timezone := *payload.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		setParts = append(setParts, "timezone = ?")
+		args = append(args, timezone)
+	}
+	if payload.BusinessHours != nil {
+		windowDays, windowStart, windowEnd := businessHoursColumns(payload.BusinessHours)
+		setParts = append(setParts, "window_days = ?", "window_start = ?", "window_end = ?")
+		args = append(args, windowDays, windowStart, windowEnd)
+	}
+	if payload.OffPeakFrequencySecs != nil {
+		setParts = append(setParts, "off_peak_frequency_seconds = ?")
+		args = append(args, nullPositiveInt(*payload.OffPeakFrequencySecs))
+	}
+	if payload.Slug != nil {
+		if err := checkSlugFree(ctx, tx, orgID, *payload.Slug, payload.MonitorID); err != nil {
+			return err
+		}
+		setParts = append(setParts, "slug = ?")
+		args = append(args, *payload.Slug)
+	}
+	if payload.Group != nil {
+		setParts = append(setParts, "group_path = ?")
+		args = append(args, monitor.NormalizeGroup(*payload.Group))
+	}
 
-	if len(setParts) > 0 {
-		query := fmt.Sprintf("UPDATE monitor SET %s WHERE monitor_id = ?", strings.Join(setParts, ", "))
-		args = append(args, payload.MonitorID)
-		res, err := tx.ExecContext(ctx, query, args...)
+	// The deadline follows the frequency and grace set above, since MySQL
+	// assigns columns from left to right.
+	if toHeartbeat {
+		token, err := monitor.NewHeartbeatToken()
 		if err != nil {
-			return fmt.Errorf("error updating monitor fields: %v", err)
+			return fmt.Errorf("error generating heartbeat token: %v", err)
 		}
-		rows, err := res.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error checking updated rows: %v", err)
+		setParts = append(setParts, "url = ?", "heartbeat_token = ?", "heartbeat_state = 'new'",
+			"next_run_at = DATE_ADD(NOW(), INTERVAL frequency_seconds + grace_seconds SECOND)")
+		args = append(args, monitor.HeartbeatPath(token), token)
+	}
+	if fromHeartbeat {
+		setParts = append(setParts, "heartbeat_token = NULL", "heartbeat_state = 'new'")
+	}
+
+	if len(setParts) > 0 {
+		query := fmt.Sprintf("UPDATE monitor SET %s WHERE monitor_id = ? AND org_id = ?", strings.Join(setParts, ", "))
+		args = append(args, payload.MonitorID, orgID)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("error updating monitor fields: %v", err)
 		}
-		if rows == 0 {
-			return fmt.Errorf("monitor not found for monitor_id=%d", payload.MonitorID)
+	}
+
+	if fromHeartbeat || payload.CronExpression != nil || payload.Timezone != nil || payload.BusinessHours != nil || payload.OffPeakFrequencySecs != nil {
+		if err := setFirstRun(ctx, tx, int64(payload.MonitorID)); err != nil {
+			return err
 		}
 	}
 
@@ -247,6 +785,32 @@ func UpdateMonitorInDB(ctx context.Context, db *config.DB, payload UpdateMonitor
 		}
 	}
 
+	if payload.Locations != nil {
+		if err := ReplaceMonitorLocations(ctx, tx, int64(payload.MonitorID), *payload.Locations); err != nil {
+			return err
+		}
+	}
+
+	if payload.Tags != nil {
+		if err := ReplaceMonitorTags(ctx, tx, int64(payload.MonitorID), *payload.Tags); err != nil {
+			return err
+		}
+	}
+
+	after, err := getMonitor(ctx, tx, orgID, payload.MonitorID)
+	if err != nil {
+		return err
+	}
+
+	// Passwords are not stored in revisions, so only record that one was set.
+	var extra map[string]FieldChange
+	if payload.AuthPassword != nil {
+		extra = map[string]FieldChange{"auth_password": {After: json.RawMessage(`"[redacted]"`)}}
+	}
+	if err := recordMonitorChange(ctx, tx, orgID, action, int64(payload.MonitorID), before, after, extra); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error committing update transaction: %v", err)
 	}
@@ -279,109 +843,446 @@ func ReplaceAcceptedStatusCodes(ctx context.Context, tx *sql.Tx, monitorID int64
 	return InsertAcceptedStatusCodes(ctx, tx, monitorID, codes)
 }
 
-func GetAllMonitors(ctx context.Context, db *config.DB) ([]MonitorSummary, error) {
-	query := `SELECT monitor_id, monitor_name, url FROM monitor`
+func InsertMonitorLocations(ctx context.Context, tx *sql.Tx, monitorID int64, locations []string) error {
+	names := make(map[string]bool, len(locations))
+	placeholders := make([]string, 0, len(locations))
+	args := []interface{}{monitorID}
+	for _, name := range locations {
+		name = strings.TrimSpace(name)
+		if name == "" || names[name] {
+			continue
+		}
+		names[name] = true
+		placeholders = append(placeholders, "?")
+		args = append(args, name)
+	}
 
-	rows, err := db.Pool.QueryContext(ctx, query)
+	if len(placeholders) == 0 {
+		return nil
+	}
 
+	query := fmt.Sprintf(`INSERT INTO monitor_locations (monitor_id, loc_id) SELECT ?, loc_id FROM locations WHERE name IN (%s)`, strings.Join(placeholders, ","))
+	res, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error getting all monitors: %v", err)
+		return fmt.Errorf("error inserting monitor locations: %v", err)
+	}
 
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking inserted monitor locations: %v", err)
+	}
+	if int(rows) != len(placeholders) {
+		return ErrUnknownLocation
 	}
-	defer rows.Close()
 
-	monitors := make([]MonitorSummary, 0, 16)
+	return nil
+}
 
-	for rows.Next() {
-		var monitor MonitorSummary
+func ReplaceMonitorLocations(ctx context.Context, tx *sql.Tx, monitorID int64, locations []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM monitor_locations WHERE monitor_id = ?", monitorID); err != nil {
+		return fmt.Errorf("error deleting existing monitor locations: %v", err)
+	}
 
-		err := rows.Scan(&monitor.MonitorID, &monitor.MonitorName, &monitor.Url)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning rows: %v", err)
-		}
+	return InsertMonitorLocations(ctx, tx, monitorID, locations)
+}
 
-		monitors = append(monitors, monitor)
-	}
+func InsertMonitorTags(ctx context.Context, tx *sql.Tx, monitorID int64, tags []string) error {
+	query := `INSERT INTO monitor_tags (monitor_id, tag_key, tag_value) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE tag_value = VALUES(tag_value)`
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %v", err)
+	for _, tag := range tags {
+		key, value := monitor.ParseTag(tag)
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, query, monitorID, key, value); err != nil {
+			return fmt.Errorf("error inserting monitor tags: %v", err)
+		}
 	}
 
-	return monitors, nil
+	return nil
 }
 
-func GetResultsBetweenTimestamps(ctx context.Context, db *config.DB, monitorID int, fromTS time.Time, toTS time.Time, cursor int64, limit int) ([]MonitorResult, *int64, error) {
-	if limit <= 0 {
-		limit = 20
+func ReplaceMonitorTags(ctx context.Context, tx *sql.Tx, monitorID int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM monitor_tags WHERE monitor_id = ?", monitorID); err != nil {
+		return fmt.Errorf("error deleting existing monitor tags: %v", err)
 	}
 
-	query := `
-		SELECT
-			result_id,
-			monitor_id,
-			status_code,
-			status,
-			dns_response_time,
-			connection_time,
-			tls_handshake_time,
-			resolved_ip,
-			first_byte_time,
-			download_time,
-			response_time,
-			throughput,
-			reason,
-			created_at
-		FROM results
-		WHERE monitor_id = ?
-		  AND created_at BETWEEN ? AND ?
-	`
-	args := []interface{}{monitorID, fromTS, toTS}
-	if cursor > 0 {
-		query += ` AND result_id < ?`
-		args = append(args, cursor)
-	}
-	query += `
-		ORDER BY result_id DESC
-		LIMIT ?
-	`
-	args = append(args, limit+1)
+	return InsertMonitorTags(ctx, tx, monitorID, tags)
+}
 
-	rows, err := db.Pool.QueryContext(ctx, query, args...)
+func GetLocations(ctx context.Context, db *config.DB) ([]Location, error) {
+	query := `SELECT loc_id, name, registered_at, last_seen_at FROM locations ORDER BY name`
+
+	rows, err := db.Pool.QueryContext(ctx, query)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting results between timestamps for monitor_id=%d: %v", monitorID, err)
+		return nil, fmt.Errorf("error getting locations: %v", err)
 	}
 	defer rows.Close()
 
-	results := make([]MonitorResult, 0)
+	locations := make([]Location, 0)
+
 	for rows.Next() {
-		var result MonitorResult
-		if err := rows.Scan(
-			&result.ResultID,
-			&result.MonitorID,
-			&result.StatusCode,
-			&result.Status,
-			&result.DNSResponseTime,
-			&result.ConnectionTime,
-			&result.TLSHandshakeTime,
-			&result.ResolvedIP,
-			&result.FirstByteTime,
-			&result.DownloadTime,
-			&result.ResponseTime,
-			&result.Throughput,
-			&result.Reason,
-			&result.CreatedAt,
-		); err != nil {
-			return nil, nil, fmt.Errorf("error scanning results between timestamps: %v", err)
+		var location Location
+		var lastSeenAt sql.NullString
+
+		if err := rows.Scan(&location.LocationID, &location.Name, &location.RegisteredAt, &lastSeenAt); err != nil {
+			return nil, fmt.Errorf("error scanning locations: %v", err)
 		}
-		results = append(results, result)
+		if lastSeenAt.Valid {
+			location.LastSeenAt = &lastSeenAt.String
+		}
+
+		locations = append(locations, location)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, nil, fmt.Errorf("error iterating results between timestamps: %v", err)
+		return nil, fmt.Errorf("error iterating locations: %v", err)
 	}
 
-	var nextCursor *int64
-	if len(results) > limit {
+	return locations, nil
+}
+
+const maxMonitorPageSize = 200
+
+// monitorSorts maps the sort names /monitors accepts to the expression rows
+// are ordered by. Nullable columns are coalesced so the cursor can compare
+// against them.
+var monitorSorts = map[string]string{
+	"name":            "m.monitor_name",
+	"id":              "m.monitor_id",
+	"status":          "COALESCE(m.current_status, '')",
+	"last_checked_at": "COALESCE(m.last_checked_at, '1970-01-01 00:00:00')",
+}
+
+var MonitorStatuses = []string{"up", "down", "unknown"}
+
+type MonitorFilter struct {
+	// Tags are key or key:value; a key alone matches any value.
+	Tags []string
+	// Group matches the group and every group below it.
+	Group  string
+	Active *bool
+	Status string
+	Search string
+	// Sort is a key of monitorSorts, prefixed with - for descending order.
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+// monitorCursor is the position after the last monitor of a page, encoded
+// as opaque base64 json.
+type monitorCursor struct {
+	Sort      string `json:"s"`
+	Key       string `json:"k"`
+	MonitorID int    `json:"id"`
+}
+
+func (c monitorCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeMonitorCursor(raw string) (monitorCursor, bool) {
+	var c monitorCursor
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, false
+	}
+	if err := json.Unmarshal(decoded, &c); err != nil || c.MonitorID <= 0 {
+		return c, false
+	}
+	return c, true
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (f *MonitorFilter) validate() (monitorCursor, error) {
+	var errs validation.Errors
+	var cursor monitorCursor
+
+	if f.Sort == "" {
+		f.Sort = "name"
+	}
+	if _, ok := monitorSorts[strings.TrimPrefix(f.Sort, "-")]; !ok {
+		errs.Add("sort", "must be one of name, id, status, last_checked_at, optionally prefixed with -")
+	}
+	if f.Status != "" {
+		f.Status = strings.ToLower(f.Status)
+		errs.Enum("status", f.Status, MonitorStatuses...)
+	}
+	for i, tag := range f.Tags {
+		if key, _ := monitor.ParseTag(tag); key == "" {
+			errs.Add(fmt.Sprintf("tag[%d]", i), "must be key or key:value")
+		}
+	}
+	if f.Cursor != "" {
+		var ok bool
+		cursor, ok = decodeMonitorCursor(f.Cursor)
+		if !ok {
+			errs.Add("cursor", "is not a valid cursor")
+		} else if cursor.Sort != f.Sort {
+			errs.Add("cursor", "was issued for sort %s", cursor.Sort)
+		}
+	}
+
+	if f.Limit <= 0 {
+		f.Limit = 20
+	}
+	if f.Limit > maxMonitorPageSize {
+		f.Limit = maxMonitorPageSize
+	}
+
+	return cursor, errs.Err()
+}
+
+// GetAllMonitors lists the monitors of an org matching filter, one page at a
+// time. The returned cursor is nil on the last page.
+func GetAllMonitors(ctx context.Context, db *config.DB, orgID int64, filter MonitorFilter) ([]MonitorSummary, *string, error) {
+	cursor, err := filter.validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortName := strings.TrimPrefix(filter.Sort, "-")
+	sortExpr := monitorSorts[sortName]
+	order, cmp := "ASC", ">"
+	if strings.HasPrefix(filter.Sort, "-") {
+		order, cmp = "DESC", "<"
+	}
+
+	where := []string{"m.org_id = ?"}
+	args := []interface{}{orgID}
+
+	for _, tag := range filter.Tags {
+		key, value := monitor.ParseTag(tag)
+		if value == "" {
+			where = append(where, "EXISTS (SELECT 1 FROM monitor_tags t WHERE t.monitor_id = m.monitor_id AND t.tag_key = ?)")
+			args = append(args, key)
+			continue
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM monitor_tags t WHERE t.monitor_id = m.monitor_id AND t.tag_key = ? AND t.tag_value = ?)")
+		args = append(args, key, value)
+	}
+	if group := monitor.NormalizeGroup(filter.Group); group != "" {
+		where = append(where, "(m.group_path = ? OR m.group_path LIKE ?)")
+		args = append(args, group, escapeLike(group)+"/%")
+	}
+	if filter.Active != nil {
+		where = append(where, "COALESCE(m.is_active, 0) = ?")
+		args = append(args, *filter.Active)
+	}
+	switch filter.Status {
+	case "up", "down":
+		where = append(where, "m.current_status = ?")
+		args = append(args, strings.ToUpper(filter.Status))
+	case "unknown":
+		where = append(where, "m.current_status IS NULL")
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		where = append(where, "(m.monitor_name LIKE ? OR m.url LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if filter.Cursor != "" {
+		if sortName == "id" {
+			where = append(where, fmt.Sprintf("m.monitor_id %s ?", cmp))
+			args = append(args, cursor.MonitorID)
+		} else {
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND m.monitor_id %[2]s ?))", sortExpr, cmp))
+			args = append(args, cursor.Key, cursor.Key, cursor.MonitorID)
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT m.monitor_id, m.slug, m.monitor_name, m.url, m.monitor_type, m.group_path, COALESCE(m.is_active, 0),
+	m.current_status, m.last_checked_at, CAST(%[1]s AS CHAR)
+	FROM monitor m
+	WHERE %[2]s
+	ORDER BY %[1]s %[3]s, m.monitor_id %[3]s
+	LIMIT ?`, sortExpr, strings.Join(where, " AND "), order)
+	args = append(args, filter.Limit+1)
+
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting all monitors: %v", err)
+	}
+	defer rows.Close()
+
+	monitors := make([]MonitorSummary, 0, filter.Limit+1)
+	sortKeys := make([]string, 0, filter.Limit+1)
+
+	for rows.Next() {
+		var m MonitorSummary
+		var currentStatus sql.NullString
+		var lastCheckedAt sql.NullTime
+		var sortKey string
+
+		if err := rows.Scan(&m.MonitorID, &m.Slug, &m.MonitorName, &m.Url, &m.MonitorType, &m.Group, &m.IsActive, &currentStatus, &lastCheckedAt, &sortKey); err != nil {
+			return nil, nil, fmt.Errorf("error scanning rows: %v", err)
+		}
+		m.CurrentStatus = nullStringPtr(currentStatus)
+		m.LastCheckedAt = nullTimePtr(lastCheckedAt)
+
+		monitors = append(monitors, m)
+		sortKeys = append(sortKeys, sortKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var nextCursor *string
+	if len(monitors) > filter.Limit {
+		last := filter.Limit - 1
+		encoded := monitorCursor{Sort: filter.Sort, Key: sortKeys[last], MonitorID: monitors[last].MonitorID}.encode()
+		nextCursor = &encoded
+		monitors = monitors[:filter.Limit]
+	}
+
+	if len(monitors) == 0 {
+		return monitors, nil, nil
+	}
+
+	ids := make([]interface{}, 0, len(monitors))
+	placeholders := make([]string, 0, len(monitors))
+	for _, m := range monitors {
+		ids = append(ids, m.MonitorID)
+		placeholders = append(placeholders, "?")
+	}
+	tags, err := monitor.GetTagsForMonitor(ctx, db.Pool, ids, placeholders)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range monitors {
+		monitors[i].Tags = tags[monitors[i].MonitorID]
+		if monitors[i].Tags == nil {
+			monitors[i].Tags = []string{}
+		}
+	}
+
+	return monitors, nextCursor, nil
+}
+
+type MonitorGroup struct {
+	Group        string `json:"group"`
+	MonitorCount int    `json:"monitor_count"`
+}
+
+// GetMonitorGroups lists the groups in use by an org's monitors.
+func GetMonitorGroups(ctx context.Context, db *config.DB, orgID int64) ([]MonitorGroup, error) {
+	query := `SELECT group_path, COUNT(*) FROM monitor WHERE org_id = ? AND group_path <> '' GROUP BY group_path ORDER BY group_path`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting monitor groups: %v", err)
+	}
+	defer rows.Close()
+
+	groups := make([]MonitorGroup, 0)
+	for rows.Next() {
+		var g MonitorGroup
+		if err := rows.Scan(&g.Group, &g.MonitorCount); err != nil {
+			return nil, fmt.Errorf("error scanning monitor groups: %v", err)
+		}
+		groups = append(groups, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monitor groups: %v", err)
+	}
+
+	return groups, nil
+}
+
+func GetResultsBetweenTimestamps(ctx context.Context, db *config.DB, orgID int64, monitorID int, location string, fromTS time.Time, toTS time.Time, cursor int64, limit int) ([]MonitorResult, *int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `
+		SELECT
+			r.result_id,
+			r.monitor_id,
+			r.status_code,
+			r.status,
+			r.dns_response_time,
+			r.connection_time,
+			r.tls_handshake_time,
+			r.resolved_ip,
+			r.first_byte_time,
+			r.download_time,
+			r.response_time,
+			r.handshake_time,
+			r.round_trip_time,
+			r.throughput,
+			r.reason,
+			COALESCE(l.name, ''),
+			r.in_maintenance,
+			r.created_at
+		FROM results r
+		LEFT JOIN locations l ON l.loc_id = r.loc_id
+		WHERE r.org_id = ?
+		  AND r.monitor_id = ?
+		  AND r.created_at BETWEEN ? AND ?
+	`
+	args := []interface{}{orgID, monitorID, fromTS, toTS}
+	if location != "" {
+		query += ` AND l.name = ?`
+		args = append(args, location)
+	}
+	if cursor > 0 {
+		query += ` AND r.result_id < ?`
+		args = append(args, cursor)
+	}
+	query += `
+		ORDER BY r.result_id DESC
+		LIMIT ?
+	`
+	args = append(args, limit+1)
+
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting results between timestamps for monitor_id=%d: %v", monitorID, err)
+	}
+	defer rows.Close()
+
+	results := make([]MonitorResult, 0)
+	for rows.Next() {
+		var result MonitorResult
+		if err := rows.Scan(
+			&result.ResultID,
+			&result.MonitorID,
+			&result.StatusCode,
+			&result.Status,
+			&result.DNSResponseTime,
+			&result.ConnectionTime,
+			&result.TLSHandshakeTime,
+			&result.ResolvedIP,
+			&result.FirstByteTime,
+			&result.DownloadTime,
+			&result.ResponseTime,
+			&result.HandshakeTime,
+			&result.RoundTripTime,
+			&result.Throughput,
+			&result.Reason,
+			&result.Location,
+			&result.InMaintenance,
+			&result.CreatedAt,
+		); err != nil {
+			return nil, nil, fmt.Errorf("error scanning results between timestamps: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating results between timestamps: %v", err)
+	}
+
+	var nextCursor *int64
+	if len(results) > limit {
 		cursorValue := results[limit-1].ResultID
 		nextCursor = &cursorValue
 		results = results[:limit]
@@ -390,25 +1291,38 @@ func GetResultsBetweenTimestamps(ctx context.Context, db *config.DB, monitorID i
 	return results, nextCursor, nil
 }
 
-func GetMetricsBetweenTimestamps(ctx context.Context, db *config.DB, monitorID int, fromTS time.Time, toTS time.Time) ([]MonitorMetrics, error) {
+func GetMetricsBetweenTimestamps(ctx context.Context, db *config.DB, orgID int64, monitorID int, location string, fromTS time.Time, toTS time.Time) ([]MonitorMetrics, error) {
 	query := `
 		SELECT
-			monitor_id,
-			dns_response_time,
-			connection_time,
-			tls_handshake_time,
-			first_byte_time,
-			download_time,
-			response_time,
-			throughput,
-			created_at
-		FROM results
-		WHERE monitor_id = ?
-		  AND created_at BETWEEN ? AND ?
-		ORDER BY result_id DESC
+			r.monitor_id,
+			r.dns_response_time,
+			r.connection_time,
+			r.tls_handshake_time,
+			r.first_byte_time,
+			r.download_time,
+			r.response_time,
+			r.handshake_time,
+			r.round_trip_time,
+			r.throughput,
+			COALESCE(l.name, ''),
+			r.created_at
+		FROM results r
+		LEFT JOIN locations l ON l.loc_id = r.loc_id
+		WHERE r.org_id = ?
+		  AND r.monitor_id = ?
+		  AND r.created_at BETWEEN ? AND ?
+		  AND r.in_maintenance = 0
+	`
+	args := []interface{}{orgID, monitorID, fromTS, toTS}
+	if location != "" {
+		query += ` AND l.name = ?`
+		args = append(args, location)
+	}
+	query += `
+		ORDER BY r.result_id DESC
 	`
 
-	rows, err := db.Pool.QueryContext(ctx, query, monitorID, fromTS, toTS)
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error getting results between timestamps for monitor_id=%d: %v", monitorID, err)
 	}
@@ -425,7 +1339,10 @@ func GetMetricsBetweenTimestamps(ctx context.Context, db *config.DB, monitorID i
 			&result.FirstByteTime,
 			&result.DownloadTime,
 			&result.ResponseTime,
+			&result.HandshakeTime,
+			&result.RoundTripTime,
 			&result.Throughput,
+			&result.Location,
 			&result.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("error scanning results between timestamps: %v", err)
@@ -440,15 +1357,2978 @@ func GetMetricsBetweenTimestamps(ctx context.Context, db *config.DB, monitorID i
 	return metrics, nil
 }
 
-func SuspendMonitor(ctx context.Context, db *config.DB, MonitorID int) error {
+func GetVerdictsBetweenTimestamps(ctx context.Context, db *config.DB, orgID int64, monitorID int, fromTS time.Time, toTS time.Time) ([]MonitorVerdict, error) {
+	query := `
+		SELECT
+			v.verdict_id,
+			v.monitor_id,
+			v.cycle_id,
+			v.status,
+			v.failed_locations,
+			v.reported_locations,
+			v.total_locations,
+			v.quorum,
+			COALESCE(v.reason, ''),
+			v.decided_at
+		FROM monitor_verdicts v
+		JOIN monitor m ON m.monitor_id = v.monitor_id AND m.org_id = ?
+		WHERE v.monitor_id = ?
+		  AND v.decided_at BETWEEN ? AND ?
+		ORDER BY v.verdict_id DESC
+	`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID, monitorID, fromTS, toTS)
+	if err != nil {
+		return nil, fmt.Errorf("error getting verdicts between timestamps for monitor_id=%d: %v", monitorID, err)
+	}
+	defer rows.Close()
+
+	verdicts := make([]MonitorVerdict, 0)
+	for rows.Next() {
+		var verdict MonitorVerdict
+		if err := rows.Scan(
+			&verdict.VerdictID,
+			&verdict.MonitorID,
+			&verdict.CycleID,
+			&verdict.Status,
+			&verdict.FailedLocations,
+			&verdict.ReportedLocations,
+			&verdict.TotalLocations,
+			&verdict.Quorum,
+			&verdict.Reason,
+			&verdict.DecidedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning verdicts between timestamps: %v", err)
+		}
+		verdicts = append(verdicts, verdict)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating verdicts between timestamps: %v", err)
+	}
+
+	return verdicts, nil
+}
+
+func SuspendMonitor(ctx context.Context, db *config.DB, orgID int64, MonitorID int) error {
+	if err := monitorExists(ctx, db, orgID, MonitorID); err != nil {
+		return err
+	}
+
 	query := `UPDATE monitor
 	SET is_active = 0
-	WHERE monitor_id = ?`
+	WHERE monitor_id = ? AND org_id = ?`
+
+	return setMonitorActive(ctx, db, orgID, MonitorID, query, "suspend")
+}
+
+var activeChange = map[string]map[string]FieldChange{
+	"suspend": {"is_active": {Before: json.RawMessage(`true`), After: json.RawMessage(`false`)}},
+	"resume":  {"is_active": {Before: json.RawMessage(`false`), After: json.RawMessage(`true`)}},
+}
+
+// setMonitorActive runs a suspend or resume query and audits it when it
+// changed the monitor.
+func setMonitorActive(ctx context.Context, db *config.DB, orgID int64, monitorID int, query string, action string) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
 
-	_, err := db.Pool.ExecContext(ctx, query, MonitorID)
+	res, err := tx.ExecContext(ctx, query, monitorID, orgID)
 	if err != nil {
-		return fmt.Errorf("error updating monitor after poll: %w", err)
+		return fmt.Errorf("error updating monitor_id=%d: %w", monitorID, err)
+	}
+
+	if rows, err := res.RowsAffected(); err == nil && rows > 0 {
+		if action == "resume" {
+			if err := setFirstRun(ctx, tx, int64(monitorID)); err != nil {
+				return err
+			}
+		}
+		if err := recordAudit(ctx, tx, orgID, action, AuditMonitor, int64(monitorID), activeChange[action]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
 	}
 
 	return nil
 }
+
+type SchedulerLeader struct {
+	InstanceID string `json:"instance_id"`
+	Hostname   string `json:"hostname"`
+	AcquiredAt string `json:"acquired_at"`
+	RenewedAt  string `json:"renewed_at"`
+	Active     bool   `json:"active"`
+}
+
+func GetSchedulerLeader(ctx context.Context, db *config.DB) (*SchedulerLeader, error) {
+	query := `SELECT instance_id, COALESCE(hostname, ''), acquired_at, renewed_at, IS_USED_LOCK(?) IS NOT NULL
+	FROM scheduler_leader WHERE id = 1`
+
+	var leader SchedulerLeader
+	err := db.Pool.QueryRowContext(ctx, query, monitor.SchedulerLockName).Scan(&leader.InstanceID, &leader.Hostname, &leader.AcquiredAt, &leader.RenewedAt, &leader.Active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduler leader: %v", err)
+	}
+
+	return &leader, nil
+}
+
+type SchedulerStats struct {
+	WindowSecs   int     `json:"window_secs"`
+	Monitors     int     `json:"monitors"`
+	TotalChecks  int     `json:"total_checks"`
+	PeakChecks   int     `json:"peak_checks"`
+	AvgPerSecond float64 `json:"avg_per_second"`
+	PerSecond    []int   `json:"per_second"`
+}
+
+func GetSchedulerStats(ctx context.Context, db *config.DB, orgID int64, windowSecs int) (*SchedulerStats, error) {
+	query := `SELECT m.frequency_seconds, GREATEST(TIMESTAMPDIFF(SECOND, NOW(), m.next_run_at), 0), GREATEST(COUNT(ml.loc_id), 1)
+	FROM monitor m
+	LEFT JOIN monitor_locations ml ON ml.monitor_id = m.monitor_id
+	WHERE m.org_id = ? AND m.is_active = 1 AND m.monitor_type <> 'heartbeat'
+	GROUP BY m.monitor_id, m.frequency_seconds, m.next_run_at`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduled monitors: %v", err)
+	}
+	defer rows.Close()
+
+	stats := &SchedulerStats{
+		WindowSecs: windowSecs,
+		PerSecond:  make([]int, windowSecs),
+	}
+
+	for rows.Next() {
+		var frequency, firstRun, checks int
+		if err := rows.Scan(&frequency, &firstRun, &checks); err != nil {
+			return nil, fmt.Errorf("error scanning scheduled monitors: %v", err)
+		}
+
+		stats.Monitors++
+		for t := firstRun; t < windowSecs; t += max(frequency, 1) {
+			stats.PerSecond[t] += checks
+			stats.TotalChecks += checks
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scheduled monitors: %v", err)
+	}
+
+	for _, n := range stats.PerSecond {
+		stats.PeakChecks = max(stats.PeakChecks, n)
+	}
+	if windowSecs > 0 {
+		stats.AvgPerSecond = float64(stats.TotalChecks) / float64(windowSecs)
+	}
+
+	return stats, nil
+}
+
+var (
+	ErrInvalidMaintenanceWindow  = errors.New("invalid maintenance window")
+	ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+)
+
+type MaintenanceWindow struct {
+	WindowID       int64    `json:"window_id"`
+	Name           string   `json:"name"`
+	Mode           string   `json:"mode"`
+	StartsAt       string   `json:"starts_at"`
+	EndsAt         *string  `json:"ends_at"`
+	CronExpression *string  `json:"cron_expression"`
+	DurationSecs   *int64   `json:"duration_secs"`
+	Timezone       string   `json:"timezone"`
+	MonitorIDs     []int64  `json:"monitor_ids"`
+	Tags           []string `json:"tags"`
+	Active         bool     `json:"active"`
+	CreatedAt      string   `json:"created_at"`
+}
+
+func validateMaintenanceWindow(payload *MaintenanceWindowPayload) (time.Time, sql.NullTime, error) {
+	var endsAt sql.NullTime
+
+	if strings.TrimSpace(payload.Name) == "" {
+		return time.Time{}, endsAt, fmt.Errorf("%w: name is required", ErrInvalidMaintenanceWindow)
+	}
+
+	if payload.Mode == "" {
+		payload.Mode = monitor.MaintenanceSuppress
+	}
+	if payload.Mode != monitor.MaintenancePause && payload.Mode != monitor.MaintenanceSuppress {
+		return time.Time{}, endsAt, fmt.Errorf("%w: mode must be pause or suppress", ErrInvalidMaintenanceWindow)
+	}
+
+	if payload.Timezone == "" {
+		payload.Timezone = "UTC"
+	}
+	if _, err := monitor.ParseTimezone(payload.Timezone); err != nil {
+		return time.Time{}, endsAt, fmt.Errorf("%w: %v", ErrInvalidMaintenanceWindow, err)
+	}
+
+	if len(payload.MonitorIDs) == 0 && len(payload.Tags) == 0 {
+		return time.Time{}, endsAt, fmt.Errorf("%w: monitor_ids or tags are required", ErrInvalidMaintenanceWindow)
+	}
+
+	startsAt := time.Now().UTC()
+	if payload.StartsAt != "" {
+		t, err := parseTimestamp(payload.StartsAt)
+		if err != nil {
+			return time.Time{}, endsAt, fmt.Errorf("%w: starts_at must be RFC3339", ErrInvalidMaintenanceWindow)
+		}
+		startsAt = t
+	}
+
+	if payload.EndsAt != "" {
+		t, err := parseTimestamp(payload.EndsAt)
+		if err != nil {
+			return time.Time{}, endsAt, fmt.Errorf("%w: ends_at must be RFC3339", ErrInvalidMaintenanceWindow)
+		}
+		if !t.After(startsAt) {
+			return time.Time{}, endsAt, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidMaintenanceWindow)
+		}
+		endsAt = sql.NullTime{Time: t, Valid: true}
+	}
+
+	if payload.CronExpression == "" {
+		if payload.StartsAt == "" || !endsAt.Valid {
+			return time.Time{}, endsAt, fmt.Errorf("%w: one-off windows need starts_at and ends_at", ErrInvalidMaintenanceWindow)
+		}
+		return startsAt, endsAt, nil
+	}
+
+	if _, err := monitor.ParseCron(payload.CronExpression); err != nil {
+		return time.Time{}, endsAt, fmt.Errorf("%w: %v", ErrInvalidMaintenanceWindow, err)
+	}
+	if payload.DurationSecs <= 0 {
+		return time.Time{}, endsAt, fmt.Errorf("%w: recurring windows need a positive duration_secs", ErrInvalidMaintenanceWindow)
+	}
+
+	return startsAt, endsAt, nil
+}
+
+func replaceMaintenanceWindowScope(ctx context.Context, tx *sql.Tx, orgID int64, windowID int64, monitorIDs []int, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM maintenance_window_monitors WHERE window_id = ?`, windowID); err != nil {
+		return fmt.Errorf("error deleting maintenance window monitors: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM maintenance_window_tags WHERE window_id = ?`, windowID); err != nil {
+		return fmt.Errorf("error deleting maintenance window tags: %v", err)
+	}
+
+	seen := make(map[int]bool, len(monitorIDs))
+	for _, id := range monitorIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		res, err := tx.ExecContext(ctx, `INSERT INTO maintenance_window_monitors (window_id, monitor_id) SELECT ?, monitor_id FROM monitor WHERE monitor_id = ? AND org_id = ?`, windowID, id, orgID)
+		if err != nil {
+			return fmt.Errorf("error inserting maintenance window monitors: %v", err)
+		}
+		if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+			return fmt.Errorf("%w: monitor_id=%d does not exist", ErrInvalidMaintenanceWindow, id)
+		}
+	}
+
+	for _, tag := range tags {
+		key, value := monitor.ParseTag(tag)
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT IGNORE INTO maintenance_window_tags (window_id, tag_key, tag_value) VALUES (?, ?, ?)`, windowID, key, value); err != nil {
+			return fmt.Errorf("error inserting maintenance window tags: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// maintenanceWindowState reads a window back in the shape it is written, for
+// the audit log.
+func maintenanceWindowState(ctx context.Context, q queryer, windowID int64) (*MaintenanceWindowPayload, error) {
+	var w MaintenanceWindowPayload
+	var startsAt time.Time
+	var endsAt sql.NullTime
+	var cronExpression sql.NullString
+	var durationSecs sql.NullInt64
+
+	err := q.QueryRowContext(ctx, `SELECT window_id, name, mode, starts_at, ends_at, cron_expression, duration_seconds, timezone
+	FROM maintenance_windows
+	WHERE window_id = ?`, windowID).Scan(&w.WindowID, &w.Name, &w.Mode, &startsAt, &endsAt, &cronExpression, &durationSecs, &w.Timezone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMaintenanceWindowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance window: %v", err)
+	}
+
+	w.StartsAt = startsAt.UTC().Format(time.RFC3339)
+	if endsAt.Valid {
+		w.EndsAt = endsAt.Time.UTC().Format(time.RFC3339)
+	}
+	w.CronExpression = cronExpression.String
+	w.DurationSecs = int(durationSecs.Int64)
+	w.MonitorIDs = make([]int, 0)
+	w.Tags = make([]string, 0)
+
+	rows, err := q.QueryContext(ctx, `SELECT monitor_id FROM maintenance_window_monitors WHERE window_id = ? ORDER BY monitor_id`, windowID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance window monitors: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance window monitors: %v", err)
+		}
+		w.MonitorIDs = append(w.MonitorIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance window monitors: %v", err)
+	}
+
+	tagRows, err := q.QueryContext(ctx, `SELECT tag_key, tag_value FROM maintenance_window_tags WHERE window_id = ? ORDER BY tag_key, tag_value`, windowID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance window tags: %v", err)
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var key, value string
+		if err := tagRows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance window tags: %v", err)
+		}
+		w.Tags = append(w.Tags, monitor.FormatTag(key, value))
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance window tags: %v", err)
+	}
+
+	return &w, nil
+}
+
+func InsertMaintenanceWindow(ctx context.Context, db *config.DB, orgID int64, payload MaintenanceWindowPayload) (int64, error) {
+	startsAt, endsAt, err := validateMaintenanceWindow(&payload)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO maintenance_windows (org_id, name, mode, starts_at, ends_at, cron_expression, duration_seconds, timezone) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, query, orgID, payload.Name, payload.Mode, startsAt, endsAt, nullString(payload.CronExpression), nullPositiveInt(payload.DurationSecs), payload.Timezone)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting maintenance window: %v", err)
+	}
+
+	windowID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	if err := replaceMaintenanceWindowScope(ctx, tx, orgID, windowID, payload.MonitorIDs, payload.Tags); err != nil {
+		return 0, err
+	}
+
+	after, err := maintenanceWindowState(ctx, tx, windowID)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordChange(ctx, tx, orgID, "create", AuditMaintenanceWindow, windowID, nil, after); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return windowID, nil
+}
+
+func UpdateMaintenanceWindow(ctx context.Context, db *config.DB, orgID int64, payload MaintenanceWindowPayload) error {
+	startsAt, endsAt, err := validateMaintenanceWindow(&payload)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM maintenance_windows WHERE window_id = ? AND org_id = ? FOR UPDATE`, payload.WindowID, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMaintenanceWindowNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up maintenance window: %v", err)
+	}
+
+	before, err := maintenanceWindowState(ctx, tx, int64(payload.WindowID))
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE maintenance_windows
+	SET name = ?, mode = ?, starts_at = ?, ends_at = ?, cron_expression = ?, duration_seconds = ?, timezone = ?
+	WHERE window_id = ?`
+	if _, err := tx.ExecContext(ctx, query, payload.Name, payload.Mode, startsAt, endsAt, nullString(payload.CronExpression), nullPositiveInt(payload.DurationSecs), payload.Timezone, payload.WindowID); err != nil {
+		return fmt.Errorf("error updating maintenance window: %v", err)
+	}
+
+	if err := replaceMaintenanceWindowScope(ctx, tx, orgID, int64(payload.WindowID), payload.MonitorIDs, payload.Tags); err != nil {
+		return err
+	}
+
+	after, err := maintenanceWindowState(ctx, tx, int64(payload.WindowID))
+	if err != nil {
+		return err
+	}
+	if err := recordChange(ctx, tx, orgID, "update", AuditMaintenanceWindow, int64(payload.WindowID), before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func DeleteMaintenanceWindow(ctx context.Context, db *config.DB, orgID int64, windowID int) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM maintenance_windows WHERE window_id = ? AND org_id = ? FOR UPDATE`, windowID, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMaintenanceWindowNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up maintenance window: %v", err)
+	}
+
+	before, err := maintenanceWindowState(ctx, tx, int64(windowID))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE window_id = ?`, windowID); err != nil {
+		return fmt.Errorf("error deleting maintenance window: %v", err)
+	}
+
+	if err := recordChange(ctx, tx, orgID, "delete", AuditMaintenanceWindow, int64(windowID), before, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func GetMaintenanceWindows(ctx context.Context, db *config.DB, orgID int64) ([]MaintenanceWindow, error) {
+	query := `SELECT window_id, name, mode, starts_at, ends_at, cron_expression, duration_seconds, timezone, created_at
+	FROM maintenance_windows
+	WHERE org_id = ?
+	ORDER BY starts_at DESC`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance windows: %v", err)
+	}
+	defer rows.Close()
+
+	windows := make([]MaintenanceWindow, 0)
+	byID := make(map[int64]int)
+
+	for rows.Next() {
+		var w monitor.MaintenanceWindow
+		var name string
+		var createdAt string
+
+		if err := rows.Scan(&w.ID, &name, &w.Mode, &w.StartsAt, &w.EndsAt, &w.Cron, &w.DurationSecs, &w.Timezone, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance windows: %v", err)
+		}
+
+		window := MaintenanceWindow{
+			WindowID:   w.ID,
+			Name:       name,
+			Mode:       w.Mode,
+			StartsAt:   w.StartsAt.Format(time.RFC3339),
+			Timezone:   w.Timezone,
+			MonitorIDs: make([]int64, 0),
+			Tags:       make([]string, 0),
+			Active:     w.ActiveAt(time.Now()),
+			CreatedAt:  createdAt,
+		}
+		if w.EndsAt.Valid {
+			endsAt := w.EndsAt.Time.Format(time.RFC3339)
+			window.EndsAt = &endsAt
+		}
+		if w.Cron.Valid {
+			window.CronExpression = &w.Cron.String
+		}
+		if w.DurationSecs.Valid {
+			window.DurationSecs = &w.DurationSecs.Int64
+		}
+
+		byID[w.ID] = len(windows)
+		windows = append(windows, window)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance windows: %v", err)
+	}
+
+	monitorRows, err := db.Pool.QueryContext(ctx, `SELECT wm.window_id, wm.monitor_id
+	FROM maintenance_window_monitors wm
+	JOIN maintenance_windows w ON w.window_id = wm.window_id
+	WHERE w.org_id = ?
+	ORDER BY wm.monitor_id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance window monitors: %v", err)
+	}
+	defer monitorRows.Close()
+
+	for monitorRows.Next() {
+		var windowID, monitorID int64
+		if err := monitorRows.Scan(&windowID, &monitorID); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance window monitors: %v", err)
+		}
+		if i, ok := byID[windowID]; ok {
+			windows[i].MonitorIDs = append(windows[i].MonitorIDs, monitorID)
+		}
+	}
+
+	if err := monitorRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance window monitors: %v", err)
+	}
+
+	tagRows, err := db.Pool.QueryContext(ctx, `SELECT wt.window_id, wt.tag_key, wt.tag_value
+	FROM maintenance_window_tags wt
+	JOIN maintenance_windows w ON w.window_id = wt.window_id
+	WHERE w.org_id = ?
+	ORDER BY wt.tag_key, wt.tag_value`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance window tags: %v", err)
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var windowID int64
+		var key, value string
+		if err := tagRows.Scan(&windowID, &key, &value); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance window tags: %v", err)
+		}
+		if i, ok := byID[windowID]; ok {
+			windows[i].Tags = append(windows[i].Tags, monitor.FormatTag(key, value))
+		}
+	}
+
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance window tags: %v", err)
+	}
+
+	return windows, nil
+}
+
+var ErrMonitorNotFound = errors.New("monitor not found")
+
+// MonitorConfig is the part of a monitor users configure. Monitor revisions
+// store it, so it leaves out secrets and scheduler state.
+type MonitorConfig struct {
+	Slug                 string              `json:"slug"`
+	Name                 string              `json:"name"`
+	Url                  string              `json:"url"`
+	MonitorType          string              `json:"monitor_type"`
+	FrequencySecs        int                 `json:"frequency_secs"`
+	ResponseFormat       string              `json:"response_format"`
+	HttpMethod           string              `json:"http_method"`
+	ConnectionTimeout    *int64              `json:"connection_timeout"`
+	RequestHeaders       map[string][]string `json:"request_headers"`
+	ResponseHeaders      map[string][]string `json:"response_headers"`
+	AcceptedStatusCodes  []int               `json:"accepted_status_codes"`
+	RequestBody          *string             `json:"request_body"`
+	ResponsePattern      *string             `json:"response_pattern"`
+	ResponseSchema       *string             `json:"response_schema"`
+	TLSMode              string              `json:"tls_mode"`
+	AuthUsername         *string             `json:"auth_username"`
+	HasAuthPassword      bool                `json:"has_auth_password"`
+	SkipTLSVerify        bool                `json:"skip_tls_verify"`
+	RequiredCapabilities []string            `json:"required_capabilities"`
+	ExpectedResult       *string             `json:"expected_result"`
+	GraceSeconds         int                 `json:"grace_seconds"`
+	PayloadEncoding      string              `json:"payload_encoding"`
+	Locations            []string            `json:"locations"`
+	QuorumFailures       int                 `json:"quorum_failures"`
+	JitterPercent        int                 `json:"jitter_percent"`
+	CronExpression       *string             `json:"cron_expression"`
+	Timezone             string              `json:"timezone"`
+	BusinessHours        *BusinessHours      `json:"business_hours"`
+	OffPeakFrequencySecs *int64              `json:"off_peak_frequency_secs"`
+	Tags                 []string            `json:"tags"`
+	Group                string              `json:"group"`
+}
+
+type MonitorDetail struct {
+	MonitorID int `json:"monitor_id"`
+	MonitorConfig
+	HeartbeatPath string  `json:"heartbeat_path,omitempty"`
+	IsActive      bool    `json:"is_active"`
+	Status        string  `json:"status"`
+	LastRunAt     *string `json:"last_run_at"`
+	NextRunAt     *string `json:"next_run_at"`
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	monitor.Querier
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullInt64Ptr(i sql.NullInt64) *int64 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int64
+}
+
+func nullTimePtr(t sql.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	formatted := t.Time.Format(time.RFC3339)
+	return &formatted
+}
+
+func GetMonitor(ctx context.Context, db *config.DB, orgID int64, monitorID int) (*MonitorDetail, error) {
+	return getMonitor(ctx, db.Pool, orgID, monitorID)
+}
+
+func getMonitor(ctx context.Context, q queryer, orgID int64, monitorID int) (*MonitorDetail, error) {
+	monitors, err := getMonitors(ctx, q, orgID, "monitor_id = ?", monitorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(monitors) == 0 {
+		return nil, ErrMonitorNotFound
+	}
+	return &monitors[0], nil
+}
+
+// getMonitors loads the full configuration of an org's monitors matching
+// where, with their headers, status codes, locations and tags.
+func getMonitors(ctx context.Context, q queryer, orgID int64, where string, args ...any) ([]MonitorDetail, error) {
+	query := `SELECT monitor_id, slug, monitor_name, url, monitor_type, frequency_seconds, response_format, http_method,
+	connection_timeout, request_body, response_pattern, response_schema, tls_mode, auth_username, auth_password IS NOT NULL AND auth_password <> '', skip_tls_verify,
+	required_capabilities, expected_result, grace_seconds, payload_encoding, quorum_failures, jitter_percent,
+	cron_expression, timezone, window_days, window_start, window_end, off_peak_frequency_seconds,
+	heartbeat_token, group_path, COALESCE(is_active, 0), COALESCE(status, 'idle'), last_run_at, next_run_at
+	FROM monitor
+	WHERE org_id = ? AND ` + where + `
+	ORDER BY monitor_id`
+
+	rows, err := q.QueryContext(ctx, query, append([]any{orgID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting monitors: %v", err)
+	}
+	defer rows.Close()
+
+	monitors := make([]MonitorDetail, 0)
+	for rows.Next() {
+		var (
+			m                    MonitorDetail
+			connectionTimeout    sql.NullInt64
+			requestBody          sql.NullString
+			responsePattern      sql.NullString
+			responseSchema       sql.NullString
+			authUsername         sql.NullString
+			requiredCapabilities sql.NullString
+			expectedResult       sql.NullString
+			cronExpression       sql.NullString
+			windowDays           sql.NullString
+			windowStart          sql.NullString
+			windowEnd            sql.NullString
+			offPeakFrequency     sql.NullInt64
+			heartbeatToken       sql.NullString
+			lastRunAt            sql.NullTime
+			nextRunAt            sql.NullTime
+		)
+
+		err := rows.Scan(
+			&m.MonitorID,
+			&m.Slug,
+			&m.Name,
+			&m.Url,
+			&m.MonitorType,
+			&m.FrequencySecs,
+			&m.ResponseFormat,
+			&m.HttpMethod,
+			&connectionTimeout,
+			&requestBody,
+			&responsePattern,
+			&responseSchema,
+			&m.TLSMode,
+			&authUsername,
+			&m.HasAuthPassword,
+			&m.SkipTLSVerify,
+			&requiredCapabilities,
+			&expectedResult,
+			&m.GraceSeconds,
+			&m.PayloadEncoding,
+			&m.QuorumFailures,
+			&m.JitterPercent,
+			&cronExpression,
+			&m.Timezone,
+			&windowDays,
+			&windowStart,
+			&windowEnd,
+			&offPeakFrequency,
+			&heartbeatToken,
+			&m.Group,
+			&m.IsActive,
+			&m.Status,
+			&lastRunAt,
+			&nextRunAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning monitor: %v", err)
+		}
+
+		m.ConnectionTimeout = nullInt64Ptr(connectionTimeout)
+		m.RequestBody = nullStringPtr(requestBody)
+		m.ResponsePattern = nullStringPtr(responsePattern)
+		m.ResponseSchema = nullStringPtr(responseSchema)
+		m.AuthUsername = nullStringPtr(authUsername)
+		m.ExpectedResult = nullStringPtr(expectedResult)
+		m.CronExpression = nullStringPtr(cronExpression)
+		m.OffPeakFrequencySecs = nullInt64Ptr(offPeakFrequency)
+		m.LastRunAt = nullTimePtr(lastRunAt)
+		m.NextRunAt = nullTimePtr(nextRunAt)
+
+		m.RequiredCapabilities = make([]string, 0)
+		if requiredCapabilities.Valid && requiredCapabilities.String != "" {
+			m.RequiredCapabilities = strings.Split(requiredCapabilities.String, ",")
+		}
+		if windowDays.Valid && windowDays.String != "" {
+			m.BusinessHours = &BusinessHours{
+				Days:  strings.Split(windowDays.String, ","),
+				Start: windowStart.String,
+				End:   windowEnd.String,
+			}
+		}
+		if heartbeatToken.Valid {
+			m.HeartbeatPath = monitor.HeartbeatPath(heartbeatToken.String)
+		}
+
+		monitors = append(monitors, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monitors: %v", err)
+	}
+	rows.Close()
+
+	if len(monitors) == 0 {
+		return monitors, nil
+	}
+
+	ids := make([]interface{}, 0, len(monitors))
+	placeholders := make([]string, 0, len(monitors))
+	for _, m := range monitors {
+		ids = append(ids, m.MonitorID)
+		placeholders = append(placeholders, "?")
+	}
+
+	requestHeaders, err := monitor.GetRequestHeadersForMonitor(ctx, q, ids, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	responseHeaders, err := monitor.GetResponseHeadersForMonitor(ctx, q, ids, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := monitor.GetAcceptedStatusCodeForMonitor(ctx, q, ids, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := monitor.GetLocationsForMonitor(ctx, q, ids, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := monitor.GetTagsForMonitor(ctx, q, ids, placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range monitors {
+		m := &monitors[i]
+		m.RequestHeaders = requestHeaders[m.MonitorID]
+		if m.RequestHeaders == nil {
+			m.RequestHeaders = map[string][]string{}
+		}
+		m.ResponseHeaders = responseHeaders[m.MonitorID]
+		if m.ResponseHeaders == nil {
+			m.ResponseHeaders = map[string][]string{}
+		}
+		m.AcceptedStatusCodes = codes[m.MonitorID]
+		if m.AcceptedStatusCodes == nil {
+			m.AcceptedStatusCodes = []int{}
+		}
+		m.Locations = locations[m.MonitorID]
+		if m.Locations == nil {
+			m.Locations = []string{}
+		}
+		m.Tags = tags[m.MonitorID]
+		if m.Tags == nil {
+			m.Tags = []string{}
+		}
+	}
+
+	return monitors, nil
+}
+
+func monitorExists(ctx context.Context, db *config.DB, orgID int64, monitorID int) error {
+	var exists int
+	err := db.Pool.QueryRowContext(ctx, `SELECT 1 FROM monitor WHERE monitor_id = ? AND org_id = ?`, monitorID, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMonitorNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up monitor_id=%d: %v", monitorID, err)
+	}
+	return nil
+}
+
+func ResumeMonitor(ctx context.Context, db *config.DB, orgID int64, monitorID int) error {
+	if err := monitorExists(ctx, db, orgID, monitorID); err != nil {
+		return err
+	}
+
+	query := `UPDATE monitor
+	SET is_active = 1,
+	status = 'idle',
+	next_run_at = IF(monitor_type = 'heartbeat', DATE_ADD(NOW(), INTERVAL frequency_seconds + grace_seconds SECOND), next_run_at),
+	heartbeat_state = IF(monitor_type = 'heartbeat', 'new', heartbeat_state)
+	WHERE monitor_id = ? AND org_id = ? AND COALESCE(is_active, 0) = 0`
+
+	return setMonitorActive(ctx, db, orgID, monitorID, query, "resume")
+}
+
+// monitorChildTables lists every table that references a monitor, in the
+// order rows have to be removed before the monitor itself.
+var monitorChildTables = []string{
+	"monitor_request_headers",
+	"monitor_response_headers",
+	"monitor_accepted_status_codes",
+	"monitor_locations",
+	"monitor_tags",
+	"maintenance_window_monitors",
+	"monitor_verdicts",
+	"monitor_revisions",
+	"results",
+}
+
+func DeleteMonitor(ctx context.Context, db *config.DB, orgID int64, monitorID int) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM monitor WHERE monitor_id = ? AND org_id = ? FOR UPDATE`, monitorID, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMonitorNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up monitor_id=%d: %v", monitorID, err)
+	}
+
+	before, err := getMonitor(ctx, tx, orgID, monitorID)
+	if err != nil {
+		return err
+	}
+	if err := recordMonitorChange(ctx, tx, orgID, "delete", int64(monitorID), before, nil, nil); err != nil {
+		return err
+	}
+
+	for _, table := range monitorChildTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE monitor_id = ?", table), monitorID); err != nil {
+			return fmt.Errorf("error deleting %s for monitor_id=%d: %v", table, monitorID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM monitor WHERE monitor_id = ?`, monitorID); err != nil {
+		return fmt.Errorf("error deleting monitor_id=%d: %v", monitorID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+	// ScopePlatform is only held by the bootstrap key and manages
+	// organizations across the whole deployment.
+	ScopePlatform = "platform"
+)
+
+// scopeRank orders scopes so that each one implies those below it.
+var scopeRank = map[string]int{ScopeRead: 1, ScopeWrite: 2, ScopeAdmin: 3, ScopePlatform: 4}
+
+const apiKeyPrefix = "pk_"
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+type APIKey struct {
+	KeyID      int64    `json:"key_id"`
+	OrgID      int64    `json:"org_id"`
+	UserID     *int64   `json:"user_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+func (k *APIKey) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if scopeRank[s] >= scopeRank[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIKey is what gets stored; keys are random enough that a plain
+// SHA-256 can not be brute forced.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+func displayPrefix(key string) string {
+	return key[:min(len(key), len(apiKeyPrefix)+8)]
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertAPIKey(ctx context.Context, ex execer, orgID int64, userID *int64, name string, scopes []string, expiresAt sql.NullTime) (*APIKey, string, error) {
+	key, err := NewAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating api key: %v", err)
+	}
+
+	query := `INSERT INTO api_keys (org_id, user_id, name, key_prefix, key_hash, scopes, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := ex.ExecContext(ctx, query, orgID, userID, name, displayPrefix(key), HashAPIKey(key), strings.Join(scopes, ","), expiresAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("error inserting api key: %v", err)
+	}
+
+	keyID, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	created := &APIKey{
+		KeyID:     keyID,
+		OrgID:     orgID,
+		UserID:    userID,
+		Name:      name,
+		Prefix:    displayPrefix(key),
+		Scopes:    scopes,
+		ExpiresAt: nullTimePtr(expiresAt),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	return created, key, nil
+}
+
+func InsertAPIKey(ctx context.Context, db *config.DB, orgID int64, payload APIKeyPayload) (*APIKey, string, error) {
+	var errs validation.Errors
+
+	if strings.TrimSpace(payload.Name) == "" {
+		errs.Add("name", "is required")
+	}
+	errs.MaxLen("name", payload.Name, 255)
+
+	if len(payload.Scopes) == 0 {
+		errs.Add("scopes", "at least one scope is required")
+	}
+	for i, scope := range payload.Scopes {
+		errs.Enum(fmt.Sprintf("scopes[%d]", i), scope, ScopeRead, ScopeWrite, ScopeAdmin)
+	}
+
+	var expiresAt sql.NullTime
+	if payload.ExpiresAt != "" {
+		t, err := parseTimestamp(payload.ExpiresAt)
+		if err != nil {
+			errs.Add("expires_at", "must be an RFC3339 timestamp")
+		} else if !t.After(time.Now()) {
+			errs.Add("expires_at", "must be in the future")
+		}
+		expiresAt = sql.NullTime{Time: t, Valid: err == nil}
+	}
+
+	if payload.UserID != nil {
+		role, err := memberRole(ctx, db, orgID, *payload.UserID)
+		if errors.Is(err, ErrMemberNotFound) {
+			errs.Add("user_id", "must be a member of the organization")
+		} else if err != nil {
+			return nil, "", err
+		}
+		for i, scope := range payload.Scopes {
+			if role != "" && scopeRank[scope] > scopeRank[roleScope[role]] {
+				errs.Add(fmt.Sprintf("scopes[%d]", i), "exceeds what the %s role allows", role)
+			}
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, "", err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return nil, "", fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	created, key, err := insertAPIKey(ctx, tx, orgID, payload.UserID, payload.Name, payload.Scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := recordChange(ctx, tx, orgID, "create", AuditAPIKey, created.KeyID, nil, created); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return created, key, nil
+}
+
+func GetAPIKeys(ctx context.Context, db *config.DB, orgID int64) ([]APIKey, error) {
+	query := `SELECT key_id, org_id, user_id, name, key_prefix, scopes, expires_at, last_used_at, created_at
+	FROM api_keys
+	WHERE org_id = ?
+	ORDER BY key_id`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting api keys: %v", err)
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		var k APIKey
+		var userID sql.NullInt64
+		var scopes string
+		var expiresAt, lastUsedAt sql.NullTime
+		var createdAt time.Time
+
+		if err := rows.Scan(&k.KeyID, &k.OrgID, &userID, &k.Name, &k.Prefix, &scopes, &expiresAt, &lastUsedAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning api keys: %v", err)
+		}
+
+		k.UserID = nullInt64Ptr(userID)
+		k.Scopes = strings.Split(scopes, ",")
+		k.ExpiresAt = nullTimePtr(expiresAt)
+		k.LastUsedAt = nullTimePtr(lastUsedAt)
+		k.CreatedAt = createdAt.Format(time.RFC3339)
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %v", err)
+	}
+
+	return keys, nil
+}
+
+func DeleteAPIKey(ctx context.Context, db *config.DB, orgID int64, keyID int) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var k APIKey
+	var userID sql.NullInt64
+	var scopes string
+	var expiresAt sql.NullTime
+
+	err = tx.QueryRowContext(ctx, `SELECT key_id, org_id, user_id, name, key_prefix, scopes, expires_at FROM api_keys WHERE key_id = ? AND org_id = ? FOR UPDATE`, keyID, orgID).
+		Scan(&k.KeyID, &k.OrgID, &userID, &k.Name, &k.Prefix, &scopes, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up api key: %v", err)
+	}
+	k.UserID = nullInt64Ptr(userID)
+	k.Scopes = strings.Split(scopes, ",")
+	k.ExpiresAt = nullTimePtr(expiresAt)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE key_id = ?`, keyID); err != nil {
+		return fmt.Errorf("error deleting api key: %v", err)
+	}
+
+	if err := recordChange(ctx, tx, orgID, "delete", AuditAPIKey, k.KeyID, &k, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey looks a presented key up by its hash. A key that
+// belongs to a user never grants more than the user's role in the
+// organization, and stops working when the user leaves it. last_used_at is
+// only written once a minute so busy clients do not turn every read into a
+// write.
+func AuthenticateAPIKey(ctx context.Context, db *config.DB, key string) (*APIKey, error) {
+	query := `SELECT k.key_id, k.org_id, k.user_id, k.name, k.key_prefix, k.scopes, k.expires_at, k.last_used_at, k.created_at, mb.role
+	FROM api_keys k
+	LEFT JOIN memberships mb ON mb.org_id = k.org_id AND mb.user_id = k.user_id
+	WHERE k.key_hash = ? AND (k.expires_at IS NULL OR k.expires_at > NOW())`
+
+	var k APIKey
+	var userID sql.NullInt64
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	var createdAt time.Time
+	var role sql.NullString
+
+	err := db.Pool.QueryRowContext(ctx, query, HashAPIKey(key)).Scan(&k.KeyID, &k.OrgID, &userID, &k.Name, &k.Prefix, &scopes, &expiresAt, &lastUsedAt, &createdAt, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up api key: %v", err)
+	}
+	if userID.Valid && !role.Valid {
+		return nil, ErrInvalidAPIKey
+	}
+
+	k.UserID = nullInt64Ptr(userID)
+	k.Scopes = strings.Split(scopes, ",")
+	if role.Valid {
+		k.Scopes = capScopes(k.Scopes, role.String)
+	}
+	k.ExpiresAt = nullTimePtr(expiresAt)
+	k.LastUsedAt = nullTimePtr(lastUsedAt)
+	k.CreatedAt = createdAt.Format(time.RFC3339)
+
+	touch := `UPDATE api_keys SET last_used_at = NOW()
+	WHERE key_id = ? AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL 1 MINUTE)`
+	if _, err := db.Pool.ExecContext(ctx, touch, k.KeyID); err != nil {
+		return nil, fmt.Errorf("error updating api key last use: %v", err)
+	}
+
+	return &k, nil
+}
+
+const DefaultOrgID int64 = 1
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// roleScope is the widest API key scope a member with the role can use.
+var roleScope = map[string]string{RoleOwner: ScopeAdmin, RoleEditor: ScopeWrite, RoleViewer: ScopeRead}
+
+// capScopes lowers every scope above what the role allows to the role's own
+// scope.
+func capScopes(scopes []string, role string) []string {
+	capped := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scopeRank[scope] > scopeRank[roleScope[role]] {
+			scope = roleScope[role]
+		}
+		capped = append(capped, scope)
+	}
+	return capped
+}
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrQuotaExceeded        = errors.New("organization quota exceeded")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrMemberExists         = errors.New("user is already a member")
+	ErrLastOwner            = errors.New("organization needs at least one owner")
+)
+
+type Organization struct {
+	OrgID            int64  `json:"org_id"`
+	Name             string `json:"name"`
+	MaxMonitors      int    `json:"max_monitors"`
+	MinFrequencySecs int    `json:"min_frequency_secs"`
+	Monitors         int    `json:"monitors"`
+	CreatedAt        string `json:"created_at"`
+}
+
+type Member struct {
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+// OrgQuota holds an organization's limits; zero means unlimited.
+type OrgQuota struct {
+	MaxMonitors      int
+	MinFrequencySecs int
+}
+
+func orgQuota(ctx context.Context, tx *sql.Tx, orgID int64) (*OrgQuota, error) {
+	return queryOrgQuota(ctx, tx, orgID, "")
+}
+
+// lockOrgQuota also locks the organization row, so concurrent creates can
+// not both pass the monitor count check.
+func lockOrgQuota(ctx context.Context, tx *sql.Tx, orgID int64) (*OrgQuota, error) {
+	return queryOrgQuota(ctx, tx, orgID, " FOR UPDATE")
+}
+
+func queryOrgQuota(ctx context.Context, tx *sql.Tx, orgID int64, lock string) (*OrgQuota, error) {
+	var q OrgQuota
+	err := tx.QueryRowContext(ctx, `SELECT max_monitors, min_frequency_seconds FROM organizations WHERE org_id = ?`+lock, orgID).Scan(&q.MaxMonitors, &q.MinFrequencySecs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting quota for org_id=%d: %v", orgID, err)
+	}
+	return &q, nil
+}
+
+func (q *OrgQuota) checkMonitorCount(ctx context.Context, tx *sql.Tx, orgID int64) error {
+	if q.MaxMonitors <= 0 {
+		return nil
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM monitor WHERE org_id = ?`, orgID).Scan(&count); err != nil {
+		return fmt.Errorf("error counting monitors for org_id=%d: %v", orgID, err)
+	}
+	if count >= q.MaxMonitors {
+		return fmt.Errorf("%w: at most %d monitors", ErrQuotaExceeded, q.MaxMonitors)
+	}
+
+	return nil
+}
+
+// checkFrequency rejects schedules that would run a monitor more often than
+// the organization allows. Nil fields are left unchecked.
+func (q *OrgQuota) checkFrequency(frequencySecs *int, offPeakFrequencySecs *int, cronExpr *string) error {
+	if q.MinFrequencySecs <= 0 {
+		return nil
+	}
+
+	var errs validation.Errors
+	if frequencySecs != nil && *frequencySecs < q.MinFrequencySecs {
+		errs.Add("frequency_secs", "must be at least %d for this organization", q.MinFrequencySecs)
+	}
+	if offPeakFrequencySecs != nil && *offPeakFrequencySecs > 0 && *offPeakFrequencySecs < q.MinFrequencySecs {
+		errs.Add("off_peak_frequency_secs", "must be at least %d for this organization", q.MinFrequencySecs)
+	}
+	if cronExpr != nil && *cronExpr != "" {
+		if sched, err := monitor.ParseCron(*cronExpr); err == nil {
+			if gap := monitor.MinCronInterval(sched, time.Now(), 1000); gap > 0 && gap < time.Duration(q.MinFrequencySecs)*time.Second {
+				errs.Add("cron_expression", "must not fire more often than every %d seconds for this organization", q.MinFrequencySecs)
+			}
+		}
+	}
+
+	return errs.Err()
+}
+
+func validateOrganization(name *string, maxMonitors *int, minFrequencySecs *int, errs *validation.Errors) {
+	if name != nil {
+		if strings.TrimSpace(*name) == "" {
+			errs.Add("name", "must not be empty")
+		}
+		errs.MaxLen("name", *name, 255)
+	}
+	if maxMonitors != nil {
+		errs.Min("max_monitors", *maxMonitors, 0)
+	}
+	if minFrequencySecs != nil {
+		errs.Min("min_frequency_secs", *minFrequencySecs, 0)
+	}
+}
+
+func validateMember(email string, name string, role string, errs *validation.Errors) {
+	if _, err := mail.ParseAddress(email); err != nil || !strings.Contains(email, "@") {
+		errs.Add("email", "must be an email address")
+	}
+	errs.MaxLen("email", email, 255)
+	errs.MaxLen("name", name, 255)
+	errs.Enum("role", role, RoleOwner, RoleEditor, RoleViewer)
+}
+
+// upsertUser returns the id of the user with the email, creating it first if
+// needed.
+func upsertUser(ctx context.Context, tx *sql.Tx, email string, name string) (int64, error) {
+	query := `INSERT INTO users (email, name) VALUES (?, ?)
+	ON DUPLICATE KEY UPDATE user_id = LAST_INSERT_ID(user_id)`
+
+	res, err := tx.ExecContext(ctx, query, strings.ToLower(email), name)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting user: %v", err)
+	}
+
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+	return userID, nil
+}
+
+// InsertOrganization creates an organization with its first owner and an
+// admin key for that owner, so the organization can manage itself.
+func InsertOrganization(ctx context.Context, db *config.DB, payload OrganizationPayload) (*Organization, string, error) {
+	var errs validation.Errors
+	if strings.TrimSpace(payload.Name) == "" {
+		errs.Add("name", "is required")
+	}
+	validateOrganization(&payload.Name, &payload.MaxMonitors, &payload.MinFrequencySecs, &errs)
+	validateMember(payload.OwnerEmail, payload.OwnerName, RoleOwner, &errs)
+	if err := errs.Err(); err != nil {
+		return nil, "", err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return nil, "", fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO organizations (name, max_monitors, min_frequency_seconds) VALUES (?, ?, ?)`, payload.Name, payload.MaxMonitors, payload.MinFrequencySecs)
+	if err != nil {
+		return nil, "", fmt.Errorf("error inserting organization: %v", err)
+	}
+
+	orgID, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	userID, err := upsertUser(ctx, tx, payload.OwnerEmail, payload.OwnerName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO memberships (org_id, user_id, role) VALUES (?, ?, ?)`, orgID, userID, RoleOwner); err != nil {
+		return nil, "", fmt.Errorf("error inserting owner membership: %v", err)
+	}
+
+	created, key, err := insertAPIKey(ctx, tx, orgID, &userID, "owner", []string{ScopeAdmin}, sql.NullTime{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	org := &Organization{
+		OrgID:            orgID,
+		Name:             payload.Name,
+		MaxMonitors:      payload.MaxMonitors,
+		MinFrequencySecs: payload.MinFrequencySecs,
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+	}
+
+	owner := map[string]string{"email": payload.OwnerEmail, "name": payload.OwnerName, "role": RoleOwner}
+	if err := recordChange(ctx, tx, orgID, "create", AuditOrganization, orgID, nil, org); err != nil {
+		return nil, "", err
+	}
+	if err := recordChange(ctx, tx, orgID, "add", AuditMember, userID, nil, owner); err != nil {
+		return nil, "", err
+	}
+	if err := recordChange(ctx, tx, orgID, "create", AuditAPIKey, created.KeyID, nil, created); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return org, key, nil
+}
+
+const organizationColumns = `SELECT o.org_id, o.name, o.max_monitors, o.min_frequency_seconds, o.created_at,
+	(SELECT COUNT(*) FROM monitor m WHERE m.org_id = o.org_id)
+	FROM organizations o`
+
+func scanOrganization(row interface{ Scan(...any) error }) (*Organization, error) {
+	var o Organization
+	var createdAt time.Time
+	if err := row.Scan(&o.OrgID, &o.Name, &o.MaxMonitors, &o.MinFrequencySecs, &createdAt, &o.Monitors); err != nil {
+		return nil, err
+	}
+	o.CreatedAt = createdAt.Format(time.RFC3339)
+	return &o, nil
+}
+
+func GetOrganizations(ctx context.Context, db *config.DB) ([]Organization, error) {
+	rows, err := db.Pool.QueryContext(ctx, organizationColumns+` ORDER BY o.org_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting organizations: %v", err)
+	}
+	defer rows.Close()
+
+	orgs := make([]Organization, 0)
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning organizations: %v", err)
+		}
+		orgs = append(orgs, *o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %v", err)
+	}
+
+	return orgs, nil
+}
+
+func GetOrganization(ctx context.Context, db *config.DB, orgID int64) (*Organization, error) {
+	o, err := scanOrganization(db.Pool.QueryRowContext(ctx, organizationColumns+` WHERE o.org_id = ?`, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting org_id=%d: %v", orgID, err)
+	}
+	return o, nil
+}
+
+func UpdateOrganization(ctx context.Context, db *config.DB, orgID int64, payload UpdateOrganizationPayload) error {
+	var errs validation.Errors
+	validateOrganization(payload.Name, payload.MaxMonitors, payload.MinFrequencySecs, &errs)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	setParts := make([]string, 0, 3)
+	args := make([]interface{}, 0, 4)
+	if payload.Name != nil {
+		setParts = append(setParts, "name = ?")
+		args = append(args, *payload.Name)
+	}
+	if payload.MaxMonitors != nil {
+		setParts = append(setParts, "max_monitors = ?")
+		args = append(args, *payload.MaxMonitors)
+	}
+	if payload.MinFrequencySecs != nil {
+		setParts = append(setParts, "min_frequency_seconds = ?")
+		args = append(args, *payload.MinFrequencySecs)
+	}
+
+	if err := organizationExists(ctx, db, orgID); err != nil {
+		return err
+	}
+	if len(setParts) == 0 {
+		return nil
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanOrganization(tx.QueryRowContext(ctx, organizationColumns+` WHERE o.org_id = ? FOR UPDATE`, orgID))
+	if err != nil {
+		return fmt.Errorf("error getting org_id=%d: %v", orgID, err)
+	}
+
+	query := fmt.Sprintf("UPDATE organizations SET %s WHERE org_id = ?", strings.Join(setParts, ", "))
+	args = append(args, orgID)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error updating org_id=%d: %v", orgID, err)
+	}
+
+	after, err := scanOrganization(tx.QueryRowContext(ctx, organizationColumns+` WHERE o.org_id = ?`, orgID))
+	if err != nil {
+		return fmt.Errorf("error getting org_id=%d: %v", orgID, err)
+	}
+	if err := recordChange(ctx, tx, orgID, "update", AuditOrganization, orgID, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func organizationExists(ctx context.Context, db *config.DB, orgID int64) error {
+	var exists int
+	err := db.Pool.QueryRowContext(ctx, `SELECT 1 FROM organizations WHERE org_id = ?`, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrganizationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up org_id=%d: %v", orgID, err)
+	}
+	return nil
+}
+
+func memberRole(ctx context.Context, db *config.DB, orgID int64, userID int64) (string, error) {
+	var role string
+	err := db.Pool.QueryRowContext(ctx, `SELECT role FROM memberships WHERE org_id = ? AND user_id = ?`, orgID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrMemberNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error looking up membership: %v", err)
+	}
+	return role, nil
+}
+
+func GetMembers(ctx context.Context, db *config.DB, orgID int64) ([]Member, error) {
+	query := `SELECT u.user_id, u.email, u.name, mb.role, mb.created_at
+	FROM memberships mb
+	JOIN users u ON u.user_id = mb.user_id
+	WHERE mb.org_id = ?
+	ORDER BY u.email`
+
+	rows, err := db.Pool.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting members: %v", err)
+	}
+	defer rows.Close()
+
+	members := make([]Member, 0)
+	for rows.Next() {
+		var m Member
+		var createdAt time.Time
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Name, &m.Role, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning members: %v", err)
+		}
+		m.CreatedAt = createdAt.Format(time.RFC3339)
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating members: %v", err)
+	}
+
+	return members, nil
+}
+
+func AddMember(ctx context.Context, db *config.DB, orgID int64, payload MemberPayload) (int64, error) {
+	var errs validation.Errors
+	validateMember(payload.Email, payload.Name, payload.Role, &errs)
+	if err := errs.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	userID, err := upsertUser(ctx, tx, payload.Email, payload.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT IGNORE INTO memberships (org_id, user_id, role) VALUES (?, ?, ?)`, orgID, userID, payload.Role)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting membership: %v", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return 0, ErrMemberExists
+	}
+
+	member := map[string]string{"email": payload.Email, "name": payload.Name, "role": payload.Role}
+	if err := recordChange(ctx, tx, orgID, "add", AuditMember, userID, nil, member); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return userID, nil
+}
+
+// checkRemainingOwner locks the membership and fails with ErrLastOwner
+// when changing it would leave the organization without an owner.
+func checkRemainingOwner(ctx context.Context, tx *sql.Tx, orgID int64, userID int64) (string, error) {
+	var role string
+	err := tx.QueryRowContext(ctx, `SELECT role FROM memberships WHERE org_id = ? AND user_id = ? FOR UPDATE`, orgID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrMemberNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error looking up membership: %v", err)
+	}
+	if role != RoleOwner {
+		return role, nil
+	}
+
+	var owners int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM memberships WHERE org_id = ? AND role = 'owner' FOR UPDATE`, orgID).Scan(&owners); err != nil {
+		return "", fmt.Errorf("error counting owners: %v", err)
+	}
+	if owners <= 1 {
+		return role, ErrLastOwner
+	}
+
+	return role, nil
+}
+
+func UpdateMemberRole(ctx context.Context, db *config.DB, orgID int64, userID int64, role string) error {
+	var errs validation.Errors
+	errs.Enum("role", role, RoleOwner, RoleEditor, RoleViewer)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current, err := checkRemainingOwner(ctx, tx, orgID, userID)
+	if err != nil && !(errors.Is(err, ErrLastOwner) && role == RoleOwner) {
+		return err
+	}
+	if current == role {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE memberships SET role = ? WHERE org_id = ? AND user_id = ?`, role, orgID, userID); err != nil {
+		return fmt.Errorf("error updating membership: %v", err)
+	}
+
+	if err := recordChange(ctx, tx, orgID, "update", AuditMember, userID, map[string]string{"role": current}, map[string]string{"role": role}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveMember also deletes the member's API keys in the organization.
+func RemoveMember(ctx context.Context, db *config.DB, orgID int64, userID int64) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	role, err := checkRemainingOwner(ctx, tx, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE org_id = ? AND user_id = ?`, orgID, userID); err != nil {
+		return fmt.Errorf("error deleting member api keys: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM memberships WHERE org_id = ? AND user_id = ?`, orgID, userID); err != nil {
+		return fmt.Errorf("error deleting membership: %v", err)
+	}
+
+	if err := recordChange(ctx, tx, orgID, "remove", AuditMember, userID, map[string]string{"role": role}, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+const (
+	AuditMonitor           = "monitor"
+	AuditMaintenanceWindow = "maintenance_window"
+	AuditAPIKey            = "api_key"
+	AuditMember            = "member"
+	AuditOrganization      = "organization"
+)
+
+var ErrRevisionNotFound = errors.New("monitor revision not found")
+
+type AuditActor struct {
+	KeyID  *int64 `json:"key_id"`
+	UserID *int64 `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+type AuditEntry struct {
+	AuditID      int64                  `json:"audit_id"`
+	Actor        AuditActor             `json:"actor"`
+	Action       string                 `json:"action"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   int64                  `json:"resource_id"`
+	Changes      map[string]FieldChange `json:"changes"`
+	CreatedAt    string                 `json:"created_at"`
+}
+
+type MonitorRevision struct {
+	MonitorID int64         `json:"monitor_id"`
+	Revision  int           `json:"revision"`
+	Actor     AuditActor    `json:"actor"`
+	Config    MonitorConfig `json:"config"`
+	CreatedAt string        `json:"created_at"`
+}
+
+// auditActor is the API key that made the request in ctx.
+func auditActor(ctx context.Context) AuditActor {
+	key := APIKeyFromContext(ctx)
+	if key == nil {
+		return AuditActor{Name: "system"}
+	}
+
+	actor := AuditActor{UserID: key.UserID, Name: key.Name}
+	if key.KeyID != 0 {
+		actor.KeyID = &key.KeyID
+	}
+	return actor
+}
+
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// diffChanges compares the JSON fields of before and after. A nil before
+// records a creation and a nil after a deletion.
+func diffChanges(before any, after any) (map[string]FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding audit state: %v", err)
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding audit state: %v", err)
+	}
+
+	changes := make(map[string]FieldChange)
+	for field, value := range afterFields {
+		if old, ok := beforeFields[field]; !ok || !bytes.Equal(old, value) {
+			changes[field] = FieldChange{Before: beforeFields[field], After: value}
+		}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changes[field] = FieldChange{Before: old}
+		}
+	}
+
+	return changes, nil
+}
+
+// recordAudit appends an entry to the audit log. Callers pass the
+// transaction of the change, so a change is never stored without its entry.
+func recordAudit(ctx context.Context, ex execer, orgID int64, action string, resourceType string, resourceID int64, changes map[string]FieldChange) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("error encoding audit changes: %v", err)
+	}
+
+	actor := auditActor(ctx)
+
+	query := `INSERT INTO audit_log (org_id, actor_key_id, actor_user_id, actor_name, action, resource_type, resource_id, changes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := ex.ExecContext(ctx, query, orgID, actor.KeyID, actor.UserID, actor.Name, action, resourceType, resourceID, data); err != nil {
+		return fmt.Errorf("error inserting audit entry: %v", err)
+	}
+
+	return nil
+}
+
+func recordChange(ctx context.Context, ex execer, orgID int64, action string, resourceType string, resourceID int64, before any, after any) error {
+	changes, err := diffChanges(before, after)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, ex, orgID, action, resourceType, resourceID, changes)
+}
+
+func recordMonitorRevision(ctx context.Context, tx *sql.Tx, monitorID int64, cfg MonitorConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("error encoding monitor revision: %v", err)
+	}
+
+	actor := auditActor(ctx)
+
+	query := `INSERT INTO monitor_revisions (monitor_id, revision, config, actor_key_id, actor_user_id, actor_name)
+	SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ?
+	FROM monitor_revisions
+	WHERE monitor_id = ?`
+	if _, err := tx.ExecContext(ctx, query, monitorID, data, actor.KeyID, actor.UserID, actor.Name, monitorID); err != nil {
+		return fmt.Errorf("error inserting revision for monitor_id=%d: %v", monitorID, err)
+	}
+
+	return nil
+}
+
+// recordMonitorChange audits a monitor change from its state before and
+// after, and stores a new revision when its configuration changed.
+func recordMonitorChange(ctx context.Context, tx *sql.Tx, orgID int64, action string, monitorID int64, before *MonitorDetail, after *MonitorDetail, extra map[string]FieldChange) error {
+	var beforeConfig, afterConfig *MonitorConfig
+	if before != nil {
+		beforeConfig = &before.MonitorConfig
+	}
+	if after != nil {
+		afterConfig = &after.MonitorConfig
+	}
+
+	changes, err := diffChanges(beforeConfig, afterConfig)
+	if err != nil {
+		return err
+	}
+
+	if afterConfig != nil && len(changes) > 0 {
+		if err := recordMonitorRevision(ctx, tx, monitorID, *afterConfig); err != nil {
+			return err
+		}
+	}
+
+	for field, change := range extra {
+		changes[field] = change
+	}
+
+	return recordAudit(ctx, tx, orgID, action, AuditMonitor, monitorID, changes)
+}
+
+type AuditFilter struct {
+	ResourceType string
+	ResourceID   int64
+	Cursor       int64
+	Limit        int
+}
+
+func GetAuditLog(ctx context.Context, db *config.DB, orgID int64, filter AuditFilter) ([]AuditEntry, *int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+
+	query := `SELECT audit_id, actor_key_id, actor_user_id, actor_name, action, resource_type, resource_id, changes, created_at
+	FROM audit_log
+	WHERE org_id = ?`
+	args := []interface{}{orgID}
+	if filter.ResourceType != "" {
+		query += ` AND resource_type = ?`
+		args = append(args, filter.ResourceType)
+	}
+	if filter.ResourceID > 0 {
+		query += ` AND resource_id = ?`
+		args = append(args, filter.ResourceID)
+	}
+	if filter.Cursor > 0 {
+		query += ` AND audit_id < ?`
+		args = append(args, filter.Cursor)
+	}
+	query += ` ORDER BY audit_id DESC LIMIT ?`
+	args = append(args, filter.Limit+1)
+
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		var keyID, userID sql.NullInt64
+		var changes []byte
+		var createdAt time.Time
+
+		if err := rows.Scan(&e.AuditID, &keyID, &userID, &e.Actor.Name, &e.Action, &e.ResourceType, &e.ResourceID, &changes, &createdAt); err != nil {
+			return nil, nil, fmt.Errorf("error scanning audit log: %v", err)
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, nil, fmt.Errorf("error decoding changes of audit_id=%d: %v", e.AuditID, err)
+		}
+
+		e.Actor.KeyID = nullInt64Ptr(keyID)
+		e.Actor.UserID = nullInt64Ptr(userID)
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating audit log: %v", err)
+	}
+
+	var nextCursor *int64
+	if len(entries) > filter.Limit {
+		cursorValue := entries[filter.Limit-1].AuditID
+		nextCursor = &cursorValue
+		entries = entries[:filter.Limit]
+	}
+
+	return entries, nextCursor, nil
+}
+
+func scanMonitorRevision(row interface{ Scan(...any) error }) (*MonitorRevision, error) {
+	var rev MonitorRevision
+	var keyID, userID sql.NullInt64
+	var config []byte
+	var createdAt time.Time
+
+	if err := row.Scan(&rev.MonitorID, &rev.Revision, &config, &keyID, &userID, &rev.Actor.Name, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(config, &rev.Config); err != nil {
+		return nil, fmt.Errorf("error decoding revision %d: %v", rev.Revision, err)
+	}
+
+	rev.Actor.KeyID = nullInt64Ptr(keyID)
+	rev.Actor.UserID = nullInt64Ptr(userID)
+	rev.CreatedAt = createdAt.Format(time.RFC3339)
+	return &rev, nil
+}
+
+func GetMonitorRevisions(ctx context.Context, db *config.DB, orgID int64, monitorID int) ([]MonitorRevision, error) {
+	if err := monitorExists(ctx, db, orgID, monitorID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT monitor_id, revision, config, actor_key_id, actor_user_id, actor_name, created_at
+	FROM monitor_revisions
+	WHERE monitor_id = ?
+	ORDER BY revision DESC`
+
+	rows, err := db.Pool.QueryContext(ctx, query, monitorID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting revisions for monitor_id=%d: %v", monitorID, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]MonitorRevision, 0)
+	for rows.Next() {
+		rev, err := scanMonitorRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning revisions: %v", err)
+		}
+		revisions = append(revisions, *rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revisions: %v", err)
+	}
+
+	return revisions, nil
+}
+
+func GetMonitorRevision(ctx context.Context, db *config.DB, orgID int64, monitorID int, revision int) (*MonitorRevision, error) {
+	query := `SELECT r.monitor_id, r.revision, r.config, r.actor_key_id, r.actor_user_id, r.actor_name, r.created_at
+	FROM monitor_revisions r
+	JOIN monitor m ON m.monitor_id = r.monitor_id
+	WHERE r.monitor_id = ? AND r.revision = ? AND m.org_id = ?`
+
+	rev, err := scanMonitorRevision(db.Pool.QueryRowContext(ctx, query, monitorID, revision, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := monitorExists(ctx, db, orgID, monitorID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting revision %d of monitor_id=%d: %v", revision, monitorID, err)
+	}
+
+	return rev, nil
+}
+
+// rollbackPayload sets every field of a revision, clearing the optional ones
+// the revision did not have. The password is not part of revisions and is
+// left as it is, as is the slug so that rollbacks can not collide.
+func (cfg MonitorConfig) rollbackPayload(monitorID int) UpdateMonitorPayload {
+	connectionTimeout := 0
+	if cfg.ConnectionTimeout != nil {
+		connectionTimeout = int(*cfg.ConnectionTimeout)
+	}
+	offPeakFrequencySecs := 0
+	if cfg.OffPeakFrequencySecs != nil {
+		offPeakFrequencySecs = int(*cfg.OffPeakFrequencySecs)
+	}
+	businessHours := cfg.BusinessHours
+	if businessHours == nil {
+		businessHours = &BusinessHours{}
+	}
+	deref := func(s *string) *string {
+		if s == nil {
+			return new(string)
+		}
+		return s
+	}
+
+	return UpdateMonitorPayload{
+		MonitorID:            monitorID,
+		Name:                 &cfg.Name,
+		Url:                  &cfg.Url,
+		FrequencySecs:        &cfg.FrequencySecs,
+		ResponseFormat:       &cfg.ResponseFormat,
+		HttpMethod:           &cfg.HttpMethod,
+		ConnectionTimeout:    &connectionTimeout,
+		RequestHeaders:       &cfg.RequestHeaders,
+		ResponseHeaders:      &cfg.ResponseHeaders,
+		AcceptedStatusCodes:  &cfg.AcceptedStatusCodes,
+		RequestBody:          deref(cfg.RequestBody),
+		MonitorType:          &cfg.MonitorType,
+		ResponsePattern:      deref(cfg.ResponsePattern),
+		ResponseSchema:       deref(cfg.ResponseSchema),
+		TLSMode:              &cfg.TLSMode,
+		AuthUsername:         deref(cfg.AuthUsername),
+		SkipTLSVerify:        &cfg.SkipTLSVerify,
+		RequiredCapabilities: &cfg.RequiredCapabilities,
+		ExpectedResult:       deref(cfg.ExpectedResult),
+		GraceSeconds:         &cfg.GraceSeconds,
+		PayloadEncoding:      &cfg.PayloadEncoding,
+		Locations:            &cfg.Locations,
+		QuorumFailures:       &cfg.QuorumFailures,
+		JitterPercent:        &cfg.JitterPercent,
+		CronExpression:       deref(cfg.CronExpression),
+		Timezone:             &cfg.Timezone,
+		BusinessHours:        businessHours,
+		OffPeakFrequencySecs: &offPeakFrequencySecs,
+		Tags:                 &cfg.Tags,
+		Group:                &cfg.Group,
+	}
+}
+
+// RollbackMonitor restores the configuration of a revision. The rollback is
+// itself recorded as a new revision.
+func RollbackMonitor(ctx context.Context, db *config.DB, orgID int64, monitorID int, revision int) error {
+	rev, err := GetMonitorRevision(ctx, db, orgID, monitorID, revision)
+	if err != nil {
+		return err
+	}
+
+	return updateMonitor(ctx, db, orgID, rev.Config.rollbackPayload(monitorID), "rollback")
+}
+
+// MonitorsFile is the document exported from and synced to /monitors. It is
+// written as YAML, but JSON is accepted too.
+type MonitorsFile struct {
+	Monitors []MonitorSpec `json:"monitors" yaml:"monitors"`
+}
+
+// MonitorSpec is a monitor as kept in a monitors file, keyed by its slug.
+// Passwords are never exported, and sync leaves stored passwords alone.
+type MonitorSpec struct {
+	Slug                 string              `json:"slug" yaml:"slug"`
+	Name                 string              `json:"name" yaml:"name"`
+	MonitorType          string              `json:"monitor_type,omitempty" yaml:"monitor_type,omitempty"`
+	Url                  string              `json:"url,omitempty" yaml:"url,omitempty"`
+	Group                string              `json:"group,omitempty" yaml:"group,omitempty"`
+	Tags                 []string            `json:"tags,omitempty" yaml:"tags,omitempty"`
+	FrequencySecs        int                 `json:"frequency_secs" yaml:"frequency_secs"`
+	CronExpression       string              `json:"cron_expression,omitempty" yaml:"cron_expression,omitempty"`
+	Timezone             string              `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	BusinessHours        *BusinessHours      `json:"business_hours,omitempty" yaml:"business_hours,omitempty"`
+	OffPeakFrequencySecs int                 `json:"off_peak_frequency_secs,omitempty" yaml:"off_peak_frequency_secs,omitempty"`
+	JitterPercent        int                 `json:"jitter_percent,omitempty" yaml:"jitter_percent,omitempty"`
+	HttpMethod           string              `json:"http_method,omitempty" yaml:"http_method,omitempty"`
+	ConnectionTimeout    int                 `json:"connection_timeout,omitempty" yaml:"connection_timeout,omitempty"`
+	RequestHeaders       map[string][]string `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
+	RequestBody          string              `json:"request_body,omitempty" yaml:"request_body,omitempty"`
+	ResponseFormat       string              `json:"response_format,omitempty" yaml:"response_format,omitempty"`
+	ResponseHeaders      map[string][]string `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	AcceptedStatusCodes  []int               `json:"accepted_status_codes,omitempty" yaml:"accepted_status_codes,omitempty"`
+	ResponsePattern      string              `json:"response_pattern,omitempty" yaml:"response_pattern,omitempty"`
+	ResponseSchema       string              `json:"response_schema,omitempty" yaml:"response_schema,omitempty"`
+	TLSMode              string              `json:"tls_mode,omitempty" yaml:"tls_mode,omitempty"`
+	AuthUsername         string              `json:"auth_username,omitempty" yaml:"auth_username,omitempty"`
+	SkipTLSVerify        bool                `json:"skip_tls_verify,omitempty" yaml:"skip_tls_verify,omitempty"`
+	RequiredCapabilities []string            `json:"required_capabilities,omitempty" yaml:"required_capabilities,omitempty"`
+	ExpectedResult       string              `json:"expected_result,omitempty" yaml:"expected_result,omitempty"`
+	GraceSeconds         int                 `json:"grace_seconds,omitempty" yaml:"grace_seconds,omitempty"`
+	PayloadEncoding      string              `json:"payload_encoding,omitempty" yaml:"payload_encoding,omitempty"`
+	Locations            []string            `json:"locations,omitempty" yaml:"locations,omitempty"`
+	QuorumFailures       int                 `json:"quorum_failures,omitempty" yaml:"quorum_failures,omitempty"`
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt(n *int64) int {
+	if n == nil {
+		return 0
+	}
+	return int(*n)
+}
+
+func specFromMonitor(m MonitorDetail) MonitorSpec {
+	return MonitorSpec{
+		Slug:                 m.Slug,
+		Name:                 m.Name,
+		MonitorType:          m.MonitorType,
+		Url:                  m.Url,
+		Group:                m.Group,
+		Tags:                 m.Tags,
+		FrequencySecs:        m.FrequencySecs,
+		CronExpression:       derefString(m.CronExpression),
+		Timezone:             m.Timezone,
+		BusinessHours:        m.BusinessHours,
+		OffPeakFrequencySecs: derefInt(m.OffPeakFrequencySecs),
+		JitterPercent:        m.JitterPercent,
+		HttpMethod:           m.HttpMethod,
+		ConnectionTimeout:    derefInt(m.ConnectionTimeout),
+		RequestHeaders:       m.RequestHeaders,
+		RequestBody:          derefString(m.RequestBody),
+		ResponseFormat:       m.ResponseFormat,
+		ResponseHeaders:      m.ResponseHeaders,
+		AcceptedStatusCodes:  m.AcceptedStatusCodes,
+		ResponsePattern:      derefString(m.ResponsePattern),
+		ResponseSchema:       derefString(m.ResponseSchema),
+		TLSMode:              m.TLSMode,
+		AuthUsername:         derefString(m.AuthUsername),
+		SkipTLSVerify:        m.SkipTLSVerify,
+		RequiredCapabilities: m.RequiredCapabilities,
+		ExpectedResult:       derefString(m.ExpectedResult),
+		GraceSeconds:         m.GraceSeconds,
+		PayloadEncoding:      m.PayloadEncoding,
+		Locations:            m.Locations,
+		QuorumFailures:       m.QuorumFailures,
+	}.normalized()
+}
+
+// normalized fills in the defaults a monitor is created with and puts lists
+// in a stable order, so a spec and the monitor it describes compare equal.
+func (s MonitorSpec) normalized() MonitorSpec {
+	if s.MonitorType == "" {
+		s.MonitorType = monitor.MonitorTypeHTTP
+	}
+	if s.MonitorType == monitor.MonitorTypeHeartbeat {
+		s.Url = ""
+	}
+	s.Group = monitor.NormalizeGroup(s.Group)
+	if s.ResponseFormat == "" {
+		s.ResponseFormat = "string"
+	}
+	s.HttpMethod = strings.ToUpper(s.HttpMethod)
+	if s.HttpMethod == "" {
+		s.HttpMethod = http.MethodGet
+	}
+	if s.TLSMode == "" {
+		s.TLSMode = monitor.TLSModeNone
+	}
+	if s.PayloadEncoding == "" {
+		s.PayloadEncoding = monitor.PayloadEncodingText
+	}
+	if s.QuorumFailures <= 0 {
+		s.QuorumFailures = 1
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	s.JitterPercent = monitor.ClampJitter(s.JitterPercent)
+	if s.OffPeakFrequencySecs < 0 {
+		s.OffPeakFrequencySecs = 0
+	}
+
+	if len(s.AcceptedStatusCodes) == 0 {
+		s.AcceptedStatusCodes = []int{200}
+	} else {
+		s.AcceptedStatusCodes = slices.Sorted(slices.Values(s.AcceptedStatusCodes))
+	}
+	s.Locations = slices.Sorted(slices.Values(s.Locations))
+
+	// Tags are unique by key, the last value of a key wins.
+	tags := make(map[string]string, len(s.Tags))
+	for _, tag := range s.Tags {
+		if key, value := monitor.ParseTag(tag); key != "" {
+			tags[key] = value
+		}
+	}
+	s.Tags = make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		s.Tags = append(s.Tags, monitor.FormatTag(key, tags[key]))
+	}
+
+	if s.BusinessHours != nil {
+		if len(s.BusinessHours.Days) == 0 {
+			s.BusinessHours = nil
+		} else {
+			hours := *s.BusinessHours
+			hours.Days = make([]string, len(s.BusinessHours.Days))
+			for i, day := range s.BusinessHours.Days {
+				hours.Days[i] = strings.ToLower(day)
+			}
+			s.BusinessHours = &hours
+		}
+	}
+
+	return s
+}
+
+func (s MonitorSpec) createPayload() CreateMonitorPayload {
+	return CreateMonitorPayload{
+		Slug:                 s.Slug,
+		Name:                 s.Name,
+		Url:                  s.Url,
+		FrequencySecs:        s.FrequencySecs,
+		ResponseFormat:       s.ResponseFormat,
+		HttpMethod:           s.HttpMethod,
+		ConnectionTimeout:    s.ConnectionTimeout,
+		RequestHeaders:       s.RequestHeaders,
+		ResponseHeaders:      s.ResponseHeaders,
+		AcceptedStatusCodes:  s.AcceptedStatusCodes,
+		RequestBody:          s.RequestBody,
+		MonitorType:          s.MonitorType,
+		ResponsePattern:      s.ResponsePattern,
+		ResponseSchema:       s.ResponseSchema,
+		TLSMode:              s.TLSMode,
+		AuthUsername:         s.AuthUsername,
+		SkipTLSVerify:        s.SkipTLSVerify,
+		RequiredCapabilities: s.RequiredCapabilities,
+		ExpectedResult:       s.ExpectedResult,
+		GraceSeconds:         s.GraceSeconds,
+		PayloadEncoding:      s.PayloadEncoding,
+		Locations:            s.Locations,
+		QuorumFailures:       s.QuorumFailures,
+		JitterPercent:        s.JitterPercent,
+		CronExpression:       s.CronExpression,
+		Timezone:             s.Timezone,
+		BusinessHours:        s.BusinessHours,
+		OffPeakFrequencySecs: s.OffPeakFrequencySecs,
+		Tags:                 s.Tags,
+		Group:                s.Group,
+	}
+}
+
+// updatePayload sets every field of the spec, so fields left out of the
+// file are reset to their defaults. The password is left as it is.
+func (s MonitorSpec) updatePayload(monitorID int) UpdateMonitorPayload {
+	p := s.createPayload()
+	businessHours := p.BusinessHours
+	if businessHours == nil {
+		businessHours = &BusinessHours{}
+	}
+
+	payload := UpdateMonitorPayload{
+		MonitorID:            monitorID,
+		Name:                 &p.Name,
+		FrequencySecs:        &p.FrequencySecs,
+		ResponseFormat:       &p.ResponseFormat,
+		HttpMethod:           &p.HttpMethod,
+		ConnectionTimeout:    &p.ConnectionTimeout,
+		RequestHeaders:       &p.RequestHeaders,
+		ResponseHeaders:      &p.ResponseHeaders,
+		AcceptedStatusCodes:  &p.AcceptedStatusCodes,
+		RequestBody:          &p.RequestBody,
+		MonitorType:          &p.MonitorType,
+		ResponsePattern:      &p.ResponsePattern,
+		ResponseSchema:       &p.ResponseSchema,
+		TLSMode:              &p.TLSMode,
+		AuthUsername:         &p.AuthUsername,
+		SkipTLSVerify:        &p.SkipTLSVerify,
+		RequiredCapabilities: &p.RequiredCapabilities,
+		ExpectedResult:       &p.ExpectedResult,
+		GraceSeconds:         &p.GraceSeconds,
+		PayloadEncoding:      &p.PayloadEncoding,
+		Locations:            &p.Locations,
+		QuorumFailures:       &p.QuorumFailures,
+		JitterPercent:        &p.JitterPercent,
+		CronExpression:       &p.CronExpression,
+		Timezone:             &p.Timezone,
+		BusinessHours:        businessHours,
+		OffPeakFrequencySecs: &p.OffPeakFrequencySecs,
+		Tags:                 &p.Tags,
+		Group:                &p.Group,
+	}
+	if p.MonitorType != monitor.MonitorTypeHeartbeat {
+		payload.Url = &p.Url
+	}
+	return payload
+}
+
+// ExportMonitors returns every monitor of an org as a monitors file.
+func ExportMonitors(ctx context.Context, db *config.DB, orgID int64) (*MonitorsFile, error) {
+	monitors, err := getMonitors(ctx, db.Pool, orgID, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+
+	file := &MonitorsFile{Monitors: make([]MonitorSpec, 0, len(monitors))}
+	for _, m := range monitors {
+		file.Monitors = append(file.Monitors, specFromMonitor(m))
+	}
+	slices.SortFunc(file.Monitors, func(a, b MonitorSpec) int {
+		return strings.Compare(a.Slug, b.Slug)
+	})
+
+	return file, nil
+}
+
+type SyncAction struct {
+	Slug      string                 `json:"slug"`
+	MonitorID int                    `json:"monitor_id,omitempty"`
+	Name      string                 `json:"name"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// SyncPlan lists what a sync changes. Monitors missing from the file are
+// deleted with prune, and listed as unmanaged otherwise.
+type SyncPlan struct {
+	DryRun    bool         `json:"dry_run"`
+	Prune     bool         `json:"prune"`
+	Creates   []SyncAction `json:"creates"`
+	Updates   []SyncAction `json:"updates"`
+	Deletes   []SyncAction `json:"deletes"`
+	Unmanaged []SyncAction `json:"unmanaged"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// syncField prefixes the fields of validation errors with the monitor's
+// position in the file.
+func syncField(i int, err error) error {
+	var verrs validation.Errors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	prefixed := make(validation.Errors, len(verrs))
+	for j, fe := range verrs {
+		prefixed[j] = validation.FieldError{Field: fmt.Sprintf("monitors[%d].%s", i, fe.Field), Message: fe.Message}
+	}
+	return prefixed
+}
+
+func validateMonitorsFile(file *MonitorsFile) error {
+	var errs validation.Errors
+	seen := make(map[string]int, len(file.Monitors))
+
+	for i, spec := range file.Monitors {
+		field := fmt.Sprintf("monitors[%d].slug", i)
+		if spec.Slug == "" {
+			errs.Add(field, "is required")
+		} else if first, ok := seen[spec.Slug]; ok {
+			errs.Add(field, "duplicates monitors[%d]", first)
+		} else {
+			seen[spec.Slug] = i
+		}
+
+		payload := spec.createPayload()
+		if err := syncField(i, validation.ValidateMonitor(payload.validationFields(), false)); err != nil {
+			var verrs validation.Errors
+			if !errors.As(err, &verrs) {
+				return err
+			}
+			errs = append(errs, verrs...)
+		}
+	}
+
+	return errs.Err()
+}
+
+// SyncMonitors makes an org's monitors match file, matching them by slug.
+// Syncing the same file again changes nothing. With dryRun the plan is only
+// computed. Each change is applied in its own transaction, so a failed sync
+// can be finished by running it again.
+func SyncMonitors(ctx context.Context, db *config.DB, orgID int64, file *MonitorsFile, dryRun bool, prune bool) (*SyncPlan, error) {
+	if err := validateMonitorsFile(file); err != nil {
+		return nil, err
+	}
+
+	existing, err := getMonitors(ctx, db.Pool, orgID, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]MonitorDetail, len(existing))
+	for _, m := range existing {
+		bySlug[m.Slug] = m
+	}
+
+	plan := &SyncPlan{
+		DryRun:    dryRun,
+		Prune:     prune,
+		Creates:   make([]SyncAction, 0),
+		Updates:   make([]SyncAction, 0),
+		Deletes:   make([]SyncAction, 0),
+		Unmanaged: make([]SyncAction, 0),
+	}
+	specs := make(map[string]MonitorSpec, len(file.Monitors))
+	index := make(map[string]int, len(file.Monitors))
+
+	for i, spec := range file.Monitors {
+		spec = spec.normalized()
+		specs[spec.Slug] = spec
+		index[spec.Slug] = i
+
+		current, ok := bySlug[spec.Slug]
+		if !ok {
+			plan.Creates = append(plan.Creates, SyncAction{Slug: spec.Slug, Name: spec.Name})
+			continue
+		}
+
+		changes, err := diffChanges(specFromMonitor(current), spec)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Updates = append(plan.Updates, SyncAction{Slug: spec.Slug, MonitorID: current.MonitorID, Name: spec.Name, Changes: changes})
+	}
+
+	for _, m := range existing {
+		if _, ok := specs[m.Slug]; ok {
+			continue
+		}
+		action := SyncAction{Slug: m.Slug, MonitorID: m.MonitorID, Name: m.Name}
+		if prune {
+			plan.Deletes = append(plan.Deletes, action)
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, action)
+		}
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+
+	for i, action := range plan.Creates {
+		created, err := InsertMonitorToDB(ctx, db, orgID, specs[action.Slug].createPayload())
+		if err != nil {
+			return nil, fmt.Errorf("error creating monitor %s: %w", action.Slug, syncField(index[action.Slug], err))
+		}
+		plan.Creates[i].MonitorID = int(created.MonitorID)
+	}
+	for _, action := range plan.Updates {
+		if err := updateMonitor(ctx, db, orgID, specs[action.Slug].updatePayload(action.MonitorID), "sync"); err != nil {
+			return nil, fmt.Errorf("error updating monitor %s: %w", action.Slug, syncField(index[action.Slug], err))
+		}
+	}
+	for _, action := range plan.Deletes {
+		if err := DeleteMonitor(ctx, db, orgID, action.MonitorID); err != nil && !errors.Is(err, ErrMonitorNotFound) {
+			return nil, fmt.Errorf("error deleting monitor %s: %w", action.Slug, err)
+		}
+	}
+
+	return plan, nil
+}
+
+// ImportOptions are applied to every monitor of an import. ServerURL and
+// Operations only apply to OpenAPI documents.
+type ImportOptions struct {
+	Format        string
+	FrequencySecs int
+	Group         string
+	Tags          []string
+	ServerURL     string
+	Operations    []string
+}
+
+// ImportedMonitor is one monitor of an import. Errors lists why the payload
+// would be rejected.
+type ImportedMonitor struct {
+	Action    string                 `json:"action"`
+	MonitorID int                    `json:"monitor_id,omitempty"`
+	Payload   CreateMonitorPayload   `json:"payload"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Errors    validation.Errors      `json:"errors,omitempty"`
+}
+
+type ImportResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Format   string            `json:"format"`
+	Warnings []string          `json:"warnings"`
+	Monitors []ImportedMonitor `json:"monitors"`
+}
+
+// importSlug turns the key of an imported request into a slug. Keys too
+// long for a slug are shortened with a hash, so they stay distinct.
+func importSlug(key string) string {
+	slug := slugify(key)
+	if slug == strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(key), "-"), "-") {
+		return slug
+	}
+	sum := sha256.Sum256([]byte(key))
+	return strings.Trim(slug[:min(len(slug), 71)], "-") + "-" + hex.EncodeToString(sum[:4])
+}
+
+func importedPayload(req importer.Request, opts ImportOptions) CreateMonitorPayload {
+	payload := CreateMonitorPayload{
+		Name:                req.Name,
+		Url:                 req.URL,
+		FrequencySecs:       opts.FrequencySecs,
+		HttpMethod:          req.Method,
+		ConnectionTimeout:   req.TimeoutSecs,
+		RequestHeaders:      req.Headers,
+		RequestBody:         req.Body,
+		MonitorType:         monitor.MonitorTypeHTTP,
+		AuthUsername:        req.Username,
+		AuthPassword:        req.Password,
+		SkipTLSVerify:       req.Insecure,
+		AcceptedStatusCodes: req.StatusCodes,
+		ResponseSchema:      req.ResponseSchema,
+		Tags:                opts.Tags,
+		Group:               req.Group,
+	}
+	if opts.Group != "" {
+		payload.Group = monitor.NormalizeGroup(opts.Group + "/" + req.Group)
+	}
+	if req.Key != "" {
+		payload.Slug = importSlug(req.Key)
+	}
+	return payload
+}
+
+// withImport applies what an import generates to an existing monitor.
+// Headers added to the monitor since are kept.
+func (s MonitorSpec) withImport(p CreateMonitorPayload) MonitorSpec {
+	s.Name = p.Name
+	s.Url = p.Url
+	s.HttpMethod = p.HttpMethod
+	s.RequestBody = p.RequestBody
+	s.AcceptedStatusCodes = p.AcceptedStatusCodes
+	s.ResponseSchema = p.ResponseSchema
+	s.Group = p.Group
+
+	headers := maps.Clone(s.RequestHeaders)
+	if headers == nil {
+		headers = make(map[string][]string, len(p.RequestHeaders))
+	}
+	for name, values := range p.RequestHeaders {
+		headers[name] = values
+	}
+	s.RequestHeaders = headers
+
+	return s.normalized()
+}
+
+// ImportMonitors creates http monitors from a curl command, HAR file,
+// Postman collection or OpenAPI document. Requests the format keys, as
+// OpenAPI operations are, update the monitor imported from them before
+// instead of creating another one. With dryRun nothing is saved, and the
+// payloads are returned to be reviewed. Otherwise every payload has to be
+// valid, and its target allowed by the egress policy, before any monitor is
+// changed.
+func ImportMonitors(ctx context.Context, db *config.DB, orgID int64, data []byte, opts ImportOptions, dryRun bool) (*ImportResult, error) {
+	requests, warnings, err := importer.Parse(opts.Format, data, importer.Options{ServerURL: opts.ServerURL, Operations: opts.Operations})
+	if err != nil {
+		return nil, validation.Errors{{Field: "body", Message: err.Error()}}
+	}
+
+	result := &ImportResult{
+		DryRun:   dryRun,
+		Format:   opts.Format,
+		Warnings: warnings,
+		Monitors: make([]ImportedMonitor, 0, len(requests)),
+	}
+	if result.Warnings == nil {
+		result.Warnings = make([]string, 0)
+	}
+
+	var slugs []any
+	for _, req := range requests {
+		payload := importedPayload(req, opts)
+		result.Monitors = append(result.Monitors, ImportedMonitor{Action: "create", Payload: payload})
+		if payload.Slug != "" {
+			slugs = append(slugs, payload.Slug)
+		}
+	}
+
+	bySlug := make(map[string]MonitorDetail)
+	if len(slugs) > 0 {
+		existing, err := getMonitors(ctx, db.Pool, orgID, "slug IN (?"+strings.Repeat(",?", len(slugs)-1)+")", slugs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range existing {
+			bySlug[m.Slug] = m
+		}
+	}
+
+	var errs validation.Errors
+	seen := make(map[string]int)
+	specs := make([]MonitorSpec, len(result.Monitors))
+
+	for i := range result.Monitors {
+		imported := &result.Monitors[i]
+
+		if err := validation.ValidateMonitor(imported.Payload.validationFields(), false); err != nil {
+			if !errors.As(err, &imported.Errors) {
+				return nil, err
+			}
+		}
+		// Checked here as well as when saving, so a blocked target rejects
+		// the import before any monitor is changed.
+		if len(imported.Errors) == 0 {
+			if err := checkEgress(ctx, imported.Payload.MonitorType, imported.Payload.TLSMode, imported.Payload.Url); err != nil {
+				if !errors.As(err, &imported.Errors) {
+					return nil, err
+				}
+			}
+		}
+		if slug := imported.Payload.Slug; slug != "" {
+			if first, ok := seen[slug]; ok {
+				imported.Errors = append(imported.Errors, validation.FieldError{Field: "slug", Message: fmt.Sprintf("duplicates monitors[%d]", first)})
+			}
+			seen[slug] = i
+		}
+		if len(imported.Errors) > 0 {
+			var verrs validation.Errors
+			errors.As(syncField(i, imported.Errors), &verrs)
+			errs = append(errs, verrs...)
+			continue
+		}
+
+		current, ok := bySlug[imported.Payload.Slug]
+		if !ok {
+			continue
+		}
+		before := specFromMonitor(current)
+		specs[i] = before.withImport(imported.Payload)
+		changes, err := diffChanges(before, specs[i])
+		if err != nil {
+			return nil, err
+		}
+		imported.MonitorID = current.MonitorID
+		imported.Changes = changes
+		imported.Action = "update"
+		if len(changes) == 0 {
+			imported.Action = "unchanged"
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range result.Monitors {
+		imported := &result.Monitors[i]
+		switch imported.Action {
+		case "create":
+			created, err := InsertMonitorToDB(ctx, db, orgID, imported.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("error creating monitor %q: %w", imported.Payload.Name, syncField(i, err))
+			}
+			imported.MonitorID = int(created.MonitorID)
+		case "update":
+			if err := updateMonitor(ctx, db, orgID, specs[i].updatePayload(imported.MonitorID), "import"); err != nil {
+				return nil, fmt.Errorf("error updating monitor %q: %w", imported.Payload.Name, syncField(i, err))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+var ErrNotHTTPMonitor = errors.New("only http monitors can be exported as curl")
+
+// MonitorCurl returns a curl command that sends the same request as an http
+// monitor. The password is left out, so curl asks for it.
+func MonitorCurl(ctx context.Context, db *config.DB, orgID int64, monitorID int) (string, error) {
+	m, err := GetMonitor(ctx, db, orgID, monitorID)
+	if err != nil {
+		return "", err
+	}
+	if m.MonitorType != monitor.MonitorTypeHTTP {
+		return "", ErrNotHTTPMonitor
+	}
+
+	return importer.Curl(monitorRequest(*m)), nil
+}
+
+// monitorRequest is the request an http monitor's checks send.
+func monitorRequest(m MonitorDetail) importer.Request {
+	req := importer.Request{
+		Method:      m.HttpMethod,
+		URL:         m.Url,
+		Headers:     make(map[string][]string, len(m.RequestHeaders)+1),
+		Username:    derefString(m.AuthUsername),
+		Insecure:    m.SkipTLSVerify,
+		TimeoutSecs: derefInt(m.ConnectionTimeout),
+	}
+	for name, values := range m.RequestHeaders {
+		req.Headers[http.CanonicalHeaderKey(name)] = values
+	}
+
+	// Checks send the body with every method but GET, as json unless a
+	// header says otherwise.
+	if body := derefString(m.RequestBody); body != "" && m.HttpMethod != http.MethodGet {
+		req.Body = body
+		if _, ok := req.Headers["Content-Type"]; !ok {
+			req.Headers["Content-Type"] = []string{"application/json"}
+		}
+	}
+
+	return req
+}
+
+// maxCheckDuration bounds how long a check run from the API is waited for.
+const maxCheckDuration = 60 * time.Second
+
+var (
+	ErrHeartbeatNotRunnable = errors.New("heartbeat monitors are not checked, they wait for pings")
+	ErrCheckTimedOut        = errors.New("check did not finish in time")
+	ErrMonitorPaused        = errors.New("monitor is paused by a maintenance window, run it without persist")
+)
+
+// CheckResult is the outcome of a check run from the API. Times are in
+// milliseconds.
+type CheckResult struct {
+	MonitorID        int     `json:"monitor_id,omitempty"`
+	Url              string  `json:"url"`
+	StatusCode       int     `json:"status_code"`
+	Status           string  `json:"status"`
+	DNSResponseTime  int64   `json:"dns_response_time"`
+	ConnectionTime   int64   `json:"connection_time"`
+	TLSHandshakeTime int64   `json:"tls_handshake_time"`
+	ResolvedIP       string  `json:"resolved_ip"`
+	FirstByteTime    int64   `json:"first_byte_time"`
+	DownloadTime     int64   `json:"download_time"`
+	ResponseTime     int64   `json:"response_time"`
+	HandshakeTime    int64   `json:"handshake_time"`
+	RoundTripTime    int64   `json:"round_trip_time"`
+	Throughput       float64 `json:"throughput"`
+	Reason           string  `json:"reason"`
+	Maintenance      string  `json:"maintenance,omitempty"`
+	Persisted        bool    `json:"persisted"`
+}
+
+// checkMonitor is the monitor an agent would check for the payload, with the
+// defaults it is created with.
+func (p CreateMonitorPayload) checkMonitor() monitor.Monitor {
+	spec := MonitorSpec{
+		MonitorType:     p.MonitorType,
+		Url:             p.Url,
+		ResponseFormat:  p.ResponseFormat,
+		HttpMethod:      p.HttpMethod,
+		TLSMode:         p.TLSMode,
+		PayloadEncoding: p.PayloadEncoding,
+	}.normalized()
+	codes := p.AcceptedStatusCodes
+	if len(codes) == 0 {
+		codes = []int{200}
+	}
+
+	return monitor.Monitor{
+		Type:                 spec.MonitorType,
+		Url:                  p.Url,
+		FrequencySecs:        p.FrequencySecs,
+		ResponseFormat:       spec.ResponseFormat,
+		HttpMethod:           spec.HttpMethod,
+		ConnectionTimeout:    nullPositiveInt(p.ConnectionTimeout),
+		RequestHeaders:       p.RequestHeaders,
+		ResponseHeaders:      p.ResponseHeaders,
+		AcceptedStatusCodes:  codes,
+		RequestBody:          sql.NullString{String: p.RequestBody, Valid: true},
+		ResponsePattern:      nullString(p.ResponsePattern),
+		ResponseSchema:       nullString(p.ResponseSchema),
+		TLSMode:              spec.TLSMode,
+		AuthUsername:         nullString(p.AuthUsername),
+		AuthPassword:         nullString(p.AuthPassword),
+		SkipTLSVerify:        p.SkipTLSVerify,
+		RequiredCapabilities: nullString(strings.Join(p.RequiredCapabilities, ",")),
+		ExpectedResult:       nullString(p.ExpectedResult),
+		PayloadEncoding:      spec.PayloadEncoding,
+	}
+}
+
+// runCheck checks m once from the API server. A check that fails to run is
+// reported as DOWN with the error as its reason, like agents do.
+func runCheck(ctx context.Context, m monitor.Monitor) (*CheckResult, *monitor.Result, error) {
+	if m.Type == monitor.MonitorTypeHeartbeat {
+		return nil, nil, ErrHeartbeatNotRunnable
+	}
+
+	type outcome struct {
+		res *monitor.Result
+		err error
+	}
+	// Checks take no context, so one that hangs is left to finish on its
+	// own.
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := monitor.GetResult(m)
+		done <- outcome{res, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-time.After(maxCheckDuration):
+		return nil, nil, ErrCheckTimedOut
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	res := out.res
+	if out.err != nil {
+		res = &monitor.Result{MonitorID: m.ID, MonitorUrl: m.Url, Status: "DOWN", Reason: out.err.Error()}
+	}
+
+	return &CheckResult{
+		MonitorID:        m.ID,
+		Url:              res.MonitorUrl,
+		StatusCode:       res.StatusCode,
+		Status:           res.Status,
+		DNSResponseTime:  res.DNSResponseTime.Milliseconds(),
+		ConnectionTime:   res.ConnectionTime.Milliseconds(),
+		TLSHandshakeTime: res.TLSHandshakeTime.Milliseconds(),
+		ResolvedIP:       res.ResolvedIp,
+		FirstByteTime:    res.FirstByteTime.Milliseconds(),
+		DownloadTime:     res.DownloadTime.Milliseconds(),
+		ResponseTime:     res.ResponseTime.Milliseconds(),
+		HandshakeTime:    res.HandshakeTime.Milliseconds(),
+		RoundTripTime:    res.RoundTripTime.Milliseconds(),
+		Throughput:       res.Throughput,
+		Reason:           res.Reason,
+	}, res, nil
+}
+
+// RunMonitor checks a saved monitor right away, outside of its schedule.
+// With persist the result is stored like one reported by an agent, without
+// a location. It does not move the monitor's current status, which is left
+// to the quorum of its scheduled checks. Maintenance windows apply as they
+// do to scheduled checks: a suppressed result is stored without counting
+// towards uptime, and a paused monitor is not stored at all.
+func RunMonitor(ctx context.Context, db *config.DB, orgID int64, monitorID int, persist bool) (*CheckResult, error) {
+	m, err := GetMonitor(ctx, db, orgID, monitorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var password sql.NullString
+	if err := db.Pool.QueryRowContext(ctx, `SELECT auth_password FROM monitor WHERE org_id = ? AND monitor_id = ?`, orgID, monitorID).Scan(&password); err != nil {
+		return nil, fmt.Errorf("error getting monitor credentials: %v", err)
+	}
+
+	payload := specFromMonitor(*m).createPayload()
+	payload.AuthPassword = password.String
+	check := payload.checkMonitor()
+	check.ID = m.MonitorID
+
+	modes, err := monitor.MaintenanceForMonitors(ctx, db, []interface{}{monitorID}, []string{"?"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance windows: %v", err)
+	}
+	mode := modes[monitorID]
+	if persist && mode == monitor.MaintenancePause {
+		return nil, ErrMonitorPaused
+	}
+
+	// The policy may have tightened since the monitor was saved.
+	if err := checkEgress(ctx, check.Type, check.TLSMode, check.Url); err != nil {
+		return nil, err
+	}
+
+	result, res, err := runCheck(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+	result.Maintenance = mode
+	if !persist {
+		return result, nil
+	}
+
+	msg := monitor.ToResultMessage(*res)
+	msg.InMaintenance = mode == monitor.MaintenanceSuppress
+	if err := resultq.RecordManualResult(ctx, db, msg); err != nil {
+		return nil, err
+	}
+	result.Persisted = true
+	return result, nil
+}
+
+// TestMonitor checks an unsaved monitor once. Nothing is stored.
+func TestMonitor(ctx context.Context, payload CreateMonitorPayload) (*CheckResult, error) {
+	if err := validation.ValidateMonitor(payload.validationFields(), false); err != nil {
+		return nil, err
+	}
+	check := payload.checkMonitor()
+	if err := checkEgress(ctx, check.Type, check.TLSMode, check.Url); err != nil {
+		return nil, err
+	}
+
+	result, _, err := runCheck(ctx, check)
+	return result, err
+}
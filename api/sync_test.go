@@ -0,0 +1,281 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+	"gopkg.in/yaml.v3"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// storedMonitor is a monitor as getMonitors loads it, with the defaults it
+// was created with.
+func storedMonitor(slug string, monitorType string, url string) MonitorDetail {
+	return MonitorDetail{
+		MonitorID: 1,
+		MonitorConfig: MonitorConfig{
+			Slug:                 slug,
+			Name:                 slug,
+			Url:                  url,
+			MonitorType:          monitorType,
+			FrequencySecs:        60,
+			ResponseFormat:       "string",
+			HttpMethod:           "GET",
+			RequestHeaders:       map[string][]string{},
+			ResponseHeaders:      map[string][]string{},
+			AcceptedStatusCodes:  []int{200},
+			RequestBody:          ptr(""),
+			TLSMode:              monitor.TLSModeNone,
+			RequiredCapabilities: []string{},
+			PayloadEncoding:      monitor.PayloadEncodingText,
+			Locations:            []string{},
+			QuorumFailures:       1,
+			Timezone:             "UTC",
+			Tags:                 []string{},
+		},
+		IsActive: true,
+		Status:   "idle",
+	}
+}
+
+func storedMonitors() []MonitorDetail {
+	httpMonitor := storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+	httpMonitor.Group = "prod/payments"
+	httpMonitor.Tags = []string{"env:prod", "team:payments"}
+	httpMonitor.HttpMethod = "POST"
+	httpMonitor.ConnectionTimeout = ptr(int64(10))
+	httpMonitor.RequestHeaders = map[string][]string{"Content-Type": {"application/json"}, "X-Trace": {"a", "b"}}
+	httpMonitor.ResponseHeaders = map[string][]string{"Cache-Control": {"no-store"}}
+	httpMonitor.AcceptedStatusCodes = []int{200, 201, 204}
+	httpMonitor.RequestBody = ptr(`{"ping": true}`)
+	httpMonitor.ResponseFormat = "json"
+	httpMonitor.ResponsePattern = ptr(`"ok":\s*true`)
+	httpMonitor.ResponseSchema = ptr(`{"type": "object", "required": ["ok"]}`)
+	httpMonitor.AuthUsername = ptr("probe")
+	httpMonitor.HasAuthPassword = true
+	httpMonitor.SkipTLSVerify = true
+	httpMonitor.Locations = []string{"eu-west", "us-east"}
+	httpMonitor.QuorumFailures = 2
+	httpMonitor.JitterPercent = 10
+	httpMonitor.Timezone = "Europe/Berlin"
+	httpMonitor.BusinessHours = &BusinessHours{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+	httpMonitor.OffPeakFrequencySecs = ptr(int64(600))
+
+	websocket := storedMonitor("socket", monitor.MonitorTypeWebSocket, "wss://ws.example.com/stream")
+	websocket.RequestBody = ptr("ping")
+	websocket.ResponsePattern = ptr("pong")
+
+	smtp := storedMonitor("smtp", monitor.MonitorTypeSMTP, "mail.example.com:587")
+	smtp.TLSMode = monitor.TLSModeStartTLS
+	smtp.AuthUsername = ptr("probe@example.com")
+	smtp.RequiredCapabilities = []string{"AUTH", "PIPELINING"}
+	smtp.ExpectedResult = ptr("220")
+
+	imap := storedMonitor("imap", monitor.MonitorTypeIMAP, "imap.example.com")
+	imap.TLSMode = monitor.TLSModeImplicit
+
+	pop3 := storedMonitor("pop3", monitor.MonitorTypePOP3, "pop.example.com:110")
+
+	mysql := storedMonitor("mysql", monitor.MonitorTypeMySQL, "db.example.com:3306")
+	mysql.AuthUsername = ptr("monitor")
+	mysql.RequestBody = ptr("SELECT 1")
+	mysql.ExpectedResult = ptr("1")
+
+	postgres := storedMonitor("postgres", monitor.MonitorTypePostgres, "pg.example.com")
+	postgres.TLSMode = "require"
+
+	redis := storedMonitor("redis", monitor.MonitorTypeRedis, "cache.example.com:6379")
+	redis.CronExpression = ptr("*/5 * * * *")
+
+	heartbeat := storedMonitor("nightly-backup", monitor.MonitorTypeHeartbeat, monitor.HeartbeatPath("f00d"))
+	heartbeat.FrequencySecs = 86400
+	heartbeat.GraceSeconds = 600
+	heartbeat.HeartbeatPath = monitor.HeartbeatPath("f00d")
+
+	udp := storedMonitor("dns", monitor.MonitorTypeUDP, "ns1.example.com:53")
+	udp.PayloadEncoding = monitor.PayloadEncodingHex
+	udp.RequestBody = ptr("12 34 01 00 00 01")
+	udp.ExpectedResult = ptr("1234")
+
+	return []MonitorDetail{httpMonitor, websocket, smtp, imap, pop3, mysql, postgres, redis, heartbeat, udp}
+}
+
+// decodeMonitorsFile reads a monitors file the way SyncMonitorsHandler does.
+func decodeMonitorsFile(t *testing.T, data []byte) MonitorsFile {
+	t.Helper()
+
+	var file MonitorsFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		t.Fatalf("decoding monitors file: %v\n%s", err, data)
+	}
+	return file
+}
+
+func assertNoChanges(t *testing.T, stored MonitorDetail, spec MonitorSpec) {
+	t.Helper()
+
+	changes, err := diffChanges(specFromMonitor(stored), spec.normalized())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field, change := range changes {
+		t.Errorf("%s: %s changes from %s to %s", stored.Slug, field, change.Before, change.After)
+	}
+}
+
+func TestExportedSpecSyncsClean(t *testing.T) {
+	for _, stored := range storedMonitors() {
+		t.Run(stored.MonitorType, func(t *testing.T) {
+			exported := MonitorsFile{Monitors: []MonitorSpec{specFromMonitor(stored)}}
+
+			data, err := yaml.Marshal(exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertNoChanges(t, stored, decodeMonitorsFile(t, data).Monitors[0])
+
+			data, err = json.Marshal(exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertNoChanges(t, stored, decodeMonitorsFile(t, data).Monitors[0])
+		})
+	}
+}
+
+func TestNormalizedIsStable(t *testing.T) {
+	for _, stored := range storedMonitors() {
+		spec := specFromMonitor(stored)
+		changes, err := diffChanges(spec, spec.normalized())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) > 0 {
+			t.Errorf("%s: normalizing twice changes %v", stored.Slug, changes)
+		}
+	}
+}
+
+func TestHandWrittenSpecSyncsClean(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		stored func() MonitorDetail
+	}{
+		{
+			name: "defaults left out",
+			yaml: `
+monitors:
+  - slug: api
+    name: api
+    url: https://api.example.com/health
+    frequency_secs: 60
+`,
+			stored: func() MonitorDetail {
+				return storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+			},
+		},
+		{
+			name: "values stored in another form",
+			yaml: `
+monitors:
+  - slug: api
+    name: api
+    url: https://api.example.com/health
+    frequency_secs: 60
+    http_method: post
+    group: " prod / payments /"
+    tags: [team:payments, env:staging, env:prod]
+    accepted_status_codes: [204, 200]
+    locations: [us-east, eu-west]
+    quorum_failures: 0
+    business_hours:
+      days: [Mon, TUE]
+      start: "09:00"
+      end: "17:00"
+`,
+			stored: func() MonitorDetail {
+				m := storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+				m.HttpMethod = "POST"
+				m.Group = "prod/payments"
+				m.Tags = []string{"env:prod", "team:payments"}
+				m.AcceptedStatusCodes = []int{200, 204}
+				m.Locations = []string{"eu-west", "us-east"}
+				m.BusinessHours = &BusinessHours{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00"}
+				return m
+			},
+		},
+		{
+			name: "business hours without days",
+			yaml: `
+monitors:
+  - slug: api
+    name: api
+    url: https://api.example.com/health
+    frequency_secs: 60
+    business_hours:
+      days: []
+      start: "09:00"
+      end: "17:00"
+`,
+			stored: func() MonitorDetail {
+				return storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+			},
+		},
+		{
+			name: "heartbeat without its generated path",
+			yaml: `
+monitors:
+  - slug: nightly-backup
+    name: nightly-backup
+    monitor_type: heartbeat
+    frequency_secs: 86400
+    grace_seconds: 600
+`,
+			stored: func() MonitorDetail {
+				m := storedMonitor("nightly-backup", monitor.MonitorTypeHeartbeat, monitor.HeartbeatPath("f00d"))
+				m.FrequencySecs = 86400
+				m.GraceSeconds = 600
+				return m
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := decodeMonitorsFile(t, []byte(tt.yaml))
+			if err := validateMonitorsFile(&file); err != nil {
+				t.Fatalf("validateMonitorsFile() error = %v", err)
+			}
+			assertNoChanges(t, tt.stored(), file.Monitors[0])
+		})
+	}
+}
+
+func TestSpecDiffListsChangedFields(t *testing.T) {
+	stored := storedMonitor("api", monitor.MonitorTypeHTTP, "https://api.example.com/health")
+
+	spec := specFromMonitor(stored)
+	spec.FrequencySecs = 30
+	spec.Tags = []string{"env:prod"}
+	spec.Url = strings.Replace(spec.Url, "/health", "/ready", 1)
+
+	changes, err := diffChanges(specFromMonitor(stored), spec.normalized())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := slices.Sorted(maps.Keys(changes))
+	if want := []string{"frequency_secs", "tags", "url"}; !slices.Equal(fields, want) {
+		t.Errorf("changed fields = %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/dhruvthak3r/Probe/config"
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+	"github.com/dhruvthak3r/Probe/internal/mq"
+	"github.com/joho/godotenv"
+	"golang.org/x/sync/errgroup"
+)
+
+const registrationInterval = 30 * time.Second
+
+func main() {
+	_ = godotenv.Load()
+
+	workers, _ := strconv.Atoi(os.Getenv("PROBE_AGENT_WORKERS"))
+	if workers <= 0 {
+		workers = 3
+	}
+
+	location := flag.String("location", os.Getenv("PROBE_LOCATION"), "location name this agent runs checks from")
+	flag.IntVar(&workers, "workers", workers, "number of concurrent check workers")
+	flag.Parse()
+
+	if *location == "" {
+		log.Fatalf("a location is required: set --location or PROBE_LOCATION")
+	}
+	if err := mq.ValidateLocation(*location); err != nil {
+		log.Fatalf("invalid location: %v", err)
+	}
+
+	egress, err := monitor.EgressPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	monitor.Egress = egress
+
+	rmqconn, err := config.NewRabbitMQConnection()
+	if err != nil {
+		log.Fatalf("error connecting to rabbitmq: %v", err)
+	}
+	defer rmqconn.Close()
+
+	publisher, err := mq.NewRabbitMQPublisher(*rmqconn)
+	if err != nil {
+		log.Fatalf("error creating rabbitmq publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	registrar, err := mq.NewQueuePublisher(*rmqconn, mq.RegistrationsQueue)
+	if err != nil {
+		log.Fatalf("error creating rabbitmq registration publisher: %v", err)
+	}
+	defer registrar.Close()
+
+	jobs, err := mq.NewJobConsumer(*rmqconn, *location, workers)
+	if err != nil {
+		log.Fatalf("error creating rabbitmq job consumer: %v", err)
+	}
+	defer jobs.Close()
+
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g, ctx := errgroup.WithContext(rootCtx)
+
+	registration, err := newRegistration(*location)
+	if err != nil {
+		log.Fatalf("error building agent registration: %v", err)
+	}
+
+	g.Go(func() error {
+		ticker := time.NewTicker(registrationInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := registrar.PublishToQueue(ctx, registration); err != nil {
+				fmt.Printf("error registering agent: %v\n", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			return jobs.ConsumeJobs(ctx, monitor.RunJob(publisher))
+		})
+	}
+
+	fmt.Printf("probe agent running at location %s with %d workers\n", *location, workers)
+
+	if err := g.Wait(); err != nil && rootCtx.Err() == nil {
+		log.Fatalf("agent failed: %v", err)
+	}
+
+	fmt.Println("probe agent gracefully stopped")
+}
+
+func newRegistration(location string) ([]byte, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	return json.Marshal(mq.AgentRegistration{
+		Location: location,
+		AgentID:  hex.EncodeToString(id),
+		Hostname: hostname,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	handlers "github.com/dhruvthak3r/Probe/api"
 	middleware "github.com/dhruvthak3r/Probe/api"
@@ -25,37 +26,38 @@ import (
 func main() {
 	_ = godotenv.Load()
 
-	conn, err := db.NewDBConnection()
+	opts, err := ParseOptions(os.Args[1:])
 	if err != nil {
-		log.Fatalf("error connecting to db: %v", err)
+		log.Fatalf("invalid options: %v", err)
 	}
-	defer conn.Pool.Close()
 
-	migrations.Run(db.MigrationURL())
-
-	rmqconn, err := config.NewRabbitMQConnection()
+	egress, err := monitor.EgressPolicyFromEnv()
 	if err != nil {
-		log.Fatalf("error connecting to rabbitmq: %v", err)
+		log.Fatalf("%v", err)
 	}
-	defer rmqconn.Close()
+	monitor.Egress = egress
 
-	publisher, err := mq.NewRabbitMQPublisher(*rmqconn)
+	conn, err := db.NewDBConnection()
 	if err != nil {
-		log.Fatalf("error creating rabbitmq publisher: %v", err)
+		log.Fatalf("error connecting to db: %v", err)
 	}
-	defer publisher.Close()
+	defer conn.Pool.Close()
 
-	consumer, err := mq.NewConsumer(*rmqconn)
-	if err != nil {
-		log.Fatalf("error creating rabbitmq consumer: %v", err)
+	migrations.Run(db.MigrationURL())
+
+	var rmqconn *config.RabbitMQ
+	if opts.NeedsRabbitMQ() {
+		rmqconn, err = config.NewRabbitMQConnection()
+		if err != nil {
+			log.Fatalf("error connecting to rabbitmq: %v", err)
+		}
+		defer rmqconn.Close()
 	}
-	defer consumer.Close()
 
 	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	g, ctx := errgroup.WithContext(rootCtx)
-	monitorq := monitor.NewMonitorQueue()
 
 	g.Go(func() error {
 		<-ctx.Done()
@@ -63,66 +65,114 @@ func main() {
 		return nil
 	})
 
-	s, err := gocron.NewScheduler()
-	if err != nil {
-		log.Fatalf("error creating scheduler: %v", err)
-	}
+	if opts.Has(RoleScheduler) {
+		jobPublisher, err := mq.NewJobPublisher(*rmqconn)
+		if err != nil {
+			log.Fatalf("error creating rabbitmq job publisher: %v", err)
+		}
+		defer jobPublisher.Close()
+
+		monitorq := monitor.NewMonitorQueue(jobPublisher)
+
+		var leader *monitor.Leader
+		if opts.LeaderElection {
+			leader, err = monitor.NewLeader(conn)
+			if err != nil {
+				log.Fatalf("error creating scheduler leader: %v", err)
+			}
+			defer leader.Release()
+		}
+
+		s, err := gocron.NewScheduler()
+		if err != nil {
+			log.Fatalf("error creating scheduler: %v", err)
+		}
 
-	for i := 0; i < 1; i++ {
 		_, jErr := s.NewJob(
-			gocron.DurationJob(5*time.Second),
-			gocron.NewTask(monitorq.RunScheduler(ctx, conn)),
+			gocron.DurationJob(opts.SchedulerInterval),
+			gocron.NewTask(monitorq.RunScheduler(ctx, conn, leader)),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
 		)
 		if jErr != nil {
 			log.Fatalf("error creating scheduler job: %v", jErr)
 		}
+
+		s.Start()
+		defer s.Shutdown()
+	}
+
+	if opts.Has(RoleWorker) {
+		publisher, err := mq.NewRabbitMQPublisher(*rmqconn)
+		if err != nil {
+			log.Fatalf("error creating rabbitmq publisher: %v", err)
+		}
+		defer publisher.Close()
+
+		jobs, err := mq.NewJobConsumer(*rmqconn, mq.DefaultJobsLocation, opts.Pollers)
+		if err != nil {
+			log.Fatalf("error creating rabbitmq job consumer: %v", err)
+		}
+		defer jobs.Close()
+
+		for i := 0; i < opts.Pollers; i++ {
+			g.Go(func() error {
+				return jobs.ConsumeJobs(ctx, monitor.RunJob(publisher))
+			})
+		}
 	}
-	s.Start()
-	defer s.Shutdown()
 
-	for i := 0; i < 3; i++ {
+	if opts.Has(RoleIngester) {
+		consumer, err := mq.NewConsumer(*rmqconn)
+		if err != nil {
+			log.Fatalf("error creating rabbitmq consumer: %v", err)
+		}
+		defer consumer.Close()
+
+		registrations, err := mq.NewQueueConsumer(*rmqconn, mq.RegistrationsQueue)
+		if err != nil {
+			log.Fatalf("error creating rabbitmq registration consumer: %v", err)
+		}
+		defer registrations.Close()
+
+		for i := 0; i < opts.Consumers; i++ {
+			g.Go(func() error {
+				return consumer.ConsumeFromQueue(ctx, conn)
+			})
+		}
+
 		g.Go(func() error {
-			return monitorq.PollUrls(ctx, conn, publisher)
+			return registrations.ConsumeRegistrations(ctx, conn)
 		})
 	}
 
-	for i := 0; i < 3; i++ {
+	var srv *http.Server
+	if opts.Has(RoleAPI) {
+		a := &handlers.App{DB: conn, AdminAPIKey: opts.AdminAPIKey}
+		srv = &http.Server{Addr: opts.HTTPAddr, Handler: middleware.EnableCORS(opts.CORSOrigins, handlers.NewRouter(a))}
+
 		g.Go(func() error {
-			return consumer.ConsumeFromQueue(ctx, conn)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("http server error: %w", err)
+			}
+			return nil
 		})
 	}
 
-	a := &handlers.App{DB: conn}
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", handlers.HomeHandler)
-	mux.HandleFunc("/create-monitor", a.CreateMonitorhandler)
-	mux.HandleFunc("/update-monitor", a.UpdateMonitorHandler)
-	mux.HandleFunc("/suspend-monitor", a.SuspendMonitorHandler)
-	mux.HandleFunc("/get-all-monitors", a.GetAllMonitorsHandler)
-	mux.HandleFunc("/get-results", a.GetResultsBetweenTimestampsHandler)
-	mux.HandleFunc("/get-metrics", a.GetMetricsBetweenTimestampsHandler)
+	fmt.Printf("probe running with roles %s (pollers=%d consumers=%d addr=%s)\n", opts.RoleNames(), opts.Pollers, opts.Consumers, opts.HTTPAddr)
 
-	srv := &http.Server{Addr: ":8080", Handler: middleware.EnableCORS(mux)}
+	<-rootCtx.Done()
 
-	g.Go(func() error {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			return fmt.Errorf("http server error: %w", err)
+	if srv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			fmt.Printf("http shutdown error: %v\n", err)
 		}
-		return nil
-	})
-
-	fmt.Println("combined server running: API on :8080 and scheduler workers active")
-
-	<-rootCtx.Done()
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		fmt.Printf("http shutdown error: %v\n", err)
 	}
 
 	if err := g.Wait(); err != nil && rootCtx.Err() == nil {
 		log.Fatalf("service failed: %v", err)
 	}
 
-	fmt.Println("combined server gracefully stopped")
+	fmt.Println("probe gracefully stopped")
 }
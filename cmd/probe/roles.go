@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RoleAPI       = "api"
+	RoleScheduler = "scheduler"
+	RoleWorker    = "worker"
+	RoleIngester  = "ingester"
+	RoleAll       = "all"
+)
+
+type Options struct {
+	Roles             map[string]bool
+	HTTPAddr          string
+	Pollers           int
+	Consumers         int
+	SchedulerInterval time.Duration
+	LeaderElection    bool
+	CORSOrigins       []string
+	AdminAPIKey       string
+}
+
+func envOrDefault(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envIntOrDefault(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+func envDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+func envBoolOrDefault(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func ParseOptions(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+
+	roles := fs.String("role", envOrDefault("PROBE_ROLE", RoleAll), "comma-separated process roles: api, scheduler, worker, ingester or all")
+	addr := fs.String("addr", envOrDefault("PROBE_HTTP_ADDR", ":8080"), "listen address for the api role")
+	pollers := fs.Int("pollers", envIntOrDefault("PROBE_POLLERS", 3), "number of concurrent check workers for the worker role")
+	consumers := fs.Int("consumers", envIntOrDefault("PROBE_CONSUMERS", 3), "number of concurrent result consumers for the ingester role")
+	interval := fs.Duration("scheduler-interval", envDurationOrDefault("PROBE_SCHEDULER_INTERVAL", 5*time.Second), "how often the scheduler role claims due monitors")
+	leaderElection := fs.Bool("leader-election", envBoolOrDefault("PROBE_LEADER_ELECTION", false), "only let one scheduler instance claim monitors at a time")
+	corsOrigins := fs.String("cors-origins", envOrDefault("PROBE_CORS_ORIGINS", ""), "comma-separated origins allowed to call the api from a browser")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	parsed, err := ParseRoles(*roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if *pollers <= 0 || *consumers <= 0 {
+		return nil, fmt.Errorf("pollers and consumers must be positive")
+	}
+
+	if *interval < 100*time.Millisecond {
+		return nil, fmt.Errorf("scheduler interval must be at least 100ms")
+	}
+
+	// The admin key is only read from the environment so it does not show
+	// up in process listings.
+	adminKey := os.Getenv("PROBE_ADMIN_API_KEY")
+	if adminKey != "" && len(adminKey) < 32 {
+		return nil, fmt.Errorf("PROBE_ADMIN_API_KEY must be at least 32 characters")
+	}
+
+	return &Options{
+		Roles:             parsed,
+		HTTPAddr:          *addr,
+		Pollers:           *pollers,
+		Consumers:         *consumers,
+		SchedulerInterval: *interval,
+		LeaderElection:    *leaderElection,
+		CORSOrigins:       splitList(*corsOrigins),
+		AdminAPIKey:       adminKey,
+	}, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func ParseRoles(s string) (map[string]bool, error) {
+	roles := make(map[string]bool)
+
+	for _, role := range strings.Split(s, ",") {
+		role = strings.ToLower(strings.TrimSpace(role))
+		switch role {
+		case "":
+			continue
+		case RoleAll:
+			roles[RoleAPI] = true
+			roles[RoleScheduler] = true
+			roles[RoleWorker] = true
+			roles[RoleIngester] = true
+		case RoleAPI, RoleScheduler, RoleWorker, RoleIngester:
+			roles[role] = true
+		default:
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("at least one role is required")
+	}
+
+	return roles, nil
+}
+
+func (o *Options) Has(role string) bool {
+	return o.Roles[role]
+}
+
+func (o *Options) NeedsRabbitMQ() bool {
+	return o.Has(RoleScheduler) || o.Has(RoleWorker) || o.Has(RoleIngester)
+}
+
+func (o *Options) RoleNames() string {
+	names := make([]string, 0, len(o.Roles))
+	for _, role := range []string{RoleAPI, RoleScheduler, RoleWorker, RoleIngester} {
+		if o.Roles[role] {
+			names = append(names, role)
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dhruvthak3r/Probe/api"
+	"github.com/dhruvthak3r/Probe/internal/validation"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: probectl <command> [flags]
+
+commands:
+  export  write every monitor to a monitors file
+  sync    make the monitors match a monitors file
+  import  create monitors from a curl command, HAR file, Postman collection
+          or OpenAPI document
+
+Set PROBE_API_URL and PROBE_API_KEY, or pass --url and --key.`
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+func main() {
+	_ = godotenv.Load()
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "sync":
+		sync(os.Args[2:])
+	case "import":
+		importMonitors(os.Args[2:])
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *client) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	c := &client{http: &http.Client{Timeout: 5 * time.Minute}}
+
+	baseURL := os.Getenv("PROBE_API_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	fs.StringVar(&c.baseURL, "url", baseURL, "probe api address")
+	fs.StringVar(&c.key, "key", os.Getenv("PROBE_API_KEY"), "api key")
+
+	return fs, c
+}
+
+func export(args []string) {
+	fs, c := newFlagSet("export")
+	format := fs.String("format", "yaml", "yaml or json")
+	out := fs.String("o", "", "file to write instead of stdout")
+	fs.Parse(args)
+
+	body, err := c.do(http.MethodGet, "/monitors/export", url.Values{"format": {*format}}, nil, "")
+	if err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(body)
+		return
+	}
+	if err := os.WriteFile(*out, body, 0o644); err != nil {
+		log.Fatalf("error writing %s: %v", *out, err)
+	}
+}
+
+func sync(args []string) {
+	fs, c := newFlagSet("sync")
+	file := fs.String("f", "monitors.yaml", "monitors file to apply")
+	dryRun := fs.Bool("dry-run", false, "only print the plan")
+	prune := fs.Bool("prune", false, "delete monitors missing from the file")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("error reading %s: %v", *file, err)
+	}
+
+	query := url.Values{
+		"dry_run": {fmt.Sprint(*dryRun)},
+		"prune":   {fmt.Sprint(*prune)},
+	}
+	body, err := c.do(http.MethodPost, "/monitors/sync", query, data, "application/yaml")
+	if err != nil {
+		log.Fatalf("sync failed: %v", err)
+	}
+
+	var plan api.SyncPlan
+	if err := json.Unmarshal(body, &plan); err != nil {
+		log.Fatalf("error decoding sync plan: %v", err)
+	}
+	printPlan(&plan)
+}
+
+// listFlag collects a flag given several times.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func importMonitors(args []string) {
+	fs, c := newFlagSet("import")
+	format := fs.String("format", "openapi", "curl, har, postman or openapi")
+	file := fs.String("f", "", "file to import, - for stdin")
+	serverURL := fs.String("server-url", "", "server to check instead of the document's first one (openapi)")
+	group := fs.String("group", "", "group to put the monitors in")
+	frequency := fs.Int("frequency", 60, "check frequency in seconds of new monitors")
+	dryRun := fs.Bool("dry-run", false, "only print what would change")
+	var operations, tags listFlag
+	fs.Var(&operations, "operation", "operationId or \"METHOD /path\" to import, repeatable (openapi)")
+	fs.Var(&tags, "tag", "tag to add to new monitors, repeatable")
+	fs.Parse(args)
+
+	if *file == "" {
+		log.Fatal("-f is required")
+	}
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		log.Fatalf("error reading %s: %v", *file, err)
+	}
+
+	query := url.Values{
+		"format":         {*format},
+		"frequency_secs": {fmt.Sprint(*frequency)},
+		"operation":      operations,
+		"tag":            tags,
+	}
+	if *serverURL != "" {
+		query.Set("server_url", *serverURL)
+	}
+	if *group != "" {
+		query.Set("group", *group)
+	}
+	path := "/monitors/import"
+	if *dryRun {
+		path = "/monitors/import/preview"
+	}
+
+	body, err := c.do(http.MethodPost, path, query, data, "application/octet-stream")
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+
+	var result api.ImportResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		log.Fatalf("error decoding import result: %v", err)
+	}
+	printImport(&result)
+}
+
+func (c *client) do(method string, path string, query url.Values, body []byte, contentType string) ([]byte, error) {
+	endpoint := strings.TrimSuffix(c.baseURL, "/") + api.APIPrefix + path + "?" + query.Encode()
+
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.key)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, apiError(res.Status, data)
+	}
+	return data, nil
+}
+
+func apiError(status string, data []byte) error {
+	var envelope struct {
+		Error struct {
+			Code    string                  `json:"code"`
+			Message string                  `json:"message"`
+			Details []validation.FieldError `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error.Code == "" {
+		return fmt.Errorf("%s: %s", status, bytes.TrimSpace(data))
+	}
+
+	msg := fmt.Sprintf("%s: %s", status, envelope.Error.Message)
+	for _, fe := range envelope.Error.Details {
+		msg += fmt.Sprintf("\n  %s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func printPlan(plan *api.SyncPlan) {
+	for _, a := range plan.Creates {
+		fmt.Printf("+ %s (%s)\n", a.Slug, a.Name)
+	}
+	for _, a := range plan.Updates {
+		fields := make([]string, 0, len(a.Changes))
+		for field := range a.Changes {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		fmt.Printf("~ %s: %s\n", a.Slug, strings.Join(fields, ", "))
+	}
+	for _, a := range plan.Deletes {
+		fmt.Printf("- %s (%s)\n", a.Slug, a.Name)
+	}
+	for _, a := range plan.Unmanaged {
+		fmt.Printf("? %s is not in the file, use --prune to delete it\n", a.Slug)
+	}
+
+	if plan.DryRun {
+		fmt.Printf("plan: %d to create, %d to update, %d to delete, %d unchanged\n",
+			len(plan.Creates), len(plan.Updates), len(plan.Deletes), plan.Unchanged)
+		return
+	}
+	fmt.Printf("created %d, updated %d, deleted %d, %d unchanged\n",
+		len(plan.Creates), len(plan.Updates), len(plan.Deletes), plan.Unchanged)
+}
+
+func printImport(result *api.ImportResult) {
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	counts := make(map[string]int)
+	invalid := 0
+	for i, m := range result.Monitors {
+		if len(m.Errors) > 0 {
+			invalid++
+			for _, fe := range m.Errors {
+				fmt.Printf("! monitors[%d] %s: %s: %s\n", i, m.Payload.Name, fe.Field, fe.Message)
+			}
+			continue
+		}
+		counts[m.Action]++
+		switch m.Action {
+		case "create":
+			fmt.Printf("+ %s %s %s\n", m.Payload.Name, m.Payload.HttpMethod, m.Payload.Url)
+		case "update":
+			fields := make([]string, 0, len(m.Changes))
+			for field := range m.Changes {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			fmt.Printf("~ %s: %s\n", m.Payload.Slug, strings.Join(fields, ", "))
+		}
+	}
+
+	if result.DryRun {
+		fmt.Printf("plan: %d to create, %d to update, %d unchanged, %d invalid\n",
+			counts["create"], counts["update"], counts["unchanged"], invalid)
+		return
+	}
+	fmt.Printf("created %d, updated %d, %d unchanged\n", counts["create"], counts["update"], counts["unchanged"])
+}
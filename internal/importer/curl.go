@@ -0,0 +1,386 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// curlFlags maps the curl options that take a value to their long name.
+// Options missing here and from curlSwitches are skipped with a warning.
+var curlFlags = map[string]string{
+	"-X": "--request", "--request": "--request",
+	"-H": "--header", "--header": "--header",
+	"-d": "--data", "--data": "--data",
+	"--data-raw": "--data-raw", "--data-binary": "--data-binary", "--data-ascii": "--data-ascii",
+	"--data-urlencode": "--data-urlencode",
+	"-u":               "--user", "--user": "--user",
+	"-A": "--user-agent", "--user-agent": "--user-agent",
+	"-e": "--referer", "--referer": "--referer",
+	"-b": "--cookie", "--cookie": "--cookie",
+	"-m": "--max-time", "--max-time": "--max-time",
+	"--connect-timeout": "--connect-timeout",
+	"--url":             "--url",
+	"-o":                "--output", "--output": "--output",
+	"-w": "--write-out", "--write-out": "--write-out",
+	"-F": "--form", "--form": "--form",
+	"-T": "--upload-file", "--upload-file": "--upload-file",
+	"-x": "--proxy", "--proxy": "--proxy",
+	"-r": "--range", "--range": "--range",
+	"--retry":  "--retry",
+	"--cacert": "--cacert", "--cert": "--cert", "-E": "--cert", "--key": "--key",
+	"--resolve": "--resolve",
+}
+
+// curlSwitches are curl options without a value.
+var curlSwitches = map[string]string{
+	"-k": "--insecure", "--insecure": "--insecure",
+	"-I": "--head", "--head": "--head",
+	"-G": "--get", "--get": "--get",
+	"-L": "--location", "--location": "--location",
+	"-s": "--silent", "--silent": "--silent",
+	"-S": "--show-error", "--show-error": "--show-error",
+	"-v": "--verbose", "--verbose": "--verbose",
+	"-i": "--include", "--include": "--include",
+	"-f": "--fail", "--fail": "--fail",
+	"--compressed": "--compressed",
+	"-N":           "--no-buffer", "--no-buffer": "--no-buffer",
+	"--http1.1": "--http1.1", "--http2": "--http2",
+}
+
+// ParseCurl reads a curl command line, as copied from a shell or from the
+// "copy as curl" of a browser.
+func ParseCurl(command string) (Request, []string, error) {
+	var req Request
+	var warnings []string
+
+	args, err := splitShellWords(command)
+	if err != nil {
+		return req, nil, err
+	}
+	if len(args) > 0 && (args[0] == "curl" || strings.HasSuffix(args[0], "/curl")) {
+		args = args[1:]
+	}
+
+	var data []string
+	var get bool
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if req.URL != "" {
+				warnings = append(warnings, fmt.Sprintf("ignored extra url %s", arg))
+				continue
+			}
+			req.URL = arg
+			continue
+		}
+
+		name, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if n, v, ok := strings.Cut(arg, "="); ok {
+				name, value, hasValue = n, v, true
+			}
+		} else if len(arg) > 2 {
+			// Short options can be combined as in -sSL, or carry their
+			// value as in -XPOST.
+			flags, rest, ok := splitShortOptions(arg)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("ignored unknown option %s", arg))
+				continue
+			}
+			for _, flag := range flags[:len(flags)-1] {
+				applyCurlSwitch(&req, &get, curlSwitches[flag])
+			}
+			name = flags[len(flags)-1]
+			if rest != "" {
+				value, hasValue = rest, true
+			}
+		}
+
+		if long, ok := curlSwitches[name]; ok {
+			applyCurlSwitch(&req, &get, long)
+			continue
+		}
+
+		long, ok := curlFlags[name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("ignored unknown option %s", name))
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return req, warnings, fmt.Errorf("option %s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch long {
+		case "--request":
+			req.Method = strings.ToUpper(value)
+		case "--header":
+			header, val, ok := strings.Cut(value, ":")
+			if !ok || strings.TrimSpace(header) == "" {
+				warnings = append(warnings, fmt.Sprintf("ignored malformed header %q", value))
+				continue
+			}
+			if skippedHeaders[http.CanonicalHeaderKey(strings.TrimSpace(header))] {
+				continue
+			}
+			req.addHeader(header, val)
+		case "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				warnings = append(warnings, fmt.Sprintf("ignored body read from file %s", value[1:]))
+				continue
+			}
+			if long != "--data-binary" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			data = append(data, urlencodeData(value))
+		case "--user":
+			req.Username, req.Password, _ = strings.Cut(value, ":")
+		case "--user-agent":
+			req.addHeader("User-Agent", value)
+		case "--referer":
+			req.addHeader("Referer", value)
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				warnings = append(warnings, fmt.Sprintf("ignored cookies read from file %s", value))
+				continue
+			}
+			req.addHeader("Cookie", value)
+		case "--max-time", "--connect-timeout":
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs <= 0 {
+				warnings = append(warnings, fmt.Sprintf("ignored invalid %s %s", long, value))
+				continue
+			}
+			if t := int(secs + 0.999); req.TimeoutSecs == 0 || t < req.TimeoutSecs {
+				req.TimeoutSecs = t
+			}
+		case "--url":
+			req.URL = value
+		case "--output", "--write-out", "--retry":
+		case "--form", "--upload-file":
+			warnings = append(warnings, fmt.Sprintf("ignored %s, only raw bodies are supported", long))
+		default:
+			warnings = append(warnings, fmt.Sprintf("ignored option %s", long))
+		}
+	}
+
+	if req.URL == "" {
+		return req, warnings, errors.New("curl command has no url")
+	}
+	if !strings.Contains(req.URL, "://") {
+		req.URL = "http://" + req.URL
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case get && body != "":
+		sep := "?"
+		if strings.Contains(req.URL, "?") {
+			sep = "&"
+		}
+		req.URL += sep + body
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+	case body != "":
+		req.Body = body
+		if !req.hasHeader("Content-Type") {
+			req.addHeader("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	req.finish()
+	return req, warnings, nil
+}
+
+func applyCurlSwitch(req *Request, get *bool, long string) {
+	switch long {
+	case "--insecure":
+		req.Insecure = true
+	case "--head":
+		req.Method = http.MethodHead
+	case "--get":
+		*get = true
+	}
+}
+
+// splitShortOptions splits a group of short options such as -sSLk or -XPOST
+// into its options and the value of the last one.
+func splitShortOptions(arg string) ([]string, string, bool) {
+	var flags []string
+	for i := 1; i < len(arg); i++ {
+		flag := "-" + string(arg[i])
+		if _, ok := curlSwitches[flag]; ok {
+			flags = append(flags, flag)
+			continue
+		}
+		if _, ok := curlFlags[flag]; ok {
+			return append(flags, flag), arg[i+1:], true
+		}
+		return nil, "", false
+	}
+	return flags, "", len(flags) > 0
+}
+
+// urlencodeData follows curl's --data-urlencode forms "content",
+// "=content" and "name=content".
+func urlencodeData(value string) string {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return url.QueryEscape(value)
+	}
+	if name == "" {
+		return url.QueryEscape(content)
+	}
+	return name + "=" + url.QueryEscape(content)
+}
+
+// splitShellWords splits a command line the way a POSIX shell would,
+// handling quotes, backslash escapes and line continuations.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == '\n' || s[i] == '\r' {
+					if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+						i++
+					}
+					continue
+				}
+				word.WriteByte(s[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			// Bash $'...' strings, as produced by browsers.
+			i += 2
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						word.WriteByte('\n')
+					case 't':
+						word.WriteByte('\t')
+					case 'r':
+						word.WriteByte('\r')
+					default:
+						word.WriteByte(s[i])
+					}
+					continue
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated $' quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// Curl renders a request as a curl command that can be run from a shell.
+// Passwords are left out, so curl prompts for them.
+func Curl(r Request) string {
+	parts := []string{"curl"}
+	if r.Method != "" && r.Method != http.MethodGet && !(r.Method == http.MethodPost && r.Body != "") {
+		if r.Method == http.MethodHead {
+			parts = append(parts, "--head")
+		} else {
+			parts = append(parts, "-X", r.Method)
+		}
+	}
+	if r.Insecure {
+		parts = append(parts, "-k")
+	}
+	if r.TimeoutSecs > 0 {
+		parts = append(parts, "--max-time", strconv.Itoa(r.TimeoutSecs))
+	}
+
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.Headers[name] {
+			parts = append(parts, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	if r.Username != "" {
+		parts = append(parts, "-u", shellQuote(r.Username))
+	}
+	if r.Body != "" {
+		parts = append(parts, "--data-raw", shellQuote(r.Body))
+	}
+	parts = append(parts, shellQuote(r.URL))
+
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
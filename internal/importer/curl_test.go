@@ -0,0 +1,297 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func headers(kv ...string) map[string][]string {
+	h := make(map[string][]string)
+	for i := 0; i < len(kv); i += 2 {
+		h[kv[i]] = append(h[kv[i]], kv[i+1])
+	}
+	return h
+}
+
+// hasWarning reports whether one of warnings contains want.
+func hasWarning(warnings []string, want string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		want     Request
+		warnings []string
+	}{
+		{
+			name: "plain get",
+			cmd:  "curl https://api.example.com/health",
+			want: Request{Name: "GET api.example.com/health", Method: "GET", URL: "https://api.example.com/health"},
+		},
+		{
+			name: "copied from a browser",
+			cmd: `curl 'https://api.example.com/v1/orders?limit=10' \
+  -H 'accept: application/json' \
+  -H 'authorization: Bearer abc' \
+  -H 'content-length: 14' \
+  --data-raw $'{"note":"it\'s"}' \
+  --compressed`,
+			want: Request{
+				Name:    "POST api.example.com/v1/orders",
+				Method:  "POST",
+				URL:     "https://api.example.com/v1/orders?limit=10",
+				Headers: headers("Accept", "application/json", "Authorization", "Bearer abc", "Content-Type", "application/x-www-form-urlencoded"),
+				Body:    `{"note":"it's"}`,
+			},
+		},
+		{
+			name: "windows line continuations",
+			cmd:  "curl -X DELETE \\\r\n  https://api.example.com/sessions/1",
+			want: Request{Name: "DELETE api.example.com/sessions/1", Method: "DELETE", URL: "https://api.example.com/sessions/1"},
+		},
+		{
+			name: "method and data joined to their options",
+			cmd:  `curl -XPUT -d'a=1' -d 'b=2' -H 'Content-Type: text/plain' https://api.example.com/items`,
+			want: Request{
+				Name:    "PUT api.example.com/items",
+				Method:  "PUT",
+				URL:     "https://api.example.com/items",
+				Headers: headers("Content-Type", "text/plain"),
+				Body:    "a=1&b=2",
+			},
+		},
+		{
+			name: "long options with equals",
+			cmd:  `curl --request=patch --header='X-Token: 1' --data-binary='{"a":1}' --url=https://api.example.com/a`,
+			want: Request{
+				Name:    "PATCH api.example.com/a",
+				Method:  "PATCH",
+				URL:     "https://api.example.com/a",
+				Headers: headers("X-Token", "1", "Content-Type", "application/x-www-form-urlencoded"),
+				Body:    `{"a":1}`,
+			},
+		},
+		{
+			name: "data moved into the query with -G",
+			cmd:  `curl -G -d q=go -d page=2 'https://api.example.com/search?lang=en'`,
+			want: Request{Name: "GET api.example.com/search", Method: "GET", URL: "https://api.example.com/search?lang=en&q=go&page=2"},
+		},
+		{
+			name: "urlencoded data",
+			cmd:  `curl --data-urlencode 'q=a b&c' --data-urlencode '=x/y' https://api.example.com/search`,
+			want: Request{
+				Name:    "POST api.example.com/search",
+				Method:  "POST",
+				URL:     "https://api.example.com/search",
+				Headers: headers("Content-Type", "application/x-www-form-urlencoded"),
+				Body:    "q=a+b%26c&x%2Fy",
+			},
+		},
+		{
+			name: "line breaks are stripped from -d but not --data-binary",
+			cmd:  "curl -d $'a=1\\nb=2' --data-binary $'\\nc' https://api.example.com/",
+			want: Request{
+				Name:    "POST api.example.com/",
+				Method:  "POST",
+				URL:     "https://api.example.com/",
+				Headers: headers("Content-Type", "application/x-www-form-urlencoded"),
+				Body:    "a=1b=2&\nc",
+			},
+		},
+		{
+			name: "credentials with a colon in the password",
+			cmd:  `curl -u 'probe:pa:ss' https://api.example.com/private`,
+			want: Request{Name: "GET api.example.com/private", Method: "GET", URL: "https://api.example.com/private", Username: "probe", Password: "pa:ss"},
+		},
+		{
+			name: "combined switches",
+			cmd:  `curl -sSLk https://self-signed.example.com/`,
+			want: Request{Name: "GET self-signed.example.com/", Method: "GET", URL: "https://self-signed.example.com/", Insecure: true},
+		},
+		{
+			name: "shortest timeout rounded up",
+			cmd:  `curl --connect-timeout 5 -m 2.5 https://api.example.com/`,
+			want: Request{Name: "GET api.example.com/", Method: "GET", URL: "https://api.example.com/", TimeoutSecs: 3},
+		},
+		{
+			name: "head request",
+			cmd:  `curl -I https://api.example.com/`,
+			want: Request{Name: "HEAD api.example.com/", Method: "HEAD", URL: "https://api.example.com/"},
+		},
+		{
+			name: "user agent, referer and cookies",
+			cmd:  `curl -A probe/1.0 -e https://example.com -b 'session=1; theme=dark' https://api.example.com/`,
+			want: Request{
+				Name:    "GET api.example.com/",
+				Method:  "GET",
+				URL:     "https://api.example.com/",
+				Headers: headers("User-Agent", "probe/1.0", "Referer", "https://example.com", "Cookie", "session=1; theme=dark"),
+			},
+		},
+		{
+			name: "double quotes with escapes",
+			cmd:  `curl -H "X-Msg: say \"hi\" to \$HOME" "https://api.example.com/"`,
+			want: Request{Name: "GET api.example.com/", Method: "GET", URL: "https://api.example.com/", Headers: headers("X-Msg", `say "hi" to $HOME`)},
+		},
+		{
+			name: "url without a scheme",
+			cmd:  `/usr/bin/curl example.com/ping`,
+			want: Request{Name: "GET example.com/ping", Method: "GET", URL: "http://example.com/ping"},
+		},
+		{
+			name: "unsupported options",
+			cmd:  `curl -Z -F 'file=@a.txt' -d @body.json -b cookies.txt -H 'broken' --retry 3 -o out.json https://a.example.com https://b.example.com`,
+			want: Request{Name: "GET a.example.com", Method: "GET", URL: "https://a.example.com"},
+			warnings: []string{
+				"ignored unknown option -Z",
+				"ignored --form",
+				"ignored body read from file body.json",
+				"ignored cookies read from file cookies.txt",
+				`ignored malformed header "broken"`,
+				"ignored extra url https://b.example.com",
+			},
+		},
+		{
+			name:     "invalid timeout",
+			cmd:      `curl -m soon https://api.example.com/`,
+			want:     Request{Name: "GET api.example.com/", Method: "GET", URL: "https://api.example.com/"},
+			warnings: []string{"ignored invalid --max-time soon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseCurl(tt.cmd)
+			if err != nil {
+				t.Fatalf("ParseCurl() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCurl() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+			for _, want := range tt.warnings {
+				if !hasWarning(warnings, want) {
+					t.Errorf("warnings = %q, want one containing %q", warnings, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		want string
+	}{
+		{name: "empty", cmd: "", want: "curl command has no url"},
+		{name: "only curl", cmd: "curl -s", want: "curl command has no url"},
+		{name: "unterminated single quote", cmd: `curl 'https://api.example.com`, want: "unterminated single quote"},
+		{name: "unterminated double quote", cmd: `curl "https://api.example.com`, want: "unterminated double quote"},
+		{name: "unterminated ansi-c quote", cmd: `curl $'https://api.example.com`, want: "unterminated $' quote"},
+		{name: "option without its value", cmd: `curl https://api.example.com -H`, want: "option -H needs a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseCurl(tt.cmd)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseCurl() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurlRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{
+			name: "get",
+			req:  Request{Method: "GET", URL: "https://api.example.com/health?verbose=1&lang=en"},
+		},
+		{
+			name: "post with a json body",
+			req: Request{
+				Method:  "POST",
+				URL:     "https://api.example.com/orders",
+				Headers: headers("Content-Type", "application/json", "X-Trace", "a", "X-Trace", "b"),
+				Body:    `{"note": "it's \"quoted\"", "path": "$HOME"}`,
+			},
+		},
+		{
+			name: "delete with a body",
+			req: Request{
+				Method:  "DELETE",
+				URL:     "https://api.example.com/orders/1",
+				Headers: headers("Content-Type", "application/json"),
+				Body:    `{"reason":"test"}`,
+			},
+		},
+		{
+			name: "head",
+			req:  Request{Method: "HEAD", URL: "https://api.example.com/"},
+		},
+		{
+			name: "put without a body",
+			req:  Request{Method: "PUT", URL: "https://api.example.com/lock"},
+		},
+		{
+			name: "credentials, timeout and insecure",
+			req: Request{
+				Method:      "GET",
+				URL:         "https://self-signed.example.com/",
+				Headers:     headers("Authorization", "Bearer a b c", "User-Agent", "probe/1.0"),
+				Username:    "probe user",
+				Insecure:    true,
+				TimeoutSecs: 10,
+			},
+		},
+		{
+			name: "multi-line body",
+			req: Request{
+				Method:  "POST",
+				URL:     "https://api.example.com/graphql",
+				Headers: headers("Content-Type", "application/json"),
+				Body:    "{\n  \"query\": \"{ viewer { id } }\"\n}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Curl(tt.req)
+			got, warnings, err := ParseCurl(cmd)
+			if err != nil {
+				t.Fatalf("ParseCurl(%s) error = %v", cmd, err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("ParseCurl(%s) warnings = %q", cmd, warnings)
+			}
+
+			want := tt.req
+			want.finish()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseCurl(%s) =\n%+v\nwant\n%+v", cmd, got, want)
+			}
+		})
+	}
+}
+
+func TestCurlLeavesOutPassword(t *testing.T) {
+	cmd := Curl(Request{Method: "GET", URL: "https://api.example.com/", Username: "probe", Password: "secret"})
+	if strings.Contains(cmd, "secret") {
+		t.Errorf("Curl() = %s, want the password left out", cmd)
+	}
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ParseHAR reads every entry of a HAR file, as saved from the network panel
+// of a browser. The recorded response status becomes the expected status.
+func ParseHAR(data []byte) ([]Request, []string, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, nil, fmt.Errorf("invalid har file: %v", err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, nil, errors.New("har file has no entries")
+	}
+
+	var warnings []string
+	requests := make([]Request, 0, len(har.Log.Entries))
+
+	for i, entry := range har.Log.Entries {
+		if entry.Request.URL == "" {
+			warnings = append(warnings, fmt.Sprintf("skipped entry %d without a url", i))
+			continue
+		}
+
+		req := Request{
+			Method: entry.Request.Method,
+			URL:    entry.Request.URL,
+		}
+		for _, h := range entry.Request.Headers {
+			// HTTP/2 pseudo headers such as :authority are not real headers.
+			if strings.HasPrefix(h.Name, ":") || skippedHeaders[http.CanonicalHeaderKey(h.Name)] {
+				continue
+			}
+			req.addHeader(h.Name, h.Value)
+		}
+		if entry.Request.PostData != nil && entry.Request.PostData.Text != "" {
+			req.Body = entry.Request.PostData.Text
+			if !req.hasHeader("Content-Type") && entry.Request.PostData.MimeType != "" {
+				req.addHeader("Content-Type", entry.Request.PostData.MimeType)
+			}
+		}
+		if status := entry.Response.Status; status >= 100 && status < 400 {
+			req.StatusCodes = []int{status}
+		}
+
+		req.finish()
+		requests = append(requests, req)
+	}
+
+	return requests, warnings, nil
+}
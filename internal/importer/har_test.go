@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const harFixture = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://api.example.com/v1/me",
+          "httpVersion": "http/2.0",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": ":method", "value": "GET"},
+            {"name": "accept", "value": "application/json"},
+            {"name": "authorization", "value": "Bearer abc"},
+            {"name": "host", "value": "api.example.com"}
+          ]
+        },
+        "response": {"status": 200, "statusText": ""}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/orders",
+          "headers": [
+            {"name": "Content-Length", "value": "11"},
+            {"name": "Connection", "value": "keep-alive"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"qty\": 1}"}
+        },
+        "response": {"status": 201}
+      },
+      {
+        "request": {
+          "method": "PUT",
+          "url": "https://api.example.com/v1/orders/1",
+          "headers": [{"name": "Content-Type", "value": "text/plain"}],
+          "postData": {"mimeType": "application/json", "text": "note"}
+        },
+        "response": {"status": 404}
+      },
+      {
+        "request": {"method": "GET", "url": ""},
+        "response": {"status": 200}
+      },
+      {
+        "request": {"method": "get", "url": "https://cdn.example.com/app.js", "postData": {"mimeType": "", "text": ""}},
+        "response": {"status": 0}
+      }
+    ]
+  }
+}`
+
+func TestParseHAR(t *testing.T) {
+	got, warnings, err := ParseHAR([]byte(harFixture))
+	if err != nil {
+		t.Fatalf("ParseHAR() error = %v", err)
+	}
+
+	want := []Request{
+		{
+			Name:        "GET api.example.com/v1/me",
+			Method:      "GET",
+			URL:         "https://api.example.com/v1/me",
+			Headers:     headers("Accept", "application/json", "Authorization", "Bearer abc"),
+			StatusCodes: []int{200},
+		},
+		{
+			Name:        "POST api.example.com/v1/orders",
+			Method:      "POST",
+			URL:         "https://api.example.com/v1/orders",
+			Headers:     headers("Content-Type", "application/json"),
+			Body:        `{"qty": 1}`,
+			StatusCodes: []int{201},
+		},
+		{
+			Name:    "PUT api.example.com/v1/orders/1",
+			Method:  "PUT",
+			URL:     "https://api.example.com/v1/orders/1",
+			Headers: headers("Content-Type", "text/plain"),
+			Body:    "note",
+		},
+		{
+			Name:   "GET cdn.example.com/app.js",
+			Method: "GET",
+			URL:    "https://cdn.example.com/app.js",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHAR() =\n%+v\nwant\n%+v", got, want)
+	}
+	if len(warnings) != 1 || !hasWarning(warnings, "skipped entry 3 without a url") {
+		t.Errorf("warnings = %q, want the entry without a url", warnings)
+	}
+}
+
+func TestParseHARErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not json", data: "GET / HTTP/1.1", want: "invalid har file"},
+		{name: "wrong shape", data: `{"log": {"entries": {}}}`, want: "invalid har file"},
+		{name: "no log", data: `{}`, want: "har file has no entries"},
+		{name: "no entries", data: `{"log": {"entries": []}}`, want: "har file has no entries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseHAR([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseHAR() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+const (
+	FormatCurl    = "curl"
+	FormatHAR     = "har"
+	FormatPostman = "postman"
+	FormatOpenAPI = "openapi"
+)
+
+var Formats = []string{FormatCurl, FormatHAR, FormatPostman, FormatOpenAPI}
+
+// Options narrow down what is read from an OpenAPI document. Other formats
+// ignore them.
+type Options struct {
+	// ServerURL replaces the document's first server.
+	ServerURL string
+	// Operations selects operations by operationId or as "METHOD /path".
+	// All GET operations are read when it is empty.
+	Operations []string
+}
+
+// Request is an HTTP request read from a curl command, HAR entry, Postman
+// collection item or OpenAPI operation.
+type Request struct {
+	// Key identifies the request across imports of the same document. It is
+	// empty for formats whose requests have no stable identity.
+	Key      string
+	Name     string
+	Group    string
+	Method   string
+	URL      string
+	Headers  map[string][]string
+	Body     string
+	Username string
+	Password string
+	Insecure bool
+	// TimeoutSecs is zero unless the source sets a timeout.
+	TimeoutSecs int
+	// StatusCodes are the response statuses that mean success, if the
+	// source records them.
+	StatusCodes []int
+	// ResponseSchema is the JSON schema of a successful response body, if
+	// the source declares one.
+	ResponseSchema string
+}
+
+// Parse reads every request of a document in one of Formats. Warnings
+// describe the parts of the document that could not be imported.
+func Parse(format string, data []byte, opts Options) ([]Request, []string, error) {
+	switch format {
+	case FormatCurl:
+		req, warnings, err := ParseCurl(string(data))
+		if err != nil {
+			return nil, warnings, err
+		}
+		return []Request{req}, warnings, nil
+	case FormatHAR:
+		return ParseHAR(data)
+	case FormatPostman:
+		return ParsePostman(data)
+	case FormatOpenAPI:
+		return ParseOpenAPI(data, opts)
+	}
+	return nil, nil, fmt.Errorf("format must be one of %s", strings.Join(Formats, ", "))
+}
+
+func (r *Request) addHeader(name string, value string) {
+	if r.Headers == nil {
+		r.Headers = make(map[string][]string)
+	}
+	name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+	r.Headers[name] = append(r.Headers[name], strings.TrimSpace(value))
+}
+
+func (r *Request) hasHeader(name string) bool {
+	_, ok := r.Headers[textproto.CanonicalMIMEHeaderKey(name)]
+	return ok
+}
+
+// skippedHeaders are set by the http client for every check, or only make
+// sense for the connection a request was recorded on.
+var skippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Host":              true,
+}
+
+// finish fills in what every importer leaves for last: the method implied by
+// a body, and a name when the source has none.
+func (r *Request) finish() {
+	r.Method = strings.ToUpper(r.Method)
+	if r.Method == "" {
+		r.Method = http.MethodGet
+		if r.Body != "" {
+			r.Method = http.MethodPost
+		}
+	}
+	if r.Name == "" {
+		r.Name = defaultName(r.Method, r.URL)
+	}
+}
+
+func defaultName(method string, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return method + " " + rawURL
+	}
+	return method + " " + u.Host + u.EscapedPath()
+}
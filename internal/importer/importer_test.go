@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format  string
+		data    string
+		want    int
+		wantErr string
+	}{
+		{format: FormatCurl, data: "curl https://api.example.com/health", want: 1},
+		{format: FormatHAR, data: harFixture, want: 4},
+		{format: FormatPostman, data: postmanFixture, want: 5},
+		{format: FormatOpenAPI, data: openAPIFixture, want: 3},
+		{format: FormatCurl, data: "curl -s", wantErr: "curl command has no url"},
+		{format: "insomnia", data: "{}", wantErr: "format must be one of curl, har, postman, openapi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, _, err := Parse(tt.format, []byte(tt.data), Options{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Parse() returned %d requests, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestFinish(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        Request
+		wantMethod string
+		wantName   string
+	}{
+		{name: "get by default", req: Request{URL: "https://api.example.com/a%20b?x=1"}, wantMethod: "GET", wantName: "GET api.example.com/a%20b"},
+		{name: "post with a body", req: Request{URL: "https://api.example.com/", Body: "x"}, wantMethod: "POST", wantName: "POST api.example.com/"},
+		{name: "lower case method", req: Request{Method: "patch", URL: "https://api.example.com/"}, wantMethod: "PATCH", wantName: "PATCH api.example.com/"},
+		{name: "name kept", req: Request{Name: "Health", URL: "https://api.example.com/"}, wantMethod: "GET", wantName: "Health"},
+		{name: "url without a host", req: Request{URL: "{{baseUrl}}/health"}, wantMethod: "GET", wantName: "GET {{baseUrl}}/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.finish()
+			if req.Method != tt.wantMethod || req.Name != tt.wantName {
+				t.Errorf("finish() = %q %q, want %q %q", req.Method, req.Name, tt.wantMethod, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,690 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operations of a path item that can be monitored.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// maxSchemaNodes bounds the values a schema expands to once its references
+// are inlined. A shared reference is copied at every use, so a small document
+// can otherwise describe an exponentially large schema.
+const maxSchemaNodes = 50000
+
+var errSchemaTooLarge = fmt.Errorf("expands to more than %d values once its references are inlined", maxSchemaNodes)
+
+// openAPIParser keeps the document as plain values, since a $ref can point
+// anywhere in it.
+type openAPIParser struct {
+	doc      map[string]any
+	warnings []string
+	// authenticated lists the operations that need credentials.
+	authenticated []string
+	// nodes is what is left of maxSchemaNodes for the schema being expanded.
+	nodes int
+}
+
+func (p *openAPIParser) tooLarge(label string, what string) error {
+	return fmt.Errorf("the %s of %s %w", what, label, errSchemaTooLarge)
+}
+
+// ParseOpenAPI reads the operations of an OpenAPI 3 document, in YAML or
+// JSON, filling in example parameters and bodies. Without a selection only
+// GET operations are read. Each request is keyed by
+// the document's title and the operationId, or the method and path when
+// there is none, so importing a newer version of the document finds the same
+// requests again.
+func ParseOpenAPI(data []byte, opts Options) ([]Request, []string, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid openapi document: %v", err)
+	}
+	doc, ok := plainValue(raw).(map[string]any)
+	if !ok {
+		return nil, nil, errors.New("invalid openapi document: not an object")
+	}
+	if version := stringValue(doc["openapi"]); !strings.HasPrefix(version, "3.") {
+		if doc["swagger"] != nil {
+			return nil, nil, errors.New("swagger 2 documents are not supported, convert the document to openapi 3")
+		}
+		return nil, nil, errors.New("invalid openapi document: missing openapi 3 version")
+	}
+
+	p := &openAPIParser{doc: doc}
+	info, _ := doc["info"].(map[string]any)
+	title := stringValue(info["title"])
+
+	server, err := p.serverURL(opts.ServerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := make(map[string]bool, len(opts.Operations))
+	for _, op := range opts.Operations {
+		selected[operationSelector(op)] = false
+	}
+
+	var requests []Request
+	paths, _ := doc["paths"].(map[string]any)
+	for _, path := range sortedKeys(paths) {
+		item, err := p.resolve(paths[path])
+		if err != nil {
+			p.warnings = append(p.warnings, fmt.Sprintf("skipped %s: %v", path, err))
+			continue
+		}
+
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			id := stringValue(op["operationId"])
+			label := strings.ToUpper(method) + " " + path
+
+			// Checks run an operation over and over, so operations other
+			// than GET are only read when selected.
+			if len(selected) == 0 && method != "get" {
+				continue
+			}
+			if len(selected) > 0 {
+				matched := false
+				for _, key := range []string{id, label} {
+					if _, ok := selected[key]; ok && key != "" {
+						selected[key] = true
+						matched = true
+					}
+				}
+				if !matched {
+					continue
+				}
+			}
+
+			req, err := p.operation(server, title, path, method, item, op)
+			if errors.Is(err, errSchemaTooLarge) {
+				return nil, p.warnings, err
+			}
+			if err != nil {
+				p.warnings = append(p.warnings, fmt.Sprintf("skipped %s: %v", label, err))
+				continue
+			}
+			requests = append(requests, req)
+		}
+	}
+
+	if len(p.authenticated) > 0 {
+		p.warnings = append(p.warnings, fmt.Sprintf("add credentials to the monitors of operations that require authentication: %s", strings.Join(p.authenticated, ", ")))
+	}
+	for _, op := range opts.Operations {
+		if !selected[operationSelector(op)] {
+			return nil, p.warnings, fmt.Errorf("openapi document has no operation %s", op)
+		}
+	}
+	if len(requests) == 0 {
+		return nil, p.warnings, errors.New("openapi document has no operations to import")
+	}
+
+	return requests, p.warnings, nil
+}
+
+// operationSelector puts the method of a "method /path" selector in upper
+// case, and leaves operationIds as they are.
+func operationSelector(op string) string {
+	op = strings.TrimSpace(op)
+	if method, path, ok := strings.Cut(op, " "); ok && slices.Contains(openAPIMethods, strings.ToLower(method)) {
+		return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+	}
+	return op
+}
+
+func (p *openAPIParser) serverURL(override string) (string, error) {
+	if override != "" {
+		return strings.TrimSuffix(override, "/"), nil
+	}
+
+	servers, _ := p.doc["servers"].([]any)
+	if len(servers) == 0 {
+		return "", errors.New("openapi document has no servers, set a server url")
+	}
+	server, _ := servers[0].(map[string]any)
+	serverURL := stringValue(server["url"])
+
+	variables, _ := server["variables"].(map[string]any)
+	for name, v := range variables {
+		variable, _ := v.(map[string]any)
+		serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", stringValue(variable["default"]))
+	}
+	if !strings.Contains(serverURL, "://") {
+		return "", fmt.Errorf("server url %q is not absolute, set a server url", serverURL)
+	}
+
+	return strings.TrimSuffix(serverURL, "/"), nil
+}
+
+func (p *openAPIParser) operation(server string, title string, path string, method string, item map[string]any, op map[string]any) (Request, error) {
+	label := strings.ToUpper(method) + " " + path
+	req := Request{
+		Method: strings.ToUpper(method),
+		Name:   stringValue(op["summary"]),
+		Group:  title,
+	}
+
+	id := stringValue(op["operationId"])
+	if id != "" {
+		req.Key = title + " " + id
+	} else {
+		req.Key = title + " " + label
+	}
+	if req.Name == "" {
+		req.Name = id
+	}
+	if req.Name == "" {
+		req.Name = label
+	}
+	if tags, _ := op["tags"].([]any); len(tags) > 0 {
+		req.Group = strings.Trim(title+"/"+stringValue(tags[0]), "/")
+	}
+
+	params, err := p.parameters(item, op)
+	if err != nil {
+		return req, err
+	}
+
+	resolvedPath := path
+	query := url.Values{}
+	for _, param := range params {
+		name := stringValue(param["name"])
+		in := stringValue(param["in"])
+		required, _ := param["required"].(bool)
+		value, ok := p.parameterExample(param)
+
+		switch in {
+		case "path":
+			if !ok {
+				return req, fmt.Errorf("path parameter %s has no example", name)
+			}
+			resolvedPath = strings.ReplaceAll(resolvedPath, "{"+name+"}", url.PathEscape(value))
+		case "query":
+			if required && !ok {
+				p.warnings = append(p.warnings, fmt.Sprintf("left out required query parameter %s of %s without an example", name, label))
+			} else if required {
+				query.Add(name, value)
+			}
+		case "header":
+			if required && !ok {
+				p.warnings = append(p.warnings, fmt.Sprintf("left out required header %s of %s without an example", name, label))
+			} else if required && !skippedHeaders[http.CanonicalHeaderKey(name)] {
+				req.addHeader(name, value)
+			}
+		}
+	}
+
+	req.URL = server + resolvedPath
+	if len(query) > 0 {
+		req.URL += "?" + query.Encode()
+	}
+
+	if err := p.requestBody(&req, label, op["requestBody"]); err != nil {
+		return req, err
+	}
+	if err := p.responses(&req, label, op["responses"]); err != nil {
+		return req, err
+	}
+
+	security, ok := op["security"].([]any)
+	if !ok {
+		security, _ = p.doc["security"].([]any)
+	}
+	if slices.ContainsFunc(security, func(s any) bool {
+		requirement, _ := s.(map[string]any)
+		return len(requirement) > 0
+	}) {
+		p.authenticated = append(p.authenticated, label)
+	}
+
+	req.finish()
+	return req, nil
+}
+
+// parameters merges the parameters of a path item with those of one of its
+// operations, which override them by name and location.
+func (p *openAPIParser) parameters(item map[string]any, op map[string]any) ([]map[string]any, error) {
+	var params []map[string]any
+	index := make(map[string]int)
+
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		values, _ := list.([]any)
+		for _, v := range values {
+			param, err := p.resolve(v)
+			if err != nil {
+				return nil, err
+			}
+			key := stringValue(param["in"]) + " " + stringValue(param["name"])
+			if i, ok := index[key]; ok {
+				params[i] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+
+	return params, nil
+}
+
+func (p *openAPIParser) parameterExample(param map[string]any) (string, bool) {
+	example, ok := param["example"]
+	if !ok {
+		example, ok = p.firstExample(param["examples"])
+	}
+	if !ok {
+		// Made up values would rarely name an existing resource, so only
+		// those the schema declares are used.
+		schema, err := p.resolve(param["schema"])
+		if err != nil || schema == nil {
+			return "", false
+		}
+		example, ok = declaredExample(schema)
+	}
+	if !ok {
+		return "", false
+	}
+
+	switch v := example.(type) {
+	case map[string]any:
+		return "", false
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ","), true
+	case nil:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// firstExample returns the value of the first of a map of example objects.
+func (p *openAPIParser) firstExample(examples any) (any, bool) {
+	m, _ := examples.(map[string]any)
+	for _, name := range sortedKeys(m) {
+		example, err := p.resolve(m[name])
+		if err != nil {
+			continue
+		}
+		if value, ok := example["value"]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (p *openAPIParser) requestBody(req *Request, label string, raw any) error {
+	body, err := p.resolve(raw)
+	if err != nil || body == nil {
+		return err
+	}
+	content, _ := body["content"].(map[string]any)
+	mediaType := jsonMediaType(content)
+	if mediaType == "" {
+		if len(content) > 0 {
+			p.warnings = append(p.warnings, fmt.Sprintf("left out the body of %s, only json bodies are generated", label))
+		}
+		return nil
+	}
+
+	media, _ := content[mediaType].(map[string]any)
+	example, ok := media["example"]
+	if !ok {
+		example, ok = p.firstExample(media["examples"])
+	}
+	if !ok {
+		schema, _ := media["schema"].(map[string]any)
+		p.nodes = maxSchemaNodes
+		example = p.schemaExample(schema, nil)
+		if p.nodes < 0 {
+			return p.tooLarge(label, "request body schema")
+		}
+	}
+	if example == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(example)
+	if err != nil {
+		return fmt.Errorf("invalid body example: %v", err)
+	}
+	req.Body = string(data)
+	req.addHeader("Content-Type", mediaType)
+	return nil
+}
+
+// responses sets the 2xx codes of an operation as its status codes, and the
+// schema of their json body, when they all declare the same one.
+func (p *openAPIParser) responses(req *Request, label string, raw any) error {
+	responses, _ := raw.(map[string]any)
+
+	var schemas []string
+	for _, key := range sortedKeys(responses) {
+		code, err := strconv.Atoi(key)
+		if strings.EqualFold(key, "2XX") {
+			code, err = 200, nil
+		}
+		if err != nil || code < 200 || code > 299 {
+			continue
+		}
+		req.StatusCodes = append(req.StatusCodes, code)
+
+		response, err := p.resolve(responses[key])
+		if err != nil {
+			return err
+		}
+		content, _ := response["content"].(map[string]any)
+		media, _ := content[jsonMediaType(content)].(map[string]any)
+		if media["schema"] == nil {
+			schemas = append(schemas, "")
+			continue
+		}
+		p.nodes = maxSchemaNodes
+		schema, err := p.inline(media["schema"], nil)
+		if err != nil {
+			return err
+		}
+		if p.nodes < 0 {
+			return p.tooLarge(label, "response schema")
+		}
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return fmt.Errorf("invalid response schema: %v", err)
+		}
+		schemas = append(schemas, string(data))
+	}
+	slices.Sort(req.StatusCodes)
+	req.StatusCodes = slices.Compact(req.StatusCodes)
+
+	switch {
+	case len(schemas) == 0 || schemas[0] == "" || schemas[0] == "{}":
+	case slices.ContainsFunc(schemas, func(s string) bool { return s != schemas[0] }):
+		p.warnings = append(p.warnings, fmt.Sprintf("the responses of %s are not checked, its success responses differ", label))
+	default:
+		req.ResponseSchema = schemas[0]
+	}
+	return nil
+}
+
+func jsonMediaType(content map[string]any) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+	for _, mediaType := range sortedKeys(content) {
+		if strings.HasSuffix(mediaType, "+json") {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// resolve follows local references such as "#/components/schemas/Pet"
+// until it reaches an object.
+func (p *openAPIParser) resolve(v any) (map[string]any, error) {
+	m, _ := v.(map[string]any)
+	for depth := 0; m != nil; depth++ {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return m, nil
+		}
+		if depth > 32 {
+			return nil, fmt.Errorf("reference %s does not resolve", ref)
+		}
+		target, err := p.lookup(ref)
+		if err != nil {
+			return nil, err
+		}
+		m = target
+	}
+	return nil, nil
+}
+
+func (p *openAPIParser) lookup(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only references within the document are supported, got %s", ref)
+	}
+
+	var current any = p.doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("reference %s does not resolve", ref)
+		}
+		if current, ok = m[part]; !ok {
+			return nil, fmt.Errorf("reference %s does not resolve", ref)
+		}
+	}
+
+	m, ok := current.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("reference %s does not resolve to an object", ref)
+	}
+	return m, nil
+}
+
+// inline replaces the references of a schema with their targets. A schema
+// that refers to itself becomes the empty schema at the point it recurses,
+// so nested levels are not checked. Expansion stops once p.nodes runs out.
+func (p *openAPIParser) inline(v any, refs []string) (any, error) {
+	if p.nodes--; p.nodes < 0 {
+		return nil, nil
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if slices.Contains(refs, ref) {
+				return map[string]any{}, nil
+			}
+			target, err := p.lookup(ref)
+			if err != nil {
+				return nil, err
+			}
+			return p.inline(target, append(refs[:len(refs):len(refs)], ref))
+		}
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			inlined, err := p.inline(value, refs)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = inlined
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			inlined, err := p.inline(value, refs)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = inlined
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// schemaExample builds an example value from a schema, preferring the
+// examples and defaults it declares. Read-only properties are left out, as
+// the value is sent in requests.
+func (p *openAPIParser) schemaExample(schema map[string]any, refs []string) any {
+	if p.nodes--; p.nodes < 0 {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		if slices.Contains(refs, ref) {
+			return nil
+		}
+		target, err := p.lookup(ref)
+		if err != nil {
+			return nil
+		}
+		return p.schemaExample(target, append(refs[:len(refs):len(refs)], ref))
+	}
+
+	if example, ok := declaredExample(schema); ok {
+		return example
+	}
+
+	if allOf, _ := schema["allOf"].([]any); len(allOf) > 0 {
+		merged := make(map[string]any)
+		for _, sub := range allOf {
+			subSchema, _ := sub.(map[string]any)
+			if obj, ok := p.schemaExample(subSchema, refs).(map[string]any); ok {
+				for key, value := range obj {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives, _ := schema[key].([]any); len(alternatives) > 0 {
+			first, _ := alternatives[0].(map[string]any)
+			return p.schemaExample(first, refs)
+		}
+	}
+
+	schemaType := stringValue(schema["type"])
+	if types, ok := schema["type"].([]any); ok {
+		for _, t := range types {
+			if t != "null" {
+				schemaType = stringValue(t)
+				break
+			}
+		}
+	}
+	if schemaType == "" && schema["properties"] != nil {
+		schemaType = "object"
+	}
+
+	switch schemaType {
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		obj := make(map[string]any, len(properties))
+		for _, name := range sortedKeys(properties) {
+			property, _ := properties[name].(map[string]any)
+			if readOnly, _ := property["readOnly"].(bool); readOnly {
+				continue
+			}
+			if value := p.schemaExample(property, refs); value != nil {
+				obj[name] = value
+			}
+		}
+		return obj
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if item := p.schemaExample(items, refs); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "string":
+		switch stringValue(schema["format"]) {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	case "integer", "number":
+		if minimum, ok := schema["minimum"].(int); ok {
+			return minimum
+		}
+		if minimum, ok := schema["minimum"].(float64); ok {
+			return minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// declaredExample returns the example, default or first allowed value a
+// schema declares.
+func declaredExample(schema map[string]any) (any, bool) {
+	if example, ok := schema["example"]; ok {
+		return example, true
+	}
+	if examples, _ := schema["examples"].([]any); len(examples) > 0 {
+		return examples[0], true
+	}
+	if value, ok := schema["default"]; ok {
+		return value, true
+	}
+	if values, _ := schema["enum"].([]any); len(values) > 0 {
+		return values[0], true
+	}
+	return nil, false
+}
+
+// plainValue turns decoded YAML into the values JSON decoding gives, with
+// string keys everywhere. Unquoted status codes are YAML integers.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = plainValue(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = plainValue(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = plainValue(value)
+		}
+		return v
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(time.RFC3339)
+	}
+	return v
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,467 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const openAPIFixture = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://{region}.api.example.com/v1/
+    variables:
+      region:
+        default: eu
+  - url: https://staging.example.com/v1
+security:
+  - bearer: []
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      tags: [pets]
+      security: []
+      parameters:
+        - name: limit
+          in: query
+          required: true
+          schema: {type: integer, default: 20}
+        - name: cursor
+          in: query
+          schema: {type: string, example: abc}
+        - name: X-Request-Id
+          in: header
+          required: true
+          example: probe
+        - name: Host
+          in: header
+          required: true
+          example: api.example.com
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pets"}
+        default:
+          description: error
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/NewPet"}
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Pet"}
+  /pets/{petId}:
+    parameters:
+      - $ref: "#/components/parameters/PetId"
+    get:
+      summary: Get a pet
+      parameters:
+        - name: fields
+          in: query
+          required: true
+          examples:
+            b: {value: [name, tag]}
+            a: {value: [id]}
+      responses:
+        2XX:
+          description: ok
+          content:
+            application/problem+json:
+              schema: {$ref: "#/components/schemas/Pet"}
+        "204":
+          description: nothing
+    delete:
+      operationId: deletePet
+      responses:
+        "204": {description: deleted}
+  /owners/{ownerId}:
+    get:
+      operationId: getOwner
+      parameters:
+        - name: ownerId
+          in: path
+          required: true
+          schema: {type: string}
+      responses:
+        "200": {description: ok}
+  /reports:
+    get:
+      operationId: getReport
+      parameters:
+        - name: since
+          in: query
+          required: true
+          schema: {type: string, format: date}
+      responses:
+        "200":
+          content:
+            application/json:
+              schema: {type: object}
+        "202":
+          content:
+            application/json:
+              schema: {type: array}
+  /uploads:
+    put:
+      operationId: upload
+      requestBody:
+        content:
+          multipart/form-data:
+            schema: {type: object}
+      responses:
+        "200": {description: ok}
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      required: true
+      schema: {type: integer, example: 7}
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string}
+        tag: {type: string}
+    NewPet:
+      allOf:
+        - $ref: "#/components/schemas/Pet"
+        - type: object
+          properties:
+            born: {type: string, format: date}
+            weight: {type: number, minimum: 0.5}
+            kind: {type: string, enum: [dog, cat]}
+            owner: {type: string, format: email}
+            tags: {type: array, items: {type: string}}
+            vaccinated: {type: [boolean, "null"]}
+    Pets:
+      type: array
+      items: {$ref: "#/components/schemas/Pet"}
+`
+
+const petSchema = `{"properties":{"id":{"readOnly":true,"type":"integer"},"name":{"type":"string"},"tag":{"type":"string"}},"required":["id","name"],"type":"object"}`
+
+func TestParseOpenAPI(t *testing.T) {
+	got, warnings, err := ParseOpenAPI([]byte(openAPIFixture), Options{})
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+
+	want := []Request{
+		{
+			Key:            "Petstore listPets",
+			Name:           "List pets",
+			Group:          "Petstore/pets",
+			Method:         "GET",
+			URL:            "https://eu.api.example.com/v1/pets?limit=20",
+			Headers:        headers("X-Request-Id", "probe"),
+			StatusCodes:    []int{200},
+			ResponseSchema: `{"items":` + petSchema + `,"type":"array"}`,
+		},
+		{
+			Key:            "Petstore GET /pets/{petId}",
+			Name:           "Get a pet",
+			Group:          "Petstore",
+			Method:         "GET",
+			URL:            "https://eu.api.example.com/v1/pets/7?fields=id",
+			StatusCodes:    []int{200, 204},
+			ResponseSchema: "",
+		},
+		{
+			Key:         "Petstore getReport",
+			Name:        "getReport",
+			Group:       "Petstore",
+			Method:      "GET",
+			URL:         "https://eu.api.example.com/v1/reports",
+			StatusCodes: []int{200, 202},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOpenAPI() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, w := range []string{
+		"skipped GET /owners/{ownerId}: path parameter ownerId has no example",
+		"left out required query parameter since of GET /reports without an example",
+		"add credentials to the monitors of operations that require authentication: GET /pets/{petId}",
+	} {
+		if !hasWarning(warnings, w) {
+			t.Errorf("warnings = %q, want one containing %q", warnings, w)
+		}
+	}
+}
+
+func TestParseOpenAPISelection(t *testing.T) {
+	got, warnings, err := ParseOpenAPI([]byte(openAPIFixture), Options{
+		ServerURL:  "https://staging.example.com/v1/",
+		Operations: []string{"createPet", "delete /pets/{petId}", "getReport", "upload"},
+	})
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+
+	want := []Request{
+		{
+			Key:            "Petstore createPet",
+			Name:           "createPet",
+			Group:          "Petstore",
+			Method:         "POST",
+			URL:            "https://staging.example.com/v1/pets",
+			Headers:        headers("Content-Type", "application/json"),
+			Body:           `{"born":"2024-01-01","kind":"dog","name":"string","owner":"user@example.com","tag":"string","tags":["string"],"vaccinated":false,"weight":0.5}`,
+			StatusCodes:    []int{201},
+			ResponseSchema: petSchema,
+		},
+		{
+			Key:         "Petstore deletePet",
+			Name:        "deletePet",
+			Group:       "Petstore",
+			Method:      "DELETE",
+			URL:         "https://staging.example.com/v1/pets/7",
+			StatusCodes: []int{204},
+		},
+		{
+			Key:         "Petstore getReport",
+			Name:        "getReport",
+			Group:       "Petstore",
+			Method:      "GET",
+			URL:         "https://staging.example.com/v1/reports",
+			StatusCodes: []int{200, 202},
+		},
+		{
+			Key:         "Petstore upload",
+			Name:        "upload",
+			Group:       "Petstore",
+			Method:      "PUT",
+			URL:         "https://staging.example.com/v1/uploads",
+			StatusCodes: []int{200},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOpenAPI() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, w := range []string{
+		"the responses of GET /reports are not checked, its success responses differ",
+		"left out the body of PUT /uploads, only json bodies are generated",
+	} {
+		if !hasWarning(warnings, w) {
+			t.Errorf("warnings = %q, want one containing %q", warnings, w)
+		}
+	}
+}
+
+func TestParseOpenAPIJSON(t *testing.T) {
+	data := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Status"},
+  "servers": [{"url": "https://status.example.com"}],
+  "paths": {"/ping": {"get": {"responses": {"200": {"description": "ok"}}}}}
+}`
+	got, _, err := ParseOpenAPI([]byte(data), Options{})
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+	want := []Request{{Key: "Status GET /ping", Name: "GET /ping", Group: "Status", Method: "GET", URL: "https://status.example.com/ping", StatusCodes: []int{200}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOpenAPI() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseOpenAPIErrors(t *testing.T) {
+	const paths = `
+paths:
+  /ping:
+    get:
+      responses:
+        "200": {description: ok}
+`
+	tests := []struct {
+		name string
+		data string
+		opts Options
+		want string
+	}{
+		{name: "not yaml", data: "openapi: [", want: "invalid openapi document"},
+		{name: "not an object", data: "- openapi", want: "invalid openapi document: not an object"},
+		{name: "swagger 2", data: "swagger: '2.0'\ninfo: {title: Old}", want: "swagger 2 documents are not supported"},
+		{name: "no version", data: "info: {title: x}", want: "missing openapi 3 version"},
+		{name: "no servers", data: "openapi: 3.0.0" + paths, want: "openapi document has no servers"},
+		{name: "relative server", data: "openapi: 3.0.0\nservers: [{url: /v1}]" + paths, want: `server url "/v1" is not absolute`},
+		{name: "no get operations", data: "openapi: 3.0.0\nservers: [{url: 'https://a.example.com'}]\npaths:\n  /a:\n    post: {}", want: "openapi document has no operations to import"},
+		{name: "unknown operation", data: "openapi: 3.0.0\nservers: [{url: 'https://a.example.com'}]" + paths, opts: Options{Operations: []string{"deletePing"}}, want: "openapi document has no operation deletePing"},
+		{name: "external reference", data: "openapi: 3.0.0\nservers: [{url: 'https://a.example.com'}]\npaths:\n  /a: {$ref: 'other.yaml#/paths/a'}", want: "openapi document has no operations to import"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseOpenAPI([]byte(tt.data), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseOpenAPI() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// schemaDocument is a document with a GET and a POST /tree operation that
+// both use the Tree schema, next to the given components.
+func schemaDocument(components string) string {
+	return `{
+  "openapi": "3.0.3",
+  "info": {"title": "Trees"},
+  "servers": [{"url": "https://trees.example.com"}],
+  "paths": {"/tree": {
+    "get": {"operationId": "getTree", "responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Tree"}}}}}},
+    "post": {"operationId": "postTree",
+      "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Tree"}}}},
+      "responses": {"204": {"description": "saved"}}}
+  }},
+  "components": ` + components + `
+}`
+}
+
+func TestParseOpenAPISchemaCycles(t *testing.T) {
+	tests := []struct {
+		name       string
+		components string
+		wantSchema string
+		wantBody   string
+	}{
+		{
+			name: "schema referring to itself",
+			components: `{"schemas": {"Tree": {"type": "object", "properties": {
+  "name": {"type": "string"},
+  "children": {"type": "array", "items": {"$ref": "#/components/schemas/Tree"}}
+}}}}`,
+			wantSchema: `{"properties":{"children":{"items":{},"type":"array"},"name":{"type":"string"}},"type":"object"}`,
+			wantBody:   `{"children":[],"name":"string"}`,
+		},
+		{
+			name: "schemas referring to each other",
+			components: `{"schemas": {
+  "Tree": {"type": "object", "properties": {"leaf": {"$ref": "#/components/schemas/Leaf"}}},
+  "Leaf": {"type": "object", "properties": {"id": {"type": "integer"}, "tree": {"$ref": "#/components/schemas/Tree"}}}
+}}`,
+			wantSchema: `{"properties":{"leaf":{"properties":{"id":{"type":"integer"},"tree":{}},"type":"object"}},"type":"object"}`,
+			wantBody:   `{"leaf":{"id":0}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := ParseOpenAPI([]byte(schemaDocument(tt.components)), Options{Operations: []string{"getTree", "postTree"}})
+			if err != nil {
+				t.Fatalf("ParseOpenAPI() error = %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("ParseOpenAPI() = %+v, want both operations", got)
+			}
+			if got[0].ResponseSchema != tt.wantSchema {
+				t.Errorf("response schema = %s, want %s", got[0].ResponseSchema, tt.wantSchema)
+			}
+			if got[1].Body != tt.wantBody {
+				t.Errorf("body = %s, want %s", got[1].Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestParseOpenAPISchemaDepth(t *testing.T) {
+	// A chain of distinct schemas is inlined whole, however deep it is.
+	const depth = 200
+	var schemas, want []string
+	for i := range depth {
+		next := fmt.Sprintf(`{"$ref": "#/components/schemas/Level%d"}`, i+1)
+		if i == depth-1 {
+			next = `{"type": "string"}`
+		}
+		name := fmt.Sprintf("Level%d", i)
+		if i == 0 {
+			name = "Tree"
+		}
+		schemas = append(schemas, fmt.Sprintf(`"%s": {"type": "object", "properties": {"next": %s}}`, name, next))
+		want = append(want, `{"properties":{"next":`)
+	}
+	wantSchema := strings.Join(want, "") + `{"type":"string"}` + strings.Repeat(`},"type":"object"}`, depth)
+
+	got, _, err := ParseOpenAPI([]byte(schemaDocument(`{"schemas": {`+strings.Join(schemas, ",")+`}}`)), Options{})
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ResponseSchema != wantSchema {
+		t.Errorf("ParseOpenAPI() = %+v, want the chain inlined", got)
+	}
+}
+
+func TestParseOpenAPISchemaTooLarge(t *testing.T) {
+	// Each level refers to the next twice, so inlining doubles the schema at
+	// every level.
+	const depth = 40
+	var schemas []string
+	for i := range depth {
+		next := fmt.Sprintf(`{"$ref": "#/components/schemas/Level%d"}`, i+1)
+		if i == depth-1 {
+			next = `{"type": "string"}`
+		}
+		name := fmt.Sprintf("Level%d", i)
+		if i == 0 {
+			name = "Tree"
+		}
+		schemas = append(schemas, fmt.Sprintf(`"%s": {"type": "object", "properties": {"left": %s, "right": %s}}`, name, next, next))
+	}
+	doc := schemaDocument(`{"schemas": {` + strings.Join(schemas, ",") + `}}`)
+
+	for _, op := range []string{"getTree", "postTree"} {
+		t.Run(op, func(t *testing.T) {
+			_, _, err := ParseOpenAPI([]byte(doc), Options{Operations: []string{op}})
+			if !errors.Is(err, errSchemaTooLarge) {
+				t.Fatalf("ParseOpenAPI() error = %v, want %v", err, errSchemaTooLarge)
+			}
+		})
+	}
+}
+
+func TestParseOpenAPIReferenceChain(t *testing.T) {
+	var responses []string
+	for i := range 40 {
+		responses = append(responses, fmt.Sprintf(`"Ok%d": {"$ref": "#/components/responses/Ok%d"}`, i, i+1))
+	}
+	responses = append(responses, `"Ok40": {"description": "ok"}`)
+	doc := `{
+  "openapi": "3.0.3",
+  "info": {"title": "Chain"},
+  "servers": [{"url": "https://chain.example.com"}],
+  "paths": {
+    "/far": {"get": {"responses": {"200": {"$ref": "#/components/responses/Ok0"}}}},
+    "/near": {"get": {"responses": {"200": {"$ref": "#/components/responses/Ok39"}}}}
+  },
+  "components": {"responses": {` + strings.Join(responses, ",") + `}}
+}`
+
+	got, warnings, err := ParseOpenAPI([]byte(doc), Options{})
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+	if len(got) != 1 || got[0].URL != "https://chain.example.com/near" {
+		t.Errorf("ParseOpenAPI() = %+v, want only GET /near", got)
+	}
+	if !hasWarning(warnings, "skipped GET /far: reference #/components/responses/Ok33 does not resolve") {
+		t.Errorf("warnings = %q, want GET /far skipped", warnings)
+	}
+}
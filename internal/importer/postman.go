@@ -0,0 +1,320 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem `json:"item"`
+	Variable []postmanKV   `json:"variable"`
+	Auth     *postmanAuth  `json:"auth"`
+}
+
+// postmanItem is either a request or, when it has items of its own, a
+// folder.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request json.RawMessage `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	Header []postmanKV     `json:"header"`
+	URL    json.RawMessage `json:"url"`
+	Body   *struct {
+		Mode       string      `json:"mode"`
+		Raw        string      `json:"raw"`
+		URLEncoded []postmanKV `json:"urlencoded"`
+		Options    struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+	Auth *postmanAuth `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw      string          `json:"raw"`
+	Protocol string          `json:"protocol"`
+	Host     json.RawMessage `json:"host"`
+	Port     string          `json:"port"`
+	Path     json.RawMessage `json:"path"`
+	Query    []postmanKV     `json:"query"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Basic  []postmanKV `json:"basic"`
+	Bearer []postmanKV `json:"bearer"`
+	APIKey []postmanKV `json:"apikey"`
+}
+
+type postmanKV struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (kv postmanKV) value() string {
+	switch v := kv.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func postmanParam(params []postmanKV, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.value()
+		}
+	}
+	return ""
+}
+
+var postmanVariable = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+type postmanParser struct {
+	variables  map[string]string
+	unresolved map[string]bool
+	warnings   []string
+	requests   []Request
+}
+
+// ParsePostman reads every request of a Postman v2.1 collection. Folders
+// become groups below the collection's name, and collection variables are
+// filled in.
+func ParsePostman(data []byte) ([]Request, []string, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, nil, fmt.Errorf("invalid postman collection: %v", err)
+	}
+	if len(collection.Item) == 0 {
+		return nil, nil, errors.New("postman collection has no requests")
+	}
+
+	p := &postmanParser{
+		variables:  make(map[string]string, len(collection.Variable)),
+		unresolved: make(map[string]bool),
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.1") {
+		p.warnings = append(p.warnings, "collection is not in the v2.1 format, some fields may be missed")
+	}
+	for _, v := range collection.Variable {
+		if !v.Disabled {
+			p.variables[v.Key] = v.value()
+		}
+	}
+
+	p.items(collection.Item, collection.Info.Name, collection.Auth)
+
+	if len(p.unresolved) > 0 {
+		names := make([]string, 0, len(p.unresolved))
+		for name := range p.unresolved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		p.warnings = append(p.warnings, fmt.Sprintf("variables without a collection value were left as is: %s", strings.Join(names, ", ")))
+	}
+	if len(p.requests) == 0 {
+		return nil, p.warnings, errors.New("postman collection has no requests")
+	}
+
+	return p.requests, p.warnings, nil
+}
+
+func (p *postmanParser) items(items []postmanItem, group string, auth *postmanAuth) {
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+
+		if len(item.Request) == 0 {
+			sub := item.Name
+			if group != "" {
+				sub = group + "/" + item.Name
+			}
+			p.items(item.Item, sub, itemAuth)
+			continue
+		}
+
+		req, err := p.request(item, itemAuth)
+		if err != nil {
+			p.warnings = append(p.warnings, fmt.Sprintf("skipped %q: %v", item.Name, err))
+			continue
+		}
+		req.Group = group
+		p.requests = append(p.requests, req)
+	}
+}
+
+func (p *postmanParser) request(item postmanItem, auth *postmanAuth) (Request, error) {
+	req := Request{Name: item.Name}
+
+	// A request can be given as just its url.
+	var pr postmanRequest
+	var rawURL string
+	if err := json.Unmarshal(item.Request, &rawURL); err != nil {
+		if err := json.Unmarshal(item.Request, &pr); err != nil {
+			return req, fmt.Errorf("invalid request: %v", err)
+		}
+		if rawURL, err = postmanRequestURL(pr.URL); err != nil {
+			return req, err
+		}
+	}
+	if rawURL == "" {
+		return req, errors.New("request has no url")
+	}
+
+	req.Method = pr.Method
+	req.URL = p.resolve(rawURL)
+	for _, h := range pr.Header {
+		if h.Disabled || skippedHeaders[http.CanonicalHeaderKey(h.Key)] {
+			continue
+		}
+		req.addHeader(p.resolve(h.Key), p.resolve(h.value()))
+	}
+
+	if body := pr.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			req.Body = p.resolve(body.Raw)
+			if body.Options.Raw.Language == "json" && !req.hasHeader("Content-Type") {
+				req.addHeader("Content-Type", "application/json")
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, kv := range body.URLEncoded {
+				if !kv.Disabled {
+					form.Add(p.resolve(kv.Key), p.resolve(kv.value()))
+				}
+			}
+			req.Body = form.Encode()
+			if !req.hasHeader("Content-Type") {
+				req.addHeader("Content-Type", "application/x-www-form-urlencoded")
+			}
+		case "":
+		default:
+			p.warnings = append(p.warnings, fmt.Sprintf("ignored the %s body of %q, only raw and urlencoded bodies are supported", body.Mode, item.Name))
+		}
+	}
+
+	if pr.Auth != nil {
+		auth = pr.Auth
+	}
+	if auth != nil {
+		p.applyAuth(&req, item.Name, auth)
+	}
+
+	req.finish()
+	return req, nil
+}
+
+func (p *postmanParser) applyAuth(req *Request, name string, auth *postmanAuth) {
+	switch auth.Type {
+	case "", "noauth":
+	case "basic":
+		req.Username = p.resolve(postmanParam(auth.Basic, "username"))
+		req.Password = p.resolve(postmanParam(auth.Basic, "password"))
+	case "bearer":
+		req.addHeader("Authorization", "Bearer "+p.resolve(postmanParam(auth.Bearer, "token")))
+	case "apikey":
+		if in := postmanParam(auth.APIKey, "in"); in != "" && in != "header" {
+			p.warnings = append(p.warnings, fmt.Sprintf("ignored the api key of %q sent in the %s", name, in))
+			return
+		}
+		req.addHeader(p.resolve(postmanParam(auth.APIKey, "key")), p.resolve(postmanParam(auth.APIKey, "value")))
+	default:
+		p.warnings = append(p.warnings, fmt.Sprintf("ignored %s auth of %q", auth.Type, name))
+	}
+}
+
+// resolve fills in collection variables, leaving unknown ones in place.
+func (p *postmanParser) resolve(s string) string {
+	return postmanVariable.ReplaceAllStringFunc(s, func(match string) string {
+		name := postmanVariable.FindStringSubmatch(match)[1]
+		if value, ok := p.variables[name]; ok {
+			return value
+		}
+		p.unresolved[name] = true
+		return match
+	})
+}
+
+func postmanRequestURL(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var u postmanURL
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return "", fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Raw != "" {
+		return u.Raw, nil
+	}
+
+	host := strings.Join(stringOrList(u.Host), ".")
+	if host == "" {
+		return "", nil
+	}
+	if u.Port != "" {
+		host += ":" + u.Port
+	}
+	protocol := u.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+
+	built := protocol + "://" + host
+	if path := stringOrList(u.Path); len(path) > 0 {
+		built += "/" + strings.TrimPrefix(strings.Join(path, "/"), "/")
+	}
+
+	var query []string
+	for _, q := range u.Query {
+		if !q.Disabled {
+			query = append(query, q.Key+"="+q.value())
+		}
+	}
+	if len(query) > 0 {
+		built += "?" + strings.Join(query, "&")
+	}
+
+	return built, nil
+}
+
+func stringOrList(raw json.RawMessage) []string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil && s != "" {
+		return []string{s}
+	}
+	return nil
+}
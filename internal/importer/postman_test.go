@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const postmanFixture = `{
+  "info": {
+    "name": "Shop",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "variable": [
+    {"key": "baseUrl", "value": "https://api.example.com"},
+    {"key": "version", "value": 2},
+    {"key": "old", "value": "https://old.example.com", "disabled": true}
+  ],
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "item": [
+    {
+      "name": "Health",
+      "request": "{{baseUrl}}/health"
+    },
+    {
+      "name": "Orders",
+      "item": [
+        {
+          "name": "List orders",
+          "request": {
+            "method": "GET",
+            "header": [
+              {"key": "Accept", "value": "application/json"},
+              {"key": "X-Debug", "value": "1", "disabled": true},
+              {"key": "Host", "value": "api.example.com"}
+            ],
+            "url": {
+              "raw": "{{baseUrl}}/v{{version}}/orders?status=open",
+              "host": ["{{baseUrl}}"],
+              "path": ["v{{version}}", "orders"]
+            }
+          }
+        },
+        {
+          "name": "Admin",
+          "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "{{adminPassword}}"}]},
+          "item": [
+            {
+              "name": "Create order",
+              "request": {
+                "method": "POST",
+                "url": {
+                  "protocol": "https",
+                  "host": ["admin", "example", "com"],
+                  "port": "8443",
+                  "path": ["v{{version}}", "orders"],
+                  "query": [{"key": "dry", "value": "1"}, {"key": "debug", "value": "1", "disabled": true}]
+                },
+                "body": {"mode": "raw", "raw": "{\"qty\": {{version}}}", "options": {"raw": {"language": "json"}}}
+              }
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "Login",
+      "request": {
+        "method": "POST",
+        "auth": {"type": "noauth"},
+        "url": "{{baseUrl}}/login",
+        "body": {"mode": "urlencoded", "urlencoded": [
+          {"key": "user", "value": "probe"},
+          {"key": "pass", "value": "a&b"},
+          {"key": "remember", "value": "1", "disabled": true}
+        ]}
+      }
+    },
+    {
+      "name": "Upload",
+      "request": {
+        "method": "POST",
+        "auth": {"type": "apikey", "apikey": [{"key": "key", "value": "X-Api-Key"}, {"key": "value", "value": "k1"}, {"key": "in", "value": "query"}]},
+        "url": "{{baseUrl}}/upload",
+        "body": {"mode": "formdata", "formdata": [{"key": "file", "type": "file"}]}
+      }
+    },
+    {
+      "name": "Broken",
+      "request": {"method": "GET"}
+    }
+  ]
+}`
+
+func TestParsePostman(t *testing.T) {
+	got, warnings, err := ParsePostman([]byte(postmanFixture))
+	if err != nil {
+		t.Fatalf("ParsePostman() error = %v", err)
+	}
+
+	want := []Request{
+		{
+			Name:    "Health",
+			Group:   "Shop",
+			Method:  "GET",
+			URL:     "https://api.example.com/health",
+			Headers: headers("Authorization", "Bearer {{token}}"),
+		},
+		{
+			Name:    "List orders",
+			Group:   "Shop/Orders",
+			Method:  "GET",
+			URL:     "https://api.example.com/v2/orders?status=open",
+			Headers: headers("Accept", "application/json", "Authorization", "Bearer {{token}}"),
+		},
+		{
+			Name:     "Create order",
+			Group:    "Shop/Orders/Admin",
+			Method:   "POST",
+			URL:      "https://admin.example.com:8443/v2/orders?dry=1",
+			Headers:  headers("Content-Type", "application/json"),
+			Body:     `{"qty": 2}`,
+			Username: "admin",
+			Password: "{{adminPassword}}",
+		},
+		{
+			Name:    "Login",
+			Group:   "Shop",
+			Method:  "POST",
+			URL:     "https://api.example.com/login",
+			Headers: headers("Content-Type", "application/x-www-form-urlencoded"),
+			Body:    "pass=a%26b&user=probe",
+		},
+		{
+			Name:   "Upload",
+			Group:  "Shop",
+			Method: "POST",
+			URL:    "https://api.example.com/upload",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePostman() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, w := range []string{
+		`ignored the formdata body of "Upload"`,
+		`ignored the api key of "Upload" sent in the query`,
+		`skipped "Broken": request has no url`,
+		"variables without a collection value were left as is: adminPassword, token",
+	} {
+		if !hasWarning(warnings, w) {
+			t.Errorf("warnings = %q, want one containing %q", warnings, w)
+		}
+	}
+	if len(warnings) != 4 {
+		t.Errorf("warnings = %q, want 4", warnings)
+	}
+}
+
+func TestParsePostmanOlderSchema(t *testing.T) {
+	data := `{
+  "info": {"name": "Legacy", "schema": "https://schema.getpostman.com/json/collection/v2.0.0/collection.json"},
+  "item": [{"name": "Ping", "request": {"method": "HEAD", "url": "https://api.example.com/ping"}}]
+}`
+	got, warnings, err := ParsePostman([]byte(data))
+	if err != nil {
+		t.Fatalf("ParsePostman() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Method != "HEAD" || got[0].Group != "Legacy" {
+		t.Errorf("ParsePostman() = %+v, want the HEAD request in group Legacy", got)
+	}
+	if !hasWarning(warnings, "not in the v2.1 format") {
+		t.Errorf("warnings = %q, want the format warning", warnings)
+	}
+}
+
+func TestParsePostmanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not json", data: "collection", want: "invalid postman collection"},
+		{name: "wrong shape", data: `{"item": {"name": "x"}}`, want: "invalid postman collection"},
+		{name: "no items", data: `{"info": {"name": "Empty"}, "item": []}`, want: "postman collection has no requests"},
+		{name: "only empty folders", data: `{"item": [{"name": "Folder", "item": []}]}`, want: "postman collection has no requests"},
+		{name: "only requests without a url", data: `{"item": [{"name": "A", "request": {"method": "GET", "url": {"path": ["a"]}}}]}`, want: "postman collection has no requests"},
+		{name: "invalid request", data: `{"item": [{"name": "A", "request": 42}]}`, want: "postman collection has no requests"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParsePostman([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParsePostman() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
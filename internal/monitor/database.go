@@ -0,0 +1,421 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var readOnlyRedisCommands = map[string]bool{
+	"PING":     true,
+	"ECHO":     true,
+	"GET":      true,
+	"EXISTS":   true,
+	"STRLEN":   true,
+	"TTL":      true,
+	"PTTL":     true,
+	"TYPE":     true,
+	"DBSIZE":   true,
+	"LLEN":     true,
+	"SCARD":    true,
+	"ZCARD":    true,
+	"HLEN":     true,
+	"HGET":     true,
+	"INFO":     true,
+	"TIME":     true,
+	"LASTSAVE": true,
+	"ROLE":     true,
+}
+
+func GetDatabaseResult(m Monitor) (*Result, error) {
+	switch m.Type {
+	case MonitorTypeMySQL, MonitorTypePostgres:
+		return getSQLResult(m)
+	case MonitorTypeRedis:
+		return getRedisResult(m)
+	default:
+		return nil, fmt.Errorf("unsupported database monitor type %q", m.Type)
+	}
+}
+
+func getSQLResult(m Monitor) (*Result, error) {
+	connector, host, err := buildSQLConnector(m)
+	if err != nil {
+		return nil, fmt.Errorf("error building the %s dsn %v", m.Type, err)
+	}
+
+	query := "SELECT 1"
+	if m.RequestBody.Valid && strings.TrimSpace(m.RequestBody.String) != "" {
+		query = m.RequestBody.String
+	}
+
+	timeout := connectionTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := &Result{
+		MonitorID:  m.ID,
+		MonitorUrl: m.Url,
+		Status:     "UP",
+	}
+
+	start := time.Now()
+
+	fail := func(reason string) (*Result, error) {
+		result.Status = "DOWN"
+		result.Reason = reason
+		result.ResponseTime = time.Since(start)
+		return result, nil
+	}
+
+	ip, dnsTime, err := ResolveHost(ctx, host)
+	if err != nil {
+		return fail(fmt.Sprintf("dns lookup failed: %v", err))
+	}
+	result.ResolvedIp = ip
+	result.DNSResponseTime = dnsTime
+
+	pool := sql.OpenDB(connector)
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+
+	connectStart := time.Now()
+	conn, err := pool.Conn(ctx)
+	if err == nil {
+		err = conn.PingContext(ctx)
+	}
+	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return fail(reason)
+		}
+		if isTimeout(err) {
+			return fail("connection timed out")
+		}
+		return fail(fmt.Sprintf("connection failed: %v", err))
+	}
+	defer conn.Close()
+	result.ConnectionTime = time.Since(connectStart)
+
+	queryStart := time.Now()
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fail(fmt.Sprintf("error starting read-only transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	var value sql.NullString
+	if err := tx.QueryRowContext(ctx, query).Scan(&value); err != nil {
+		if isTimeout(err) {
+			return fail("query timed out")
+		}
+		return fail(fmt.Sprintf("query failed: %v", err))
+	}
+
+	result.RoundTripTime = time.Since(queryStart)
+
+	if reason := assertScalar(m, value.String); reason != "" {
+		return fail(reason)
+	}
+
+	result.ResponseTime = time.Since(start)
+
+	return result, nil
+}
+
+// buildSQLConnector returns a connector that dials through the egress
+// policy, since the drivers resolve and connect on their own.
+func buildSQLConnector(m Monitor) (driver.Connector, string, error) {
+	u, err := url.Parse(m.Url)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dial := Egress.DialContext(connectionTimeout(m))
+
+	switch m.Type {
+	case MonitorTypeMySQL:
+		host, port, err := SplitTarget(m.Url, "3306")
+		if err != nil {
+			return nil, "", err
+		}
+
+		cfg := mysql.NewConfig()
+		cfg.User = m.AuthUsername.String
+		cfg.Passwd = m.AuthPassword.String
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, port)
+		cfg.DialFunc = dial
+		cfg.DBName = strings.TrimPrefix(u.Path, "/")
+		cfg.Timeout = connectionTimeout(m)
+		cfg.ReadTimeout = connectionTimeout(m)
+		if m.TLSMode != "" && m.TLSMode != TLSModeNone {
+			cfg.TLSConfig = "true"
+		}
+
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return connector, host, nil
+
+	case MonitorTypePostgres:
+		host, port, err := SplitTarget(m.Url, "5432")
+		if err != nil {
+			return nil, "", err
+		}
+
+		q := u.Query()
+		if q.Get("sslmode") == "" {
+			if m.TLSMode != "" && m.TLSMode != TLSModeNone {
+				q.Set("sslmode", "require")
+			} else {
+				q.Set("sslmode", "disable")
+			}
+		}
+		q.Set("connect_timeout", strconv.Itoa(int(connectionTimeout(m).Seconds())))
+
+		dsn := url.URL{
+			Scheme:   "postgres",
+			Host:     net.JoinHostPort(host, port),
+			Path:     u.Path,
+			RawQuery: q.Encode(),
+		}
+		if m.AuthUsername.Valid && m.AuthUsername.String != "" {
+			dsn.User = url.UserPassword(m.AuthUsername.String, m.AuthPassword.String)
+		}
+
+		connector, err := pq.NewConnector(dsn.String())
+		if err != nil {
+			return nil, "", err
+		}
+		connector.Dialer(pqDialer(dial))
+
+		return connector, host, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported sql monitor type %q", m.Type)
+}
+
+// pqDialer adapts a dial function to the dialer interface lib/pq expects.
+type pqDialer func(ctx context.Context, network string, address string) (net.Conn, error)
+
+func (d pqDialer) Dial(network string, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+func (d pqDialer) DialTimeout(network string, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d(ctx, network, address)
+}
+
+func (d pqDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	return d(ctx, network, address)
+}
+
+func getRedisResult(m Monitor) (*Result, error) {
+	command := []string{"PING"}
+	if m.RequestBody.Valid && strings.TrimSpace(m.RequestBody.String) != "" {
+		command = strings.Fields(m.RequestBody.String)
+	}
+
+	if !readOnlyRedisCommands[strings.ToUpper(command[0])] {
+		return nil, fmt.Errorf("redis command %q is not an allowed read-only command", command[0])
+	}
+
+	host, port, err := SplitTarget(m.Url, "6379")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the redis target %v", err)
+	}
+
+	timeout := connectionTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := &Result{
+		MonitorID:  m.ID,
+		MonitorUrl: m.Url,
+		Status:     "UP",
+	}
+
+	start := time.Now()
+
+	fail := func(reason string) (*Result, error) {
+		result.Status = "DOWN"
+		result.Reason = reason
+		result.ResponseTime = time.Since(start)
+		return result, nil
+	}
+
+	if err := Egress.CheckHost(host, port); err != nil {
+		return fail(err.Error())
+	}
+
+	ip, dnsTime, err := ResolveHost(ctx, host)
+	if err != nil {
+		return fail(fmt.Sprintf("dns lookup failed: %v", err))
+	}
+	result.ResolvedIp = ip
+	result.DNSResponseTime = dnsTime
+
+	dialer := Egress.Dialer(timeout)
+
+	connectStart := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return fail(reason)
+		}
+		if isTimeout(err) {
+			return fail("connection timed out")
+		}
+		return fail(fmt.Sprintf("connection failed: %v", err))
+	}
+	defer func() { conn.Close() }()
+	result.ConnectionTime = time.Since(connectStart)
+
+	conn.SetDeadline(start.Add(timeout))
+
+	if m.TLSMode != "" && m.TLSMode != TLSModeNone {
+		tlsStart := time.Now()
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(fmt.Sprintf("tls handshake failed: %v", err))
+		}
+		conn = tlsConn
+		result.TLSHandshakeTime = time.Since(tlsStart)
+	}
+
+	br := bufio.NewReader(conn)
+
+	if m.AuthPassword.Valid && m.AuthPassword.String != "" {
+		auth := []string{"AUTH", m.AuthPassword.String}
+		if m.AuthUsername.Valid && m.AuthUsername.String != "" {
+			auth = []string{"AUTH", m.AuthUsername.String, m.AuthPassword.String}
+		}
+		if _, err := redisDo(conn, br, auth); err != nil {
+			return fail(fmt.Sprintf("authentication failed: %v", err))
+		}
+	}
+
+	queryStart := time.Now()
+	value, err := redisDo(conn, br, command)
+	if err != nil {
+		if isTimeout(err) {
+			return fail("command timed out")
+		}
+		return fail(fmt.Sprintf("command failed: %v", err))
+	}
+	result.RoundTripTime = time.Since(queryStart)
+
+	redisDo(conn, br, []string{"QUIT"})
+
+	if reason := assertScalar(m, value); reason != "" {
+		return fail(reason)
+	}
+
+	result.ResponseTime = time.Since(start)
+
+	return result, nil
+}
+
+func redisDo(w io.Writer, r *bufio.Reader, args []string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return "", err
+	}
+
+	return readRedisReply(r)
+}
+
+func readRedisReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	if line == "" {
+		return "", fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("%s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid array length %q", line[1:])
+		}
+		values := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := readRedisReply(r)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			return "", nil
+		}
+		return values[0], nil
+	default:
+		return "", fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
+
+func assertScalar(m Monitor, value string) string {
+	if m.ExpectedResult.Valid && m.ExpectedResult.String != "" {
+		ok, err := CompareScalar(value, m.ExpectedResult.String)
+		if err != nil {
+			return err.Error()
+		}
+		if !ok {
+			return fmt.Sprintf("result %q did not satisfy assertion %q", value, m.ExpectedResult.String)
+		}
+	}
+
+	matched, err := MatchResponsePattern(m.ResponsePattern.String, []byte(value))
+	if err != nil {
+		return err.Error()
+	}
+	if !matched {
+		return fmt.Sprintf("result %q did not match expected pattern", value)
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func SplitTarget(rawURL string, defaultPort string) (string, string, error) {
+	hostport := rawURL
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", "", fmt.Errorf("error parsing target url: %w", err)
+		}
+		hostport = u.Host
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+		port = defaultPort
+	}
+
+	if host == "" {
+		return "", "", fmt.Errorf("target %q has no host", rawURL)
+	}
+	if port == "" {
+		return "", "", fmt.Errorf("target %q has no port", rawURL)
+	}
+
+	return host, port, nil
+}
+
+func ResolveHost(ctx context.Context, host string) (string, time.Duration, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), 0, nil
+	}
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	elapsed := time.Since(start)
+	if err != nil {
+		return "", elapsed, err
+	}
+
+	for _, addr := range addrs {
+		if ipv4 := addr.IP.To4(); ipv4 != nil {
+			return ipv4.String(), elapsed, nil
+		}
+	}
+
+	if len(addrs) == 0 {
+		return "", elapsed, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return addrs[0].IP.String(), elapsed, nil
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout() || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,394 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultDeniedCIDRs keeps checks away from loopback, link-local (cloud
+// metadata), private, shared and multicast ranges unless explicitly allowed.
+// IPv4-mapped and well-known NAT64 addresses are matched as the IPv4 address
+// they embed, the local-use NAT64 range is denied as a whole.
+var DefaultDeniedCIDRs = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b:1::/48",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+type EgressConfig struct {
+	AllowCIDRs []string
+	DenyCIDRs  []string
+	AllowHosts []string
+	DenyHosts  []string
+	AllowPorts []string
+	DenyPorts  []string
+}
+
+// EgressPolicy decides which hosts, addresses and ports checks may connect
+// to. Allowed CIDRs take precedence over denied ones; when AllowHosts or
+// AllowPorts are set, anything not listed is denied.
+type EgressPolicy struct {
+	allowCIDRs []netip.Prefix
+	denyCIDRs  []netip.Prefix
+	allowHosts []string
+	denyHosts  []string
+	allowPorts map[int]bool
+	denyPorts  map[int]bool
+}
+
+// Egress is the policy every check dials through and the api validates
+// targets against. It is replaced at startup from the environment.
+var Egress = mustEgressPolicy(EgressConfig{DenyCIDRs: DefaultDeniedCIDRs})
+
+// EgressError is returned when the policy blocks a target.
+type EgressError struct {
+	Target string
+	Reason string
+}
+
+func (e *EgressError) Error() string {
+	return fmt.Sprintf("blocked by egress policy: %s %s", e.Target, e.Reason)
+}
+
+func NewEgressPolicy(cfg EgressConfig) (*EgressPolicy, error) {
+	p := &EgressPolicy{
+		allowPorts: make(map[int]bool),
+		denyPorts:  make(map[int]bool),
+	}
+
+	var err error
+	if p.allowCIDRs, err = parsePrefixes(cfg.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if p.denyCIDRs, err = parsePrefixes(cfg.DenyCIDRs); err != nil {
+		return nil, err
+	}
+	if err := parsePorts(cfg.AllowPorts, p.allowPorts); err != nil {
+		return nil, err
+	}
+	if err := parsePorts(cfg.DenyPorts, p.denyPorts); err != nil {
+		return nil, err
+	}
+	p.allowHosts = normalizeHosts(cfg.AllowHosts)
+	p.denyHosts = normalizeHosts(cfg.DenyHosts)
+
+	return p, nil
+}
+
+func mustEgressPolicy(cfg EgressConfig) *EgressPolicy {
+	p, err := NewEgressPolicy(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// EgressPolicyFromEnv builds the policy from the PROBE_EGRESS_* variables.
+// PROBE_EGRESS_DENY_CIDRS replaces DefaultDeniedCIDRs when set.
+func EgressPolicyFromEnv() (*EgressPolicy, error) {
+	cfg := EgressConfig{
+		AllowCIDRs: envList("PROBE_EGRESS_ALLOW_CIDRS"),
+		DenyCIDRs:  DefaultDeniedCIDRs,
+		AllowHosts: envList("PROBE_EGRESS_ALLOW_HOSTS"),
+		DenyHosts:  envList("PROBE_EGRESS_DENY_HOSTS"),
+		AllowPorts: envList("PROBE_EGRESS_ALLOW_PORTS"),
+		DenyPorts:  envList("PROBE_EGRESS_DENY_PORTS"),
+	}
+	if v, ok := os.LookupEnv("PROBE_EGRESS_DENY_CIDRS"); ok {
+		cfg.DenyCIDRs = splitList(v)
+	}
+
+	p, err := NewEgressPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid egress policy: %w", err)
+	}
+	return p, nil
+}
+
+func envList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q is not a cidr or ip address", cidr)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, targetPrefix(prefix.Masked()))
+	}
+	return prefixes, nil
+}
+
+// nat64Prefix is the well-known NAT64 prefix, whose addresses reach the IPv4
+// address in their last 32 bits.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// targetAddr returns the address a connection to addr ends up at, so that
+// IPv4-mapped and NAT64 forms of an address match the rules for it.
+func targetAddr(addr netip.Addr) netip.Addr {
+	addr = addr.Unmap().WithZone("")
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		return netip.AddrFrom4([4]byte(b[12:]))
+	}
+	return addr
+}
+
+// targetPrefix converts mapped and NAT64 prefixes to the IPv4 range they
+// embed, since addresses are compared after targetAddr.
+func targetPrefix(prefix netip.Prefix) netip.Prefix {
+	if prefix.Bits() < 96 {
+		return prefix
+	}
+	if prefix.Addr().Is4In6() || nat64Prefix.Contains(prefix.Addr()) {
+		return netip.PrefixFrom(targetAddr(prefix.Addr()), prefix.Bits()-96)
+	}
+	return prefix
+}
+
+func parsePorts(ports []string, into map[int]bool) error {
+	for _, raw := range ports {
+		port, err := strconv.Atoi(raw)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("%q is not a port between 1 and 65535", raw)
+		}
+		into[port] = true
+	}
+	return nil
+}
+
+func normalizeHosts(hosts []string) []string {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		normalized = append(normalized, normalizeHost(host))
+	}
+	return normalized
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+}
+
+// matchHost reports whether host equals a pattern or, for "*.example.com"
+// patterns, is a subdomain of it.
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHost applies the hostname and port rules, and the address rules when
+// host is an ip literal.
+func (p *EgressPolicy) CheckHost(host string, port string) error {
+	host = normalizeHost(host)
+	target := net.JoinHostPort(host, port)
+
+	if err := p.checkPort(target, port); err != nil {
+		return err
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(target, addr, false)
+	}
+
+	if matchHost(host, p.denyHosts) {
+		return &EgressError{Target: target, Reason: "matches a denied host"}
+	}
+	if len(p.allowHosts) > 0 && !matchHost(host, p.allowHosts) {
+		return &EgressError{Target: target, Reason: "is not an allowed host"}
+	}
+	return nil
+}
+
+func (p *EgressPolicy) checkPort(target string, port string) error {
+	if port == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return &EgressError{Target: target, Reason: "has an invalid port"}
+	}
+	if p.denyPorts[n] {
+		return &EgressError{Target: target, Reason: "uses a denied port"}
+	}
+	if len(p.allowPorts) > 0 && !p.allowPorts[n] {
+		return &EgressError{Target: target, Reason: "uses a port that is not allowed"}
+	}
+	return nil
+}
+
+// checkAddr applies the cidr rules to an address target connects to.
+func (p *EgressPolicy) checkAddr(target string, addr netip.Addr, resolved bool) error {
+	addr = targetAddr(addr)
+
+	for _, prefix := range p.allowCIDRs {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, prefix := range p.denyCIDRs {
+		if prefix.Contains(addr) {
+			if resolved {
+				return &EgressError{Target: target, Reason: fmt.Sprintf("resolves to %s in denied range %s", addr, prefix)}
+			}
+			return &EgressError{Target: target, Reason: fmt.Sprintf("is in denied range %s", prefix)}
+		}
+	}
+	return nil
+}
+
+// control runs after DNS resolution and before the socket connects, so a
+// hostname that passed CheckHost can not be rebound to a denied address.
+func (p *EgressPolicy) control(network string, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return &EgressError{Target: address, Reason: "is not a valid address"}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return &EgressError{Target: address, Reason: "is not a valid address"}
+	}
+	if err := p.checkPort(address, port); err != nil {
+		return err
+	}
+	return p.checkAddr(address, addr, false)
+}
+
+// Dialer returns a dialer that refuses connections the policy blocks.
+func (p *EgressPolicy) Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, Control: p.control}
+}
+
+// DialContext checks the hostname before dialing, for clients such as
+// net/http that resolve inside the dialer.
+func (p *EgressPolicy) DialContext(timeout time.Duration) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := p.Dialer(timeout)
+
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.CheckHost(host, port); err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// CheckTarget validates a monitor url when it is created or changed. Names
+// that resolve are checked against the cidr rules too, while names that do
+// not resolve yet are left to the dial time check.
+func (p *EgressPolicy) CheckTarget(ctx context.Context, monitorType string, tlsMode string, rawURL string) error {
+	if monitorType == MonitorTypeHeartbeat {
+		return nil
+	}
+
+	host, port, err := SplitTarget(rawURL, defaultTargetPort(monitorType, tlsMode, rawURL))
+	if err != nil {
+		return err
+	}
+	if err := p.CheckHost(host, port); err != nil {
+		return err
+	}
+	if _, err := netip.ParseAddr(normalizeHost(host)); err == nil {
+		return nil
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(lookupCtx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := p.checkAddr(net.JoinHostPort(host, port), addr, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func defaultTargetPort(monitorType string, tlsMode string, rawURL string) string {
+	switch monitorType {
+	case MonitorTypeSMTP, MonitorTypeIMAP, MonitorTypePOP3:
+		if tlsMode == TLSModeImplicit {
+			return defaultMailPorts[monitorType].implicit
+		}
+		return defaultMailPorts[monitorType].plain
+	case MonitorTypeMySQL:
+		return "3306"
+	case MonitorTypePostgres:
+		return "5432"
+	case MonitorTypeRedis:
+		return "6379"
+	case MonitorTypeUDP:
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "80"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https", "wss":
+		return "443"
+	}
+	return "80"
+}
+
+// EgressReason returns the DOWN reason for a check the policy blocked.
+func EgressReason(err error) (string, bool) {
+	var blocked *EgressError
+	if errors.As(err, &blocked) {
+		return blocked.Error(), true
+	}
+	return "", false
+}
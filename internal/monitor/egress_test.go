@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func isBlocked(err error) bool {
+	var blocked *EgressError
+	return errors.As(err, &blocked)
+}
+
+func TestEgressCheckTarget(t *testing.T) {
+	defaults := mustEgressPolicy(EgressConfig{DenyCIDRs: DefaultDeniedCIDRs})
+	allowInternal := mustEgressPolicy(EgressConfig{
+		AllowCIDRs: []string{"10.1.0.0/16", "::ffff:192.168.5.5"},
+		DenyCIDRs:  DefaultDeniedCIDRs,
+	})
+	ports := mustEgressPolicy(EgressConfig{AllowPorts: []string{"443", "5432"}, DenyPorts: []string{"5432"}})
+	hosts := mustEgressPolicy(EgressConfig{
+		AllowHosts: []string{"api.example.com", "*.internal.example.com"},
+		DenyHosts:  []string{"admin.internal.example.com"},
+	})
+
+	tests := []struct {
+		name        string
+		policy      *EgressPolicy
+		monitorType string
+		tlsMode     string
+		url         string
+		blocked     bool
+	}{
+		{name: "public address", policy: defaults, url: "https://93.184.216.34/"},
+		{name: "cloud metadata", policy: defaults, url: "http://169.254.169.254/latest/meta-data", blocked: true},
+		{name: "loopback", policy: defaults, url: "http://127.0.0.1:8080/", blocked: true},
+		{name: "loopback outside 127.0.0.1", policy: defaults, url: "http://127.8.9.10/", blocked: true},
+		{name: "ipv6 loopback", policy: defaults, url: "http://[::1]:8080/", blocked: true},
+		{name: "unspecified", policy: defaults, url: "http://0.0.0.0/", blocked: true},
+		{name: "10/8", policy: defaults, url: "http://10.20.30.40/", blocked: true},
+		{name: "172.16/12", policy: defaults, url: "http://172.31.255.1/", blocked: true},
+		{name: "just outside 172.16/12", policy: defaults, url: "http://172.32.0.1/"},
+		{name: "192.168/16", policy: defaults, url: "http://192.168.1.1/", blocked: true},
+		{name: "shared address space", policy: defaults, url: "http://100.64.0.1/", blocked: true},
+		{name: "unique local ipv6", policy: defaults, url: "http://[fd00::1]/", blocked: true},
+		{name: "link-local ipv6 with zone", policy: defaults, url: "http://[fe80::1%25eth0]/", blocked: true},
+		{name: "ipv4-mapped metadata", policy: defaults, url: "http://[::ffff:169.254.169.254]/", blocked: true},
+		{name: "ipv4-mapped loopback in hex", policy: defaults, url: "http://[::ffff:7f00:1]/", blocked: true},
+		{name: "nat64 metadata", policy: defaults, url: "http://[64:ff9b::a9fe:a9fe]/", blocked: true},
+		{name: "nat64 public address", policy: defaults, url: "http://[64:ff9b::5db8:d822]/"},
+		{name: "local-use nat64", policy: defaults, url: "http://[64:ff9b:1::a9fe:a9fe]/", blocked: true},
+		{name: "database on a private address", policy: defaults, monitorType: MonitorTypePostgres, url: "10.0.0.5", blocked: true},
+		{name: "udp on loopback", policy: defaults, monitorType: MonitorTypeUDP, url: "127.0.0.1:53", blocked: true},
+		{name: "heartbeat is never checked", policy: defaults, monitorType: MonitorTypeHeartbeat, url: "/heartbeat/abc"},
+		{name: "allow overrides deny", policy: allowInternal, url: "http://10.1.2.3/"},
+		{name: "allow is exact", policy: allowInternal, url: "http://10.2.0.1/", blocked: true},
+		{name: "mapped allow matches the ipv4 address", policy: allowInternal, url: "http://192.168.5.5/"},
+		{name: "allowed port", policy: ports, url: "https://93.184.216.34/"},
+		{name: "port that is not allowed", policy: ports, url: "http://93.184.216.34/", blocked: true},
+		{name: "explicit port that is not allowed", policy: ports, url: "https://93.184.216.34:8443/", blocked: true},
+		{name: "deny wins over allow for ports", policy: ports, monitorType: MonitorTypePostgres, url: "93.184.216.34", blocked: true},
+		{name: "default mail port", policy: ports, monitorType: MonitorTypeSMTP, tlsMode: TLSModeImplicit, url: "93.184.216.34", blocked: true},
+		{name: "allowed host", policy: hosts, url: "https://api.example.com/health"},
+		{name: "allowed host in another case", policy: hosts, url: "https://API.Example.com./health"},
+		{name: "allowed subdomain", policy: hosts, url: "https://billing.internal.example.com/"},
+		{name: "wildcard does not match the bare domain", policy: hosts, url: "https://internal.example.com/", blocked: true},
+		{name: "denied host", policy: hosts, url: "https://admin.internal.example.com/", blocked: true},
+		{name: "host that is not allowed", policy: hosts, url: "https://evil.example.net/", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitorType := tt.monitorType
+			if monitorType == "" {
+				monitorType = MonitorTypeHTTP
+			}
+
+			err := tt.policy.CheckTarget(context.Background(), monitorType, tt.tlsMode, tt.url)
+			if tt.blocked && !isBlocked(err) {
+				t.Fatalf("CheckTarget(%q) error = %v, want it blocked", tt.url, err)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("CheckTarget(%q) error = %v, want it allowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestEgressCheckTargetResolvesNames(t *testing.T) {
+	if _, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", "localhost"); err != nil {
+		t.Skipf("localhost does not resolve: %v", err)
+	}
+
+	policy := mustEgressPolicy(EgressConfig{DenyCIDRs: DefaultDeniedCIDRs})
+	err := policy.CheckTarget(context.Background(), MonitorTypeHTTP, "", "http://localhost:8080/")
+	if !isBlocked(err) || !strings.Contains(err.Error(), "resolves to") {
+		t.Fatalf("CheckTarget(localhost) error = %v, want it blocked after resolving", err)
+	}
+}
+
+func TestEgressDialer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	denied := mustEgressPolicy(EgressConfig{DenyCIDRs: DefaultDeniedCIDRs})
+	if _, err := denied.Dialer(time.Second).Dial("tcp", ln.Addr().String()); !isBlocked(err) {
+		t.Errorf("Dial(%s) error = %v, want it blocked", ln.Addr(), err)
+	}
+	if _, err := denied.DialContext(time.Second)(context.Background(), "tcp", ln.Addr().String()); !isBlocked(err) {
+		t.Errorf("DialContext(%s) error = %v, want it blocked", ln.Addr(), err)
+	}
+
+	allowed := mustEgressPolicy(EgressConfig{AllowCIDRs: []string{"127.0.0.1"}, DenyCIDRs: DefaultDeniedCIDRs})
+	conn, err := allowed.Dialer(time.Second).Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial(%s) error = %v, want it allowed", ln.Addr(), err)
+	}
+	conn.Close()
+}
+
+func TestEgressPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		url     string
+		blocked bool
+		wantErr string
+	}{
+		{name: "defaults", url: "http://169.254.169.254/", blocked: true},
+		{name: "allow cidr", env: map[string]string{"PROBE_EGRESS_ALLOW_CIDRS": " 10.0.0.0/24 , 169.254.169.254"}, url: "http://169.254.169.254/"},
+		{name: "empty deny list replaces the defaults", env: map[string]string{"PROBE_EGRESS_DENY_CIDRS": ""}, url: "http://127.0.0.1/"},
+		{name: "deny list replaces the defaults", env: map[string]string{"PROBE_EGRESS_DENY_CIDRS": "203.0.113.0/24"}, url: "http://203.0.113.9/", blocked: true},
+		{name: "deny host", env: map[string]string{"PROBE_EGRESS_DENY_HOSTS": "*.corp.example.com"}, url: "http://vpn.corp.example.com/", blocked: true},
+		{name: "deny port", env: map[string]string{"PROBE_EGRESS_DENY_PORTS": "25,8080"}, url: "http://93.184.216.34:8080/", blocked: true},
+		{name: "invalid cidr", env: map[string]string{"PROBE_EGRESS_ALLOW_CIDRS": "10.0.0.0/33"}, wantErr: `"10.0.0.0/33" is not a cidr or ip address`},
+		{name: "invalid deny cidr", env: map[string]string{"PROBE_EGRESS_DENY_CIDRS": "localhost"}, wantErr: `"localhost" is not a cidr or ip address`},
+		{name: "port zero", env: map[string]string{"PROBE_EGRESS_ALLOW_PORTS": "0"}, wantErr: `"0" is not a port`},
+		{name: "port name", env: map[string]string{"PROBE_EGRESS_DENY_PORTS": "https"}, wantErr: `"https" is not a port`},
+		{name: "port out of range", env: map[string]string{"PROBE_EGRESS_ALLOW_PORTS": "443,65536"}, wantErr: `"65536" is not a port`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"PROBE_EGRESS_ALLOW_CIDRS", "PROBE_EGRESS_DENY_CIDRS", "PROBE_EGRESS_ALLOW_HOSTS", "PROBE_EGRESS_DENY_HOSTS", "PROBE_EGRESS_ALLOW_PORTS", "PROBE_EGRESS_DENY_PORTS"} {
+				t.Setenv(key, "")
+			}
+			// An unset deny list keeps the defaults, so only set it when
+			// the case does.
+			if _, ok := tt.env["PROBE_EGRESS_DENY_CIDRS"]; !ok {
+				unsetEnv(t, "PROBE_EGRESS_DENY_CIDRS")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			policy, err := EgressPolicyFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("EgressPolicyFromEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EgressPolicyFromEnv() error = %v", err)
+			}
+
+			err = policy.CheckTarget(context.Background(), MonitorTypeHTTP, "", tt.url)
+			if tt.blocked != isBlocked(err) || (!tt.blocked && err != nil) {
+				t.Fatalf("CheckTarget(%q) error = %v, want blocked %v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+}
+
+// unsetEnv removes key for the rest of the test, t.Setenv can only set it.
+func unsetEnv(t *testing.T, key string) {
+	t.Helper()
+
+	t.Setenv(key, "")
+	if err := os.Unsetenv(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEgressReason(t *testing.T) {
+	policy := mustEgressPolicy(EgressConfig{DenyCIDRs: DefaultDeniedCIDRs})
+	err := policy.CheckHost("169.254.169.254", "80")
+
+	reason, ok := EgressReason(fmt.Errorf("dial: %w", err))
+	if !ok || !strings.Contains(reason, "169.254.0.0/16") {
+		t.Errorf("EgressReason() = %q, %v, want the denied range", reason, ok)
+	}
+	if _, ok := EgressReason(errors.New("connection refused")); ok {
+		t.Errorf("EgressReason() reported an unrelated error as blocked")
+	}
+}
@@ -0,0 +1,222 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/dhruvthak3r/Probe/config"
+	resultq "github.com/dhruvthak3r/Probe/internal/mq"
+)
+
+const (
+	HeartbeatSuccess = "success"
+	HeartbeatStart   = "start"
+	HeartbeatFail    = "fail"
+)
+
+var ErrHeartbeatNotFound = errors.New("heartbeat monitor not found")
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func NewHeartbeatToken() (string, error) {
+	return randomHex(16)
+}
+
+func NewCycleID() (string, error) {
+	return randomHex(16)
+}
+
+func HeartbeatPath(token string) string {
+	return "/heartbeat/" + token
+}
+
+func RecordHeartbeat(ctx context.Context, db *db.DB, token string, event string) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		monitorID   int
+		url         string
+		isActive    bool
+		lastStartAt sql.NullTime
+		now         time.Time
+	)
+
+	query := `SELECT monitor_id, url, is_active, last_start_at, NOW(3)
+	          FROM monitor
+	          WHERE heartbeat_token = ? AND monitor_type = 'heartbeat'
+	          FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, token).Scan(&monitorID, &url, &isActive, &lastStartAt, &now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrHeartbeatNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up heartbeat monitor: %v", err)
+	}
+
+	if !isActive {
+		return tx.Commit()
+	}
+
+	if event == HeartbeatStart {
+		if _, err := tx.ExecContext(ctx, `UPDATE monitor SET last_start_at = NOW(3) WHERE monitor_id = ?`, monitorID); err != nil {
+			return fmt.Errorf("error recording heartbeat start: %v", err)
+		}
+		return tx.Commit()
+	}
+
+	state := "up"
+	cycleID, err := NewCycleID()
+	if err != nil {
+		return fmt.Errorf("error generating cycle id: %v", err)
+	}
+
+	res := &resultq.ResultMessage{
+		MonitorID:  monitorID,
+		MonitorUrl: url,
+		Status:     "UP",
+		CycleID:    cycleID,
+		CycleSize:  1,
+	}
+	if event == HeartbeatFail {
+		state = "down"
+		res.Status = "DOWN"
+		res.Reason = "job reported failure"
+	}
+
+	update := `
+	    UPDATE monitor
+	    SET last_ping_at = NOW(3),
+	    last_start_at = NULL,
+	    heartbeat_state = ?,
+	    next_run_at = DATE_ADD(NOW(), INTERVAL frequency_seconds + grace_seconds SECOND)
+	    WHERE monitor_id = ?`
+
+	if _, err := tx.ExecContext(ctx, update, state, monitorID); err != nil {
+		return fmt.Errorf("error recording heartbeat: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing heartbeat: %v", err)
+	}
+
+	if lastStartAt.Valid {
+		res.ResponseTime = now.Sub(lastStartAt.Time).Milliseconds()
+	}
+
+	maintenance, err := MaintenanceForMonitors(ctx, db, []interface{}{monitorID}, []string{"?"})
+	if err != nil {
+		return err
+	}
+	res.InMaintenance = maintenance[monitorID] != ""
+
+	return resultq.RecordResult(ctx, db, res)
+}
+
+func EvaluateMissedHeartbeats(ctx context.Context, db *db.DB) error {
+	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT monitor_id, url, last_start_at
+	          FROM monitor
+	          WHERE monitor_type = 'heartbeat'
+	          AND is_active = 1
+	          AND heartbeat_state <> 'down'
+	          AND next_run_at <= NOW()
+	          FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error querying missed heartbeats: %v", err)
+	}
+
+	var missed []*resultq.ResultMessage
+	for rows.Next() {
+		var lastStartAt sql.NullTime
+		res := &resultq.ResultMessage{Status: "DOWN", Reason: "no heartbeat received within period and grace time"}
+
+		if err := rows.Scan(&res.MonitorID, &res.MonitorUrl, &lastStartAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning missed heartbeats: %v", err)
+		}
+
+		cycleID, err := NewCycleID()
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("error generating cycle id: %v", err)
+		}
+		res.CycleID = cycleID
+		res.CycleSize = 1
+		if lastStartAt.Valid {
+			res.Reason = "job started but did not report completion within period and grace time"
+		}
+
+		missed = append(missed, res)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating missed heartbeats: %v", err)
+	}
+
+	ids := make([]interface{}, len(missed))
+	placeholders := make([]string, len(missed))
+	for i, res := range missed {
+		ids[i] = res.MonitorID
+		placeholders[i] = "?"
+	}
+
+	maintenance, err := MaintenanceForMonitors(ctx, db, ids, placeholders)
+	if err != nil {
+		return err
+	}
+
+	// Paused heartbeats are looked at again once their window is over.
+	due := missed[:0]
+	for _, res := range missed {
+		switch maintenance[res.MonitorID] {
+		case MaintenancePause:
+			continue
+		case MaintenanceSuppress:
+			res.InMaintenance = true
+		}
+		due = append(due, res)
+	}
+	missed = due
+
+	for _, res := range missed {
+		if _, err := tx.ExecContext(ctx, `UPDATE monitor SET heartbeat_state = 'down' WHERE monitor_id = ?`, res.MonitorID); err != nil {
+			return fmt.Errorf("error marking heartbeat monitor down: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing missed heartbeats: %v", err)
+	}
+
+	for _, res := range missed {
+		if err := resultq.RecordResult(ctx, db, res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -15,6 +15,21 @@ import (
 )
 
 func GetResult(m Monitor) (*Result, error) {
+	switch m.Type {
+	case MonitorTypeWebSocket:
+		return GetWebSocketResult(m)
+	case MonitorTypeSMTP, MonitorTypeIMAP, MonitorTypePOP3:
+		return GetMailResult(m)
+	case MonitorTypeMySQL, MonitorTypePostgres, MonitorTypeRedis:
+		return GetDatabaseResult(m)
+	case MonitorTypeUDP:
+		return GetUDPResult(m)
+	default:
+		return GetHTTPResult(m)
+	}
+}
+
+func GetHTTPResult(m Monitor) (*Result, error) {
 
 	var (
 		dnsStart, dnsEnd         time.Time
@@ -45,6 +60,14 @@ func GetResult(m Monitor) (*Result, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return &Result{
+				MonitorID:  m.ID,
+				MonitorUrl: m.Url,
+				Status:     "DOWN",
+				Reason:     reason,
+			}, nil
+		}
 		if ne, ok := err.(net.Error); ok && ne.Timeout() {
 			return &Result{
 				MonitorID:  m.ID,
@@ -57,10 +80,17 @@ func GetResult(m Monitor) (*Result, error) {
 	}
 	defer resp.Body.Close()
 
-	bytesRead, err = io.CopyN(io.Discard, resp.Body, 1024)
+	// Only the start of the body is read to time the download, unless it is
+	// checked against a schema.
+	limit := int64(1024)
+	if m.ResponseSchema.Valid {
+		limit = maxSchemaBodyBytes + 1
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
 	if err != nil {
 		return nil, fmt.Errorf("error reading the response body %v", err)
 	}
+	bytesRead = int64(len(body))
 
 	end := time.Now()
 
@@ -88,6 +118,18 @@ func GetResult(m Monitor) (*Result, error) {
 		}, nil
 	}
 
+	if m.ResponseSchema.Valid {
+		if reason := checkResponseSchema(m.ResponseSchema.String, body); reason != "" {
+			return &Result{
+				MonitorID:  m.ID,
+				MonitorUrl: m.Url,
+				StatusCode: resp.StatusCode,
+				Status:     "DOWN",
+				Reason:     reason,
+			}, nil
+		}
+	}
+
 	downloadTime := end.Sub(firstByte)
 
 	if downloadTime > 0 {
@@ -113,6 +155,22 @@ func GetResult(m Monitor) (*Result, error) {
 	}, nil
 }
 
+// checkResponseSchema returns why body does not match the schema, or an
+// empty string when it does.
+func checkResponseSchema(raw string, body []byte) string {
+	if len(body) > maxSchemaBodyBytes {
+		return fmt.Sprintf("response body is larger than the %d bytes checked against the schema", maxSchemaBodyBytes)
+	}
+	schema, err := ParseResponseSchema(raw)
+	if err != nil {
+		return "invalid response schema: " + err.Error()
+	}
+	if err := ValidateResponseSchema(schema, body); err != nil {
+		return "response does not match the schema: " + err.Error()
+	}
+	return ""
+}
+
 func Buildreq(m Monitor, trace *httptrace.ClientTrace) (*http.Request, error) {
 	var bodyReader io.Reader
 
@@ -134,6 +192,10 @@ func Buildreq(m Monitor, trace *httptrace.ClientTrace) (*http.Request, error) {
 		req.ContentLength = int64(len(m.RequestBody.String))
 	}
 
+	if m.AuthUsername.Valid && m.AuthUsername.String != "" {
+		req.SetBasicAuth(m.AuthUsername.String, m.AuthPassword.String)
+	}
+
 	return req, nil
 }
 
@@ -160,6 +222,10 @@ func setRequestHeaders(m Monitor, req *http.Request) {
 
 				continue
 
+			case "Content-Type":
+
+				req.Header.Set(canonicalKey, val)
+
 			case "Cookie", "Set-Cookie", "Accept", "Accept-Encoding":
 
 				req.Header.Add(canonicalKey, val)
@@ -171,20 +237,23 @@ func setRequestHeaders(m Monitor, req *http.Request) {
 	}
 }
 
-func BuildClient(m Monitor) *http.Client {
+func connectionTimeout(m Monitor) time.Duration {
 	const defaultConnectionTimeout = 10 * time.Second
 
-	dialer := &net.Dialer{
-		Timeout: defaultConnectionTimeout,
-	}
 	if m.ConnectionTimeout.Valid && m.ConnectionTimeout.Int64 > 0 {
-		dialer.Timeout = time.Duration(m.ConnectionTimeout.Int64) * time.Second
+		return time.Duration(m.ConnectionTimeout.Int64) * time.Second
 	}
+	return defaultConnectionTimeout
+}
 
+func BuildClient(m Monitor) *http.Client {
 	transport := &http.Transport{
-		DialContext:       dialer.DialContext,
+		DialContext:       Egress.DialContext(connectionTimeout(m)),
 		DisableKeepAlives: true,
 	}
+	if m.SkipTLSVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
 
 	return &http.Client{Transport: transport}
 }
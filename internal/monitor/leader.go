@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+
+	db "github.com/dhruvthak3r/Probe/config"
+)
+
+const SchedulerLockName = "probe_scheduler"
+
+// Leader holds the scheduler lock on a dedicated connection. MySQL releases
+// a GET_LOCK lock as soon as that connection goes away, so a crashed leader
+// is replaced on the next tick of any other instance.
+type Leader struct {
+	mu         sync.Mutex
+	db         *db.DB
+	conn       *sql.Conn
+	InstanceID string
+	Hostname   string
+}
+
+func NewLeader(db *db.DB) (*Leader, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, fmt.Errorf("error generating instance id: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &Leader{
+		db:         db,
+		InstanceID: id,
+		Hostname:   hostname,
+	}, nil
+}
+
+func (l *Leader) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		var held sql.NullBool
+		err := l.conn.QueryRowContext(ctx, `SELECT IS_USED_LOCK(?) = CONNECTION_ID()`, SchedulerLockName).Scan(&held)
+		if err == nil && held.Valid && held.Bool {
+			return true, l.record(ctx)
+		}
+
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Pool.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error getting connection for scheduler lock: %v", err)
+	}
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 0)`, SchedulerLockName).Scan(&got); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("error acquiring scheduler lock: %v", err)
+	}
+
+	if !got.Valid || got.Int64 != 1 {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	fmt.Printf("scheduler leadership acquired by instance %s (%s)\n", l.InstanceID, l.Hostname)
+
+	return true, l.record(ctx)
+}
+
+func (l *Leader) record(ctx context.Context) error {
+	upsert := `INSERT INTO scheduler_leader (id, instance_id, hostname, acquired_at, renewed_at)
+	VALUES (1, ?, ?, NOW(3), NOW(3))
+	ON DUPLICATE KEY UPDATE
+	  acquired_at = IF(instance_id = VALUES(instance_id), acquired_at, VALUES(acquired_at)),
+	  instance_id = VALUES(instance_id),
+	  hostname = VALUES(hostname),
+	  renewed_at = VALUES(renewed_at)`
+
+	if _, err := l.conn.ExecContext(ctx, upsert, l.InstanceID, l.Hostname); err != nil {
+		return fmt.Errorf("error recording scheduler leader: %v", err)
+	}
+
+	return nil
+}
+
+func (l *Leader) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(context.Background(), `DO RELEASE_LOCK(?)`, SchedulerLockName)
+	l.conn.Close()
+	l.conn = nil
+
+	if err != nil {
+		return fmt.Errorf("error releasing scheduler lock: %v", err)
+	}
+
+	return nil
+}
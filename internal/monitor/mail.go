@@ -0,0 +1,414 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	TLSModeNone     = "none"
+	TLSModeStartTLS = "starttls"
+	TLSModeImplicit = "implicit"
+)
+
+type mailDialect interface {
+	Greeting(tp *textproto.Conn) (int, string, error)
+	Capabilities(tp *textproto.Conn) ([]string, error)
+	StartTLS(tp *textproto.Conn) error
+	Authenticate(tp *textproto.Conn, username string, password string) error
+	Quit(tp *textproto.Conn)
+}
+
+type mailPorts struct {
+	plain    string
+	implicit string
+}
+
+var defaultMailPorts = map[string]mailPorts{
+	MonitorTypeSMTP: {plain: "25", implicit: "465"},
+	MonitorTypeIMAP: {plain: "143", implicit: "993"},
+	MonitorTypePOP3: {plain: "110", implicit: "995"},
+}
+
+func newMailDialect(monitorType string) (mailDialect, error) {
+	switch monitorType {
+	case MonitorTypeSMTP:
+		return &smtpDialect{}, nil
+	case MonitorTypeIMAP:
+		return &imapDialect{}, nil
+	case MonitorTypePOP3:
+		return &pop3Dialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported mail monitor type %q", monitorType)
+	}
+}
+
+func GetMailResult(m Monitor) (*Result, error) {
+	dialect, err := newMailDialect(m.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := defaultMailPorts[m.Type]
+	defaultPort := ports.plain
+	if m.TLSMode == TLSModeImplicit {
+		defaultPort = ports.implicit
+	}
+
+	host, port, err := SplitTarget(m.Url, defaultPort)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the mail target %v", err)
+	}
+
+	timeout := connectionTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	down := func(reason string) *Result {
+		return &Result{
+			MonitorID:  m.ID,
+			MonitorUrl: m.Url,
+			Status:     "DOWN",
+			Reason:     reason,
+		}
+	}
+
+	start := time.Now()
+
+	if err := Egress.CheckHost(host, port); err != nil {
+		return down(err.Error()), nil
+	}
+
+	ip, dnsTime, err := ResolveHost(ctx, host)
+	if err != nil {
+		return down(fmt.Sprintf("dns lookup failed: %v", err)), nil
+	}
+
+	dialer := Egress.Dialer(timeout)
+
+	connectStart := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return down(reason), nil
+		}
+		if isTimeout(err) {
+			return down("connection timed out"), nil
+		}
+		return down(fmt.Sprintf("connection failed: %v", err)), nil
+	}
+	connectEnd := time.Now()
+	defer func() { conn.Close() }()
+
+	conn.SetDeadline(start.Add(timeout))
+
+	result := &Result{
+		MonitorID:       m.ID,
+		MonitorUrl:      m.Url,
+		Status:          "UP",
+		ResolvedIp:      ip,
+		DNSResponseTime: dnsTime,
+		ConnectionTime:  connectEnd.Sub(connectStart),
+	}
+
+	fail := func(reason string) (*Result, error) {
+		result.Status = "DOWN"
+		result.Reason = reason
+		result.ResponseTime = time.Since(start)
+		return result, nil
+	}
+
+	tlsConfig := &tls.Config{ServerName: host}
+
+	bannerStart := connectEnd
+	if m.TLSMode == TLSModeImplicit {
+		tlsStart := time.Now()
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(fmt.Sprintf("tls handshake failed: %v", err))
+		}
+		conn = tlsConn
+		bannerStart = time.Now()
+		result.TLSHandshakeTime = bannerStart.Sub(tlsStart)
+	}
+
+	tp := textproto.NewConn(conn)
+
+	code, banner, err := dialect.Greeting(tp)
+	if err != nil {
+		return fail(fmt.Sprintf("%s greeting failed: %v", m.Type, err))
+	}
+	result.StatusCode = code
+	result.FirstByteTime = time.Since(bannerStart)
+
+	matched, err := MatchResponsePattern(m.ResponsePattern.String, []byte(banner))
+	if err != nil {
+		return fail(err.Error())
+	}
+	if !matched {
+		return fail(fmt.Sprintf("greeting banner %q did not match expected pattern", banner))
+	}
+
+	capabilities, err := dialect.Capabilities(tp)
+	if err != nil {
+		return fail(fmt.Sprintf("%s capability request failed: %v", m.Type, err))
+	}
+
+	if m.TLSMode == TLSModeStartTLS {
+		if err := dialect.StartTLS(tp); err != nil {
+			return fail(fmt.Sprintf("starttls rejected: %v", err))
+		}
+
+		tlsStart := time.Now()
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(fmt.Sprintf("tls handshake failed: %v", err))
+		}
+		conn = tlsConn
+		result.TLSHandshakeTime = time.Since(tlsStart)
+
+		tp = textproto.NewConn(conn)
+
+		capabilities, err = dialect.Capabilities(tp)
+		if err != nil {
+			return fail(fmt.Sprintf("%s capability request after starttls failed: %v", m.Type, err))
+		}
+	}
+
+	for _, required := range splitCapabilities(m.RequiredCapabilities.String) {
+		if !hasCapability(capabilities, required) {
+			return fail(fmt.Sprintf("server does not advertise required capability %s", required))
+		}
+	}
+
+	if m.AuthUsername.Valid && m.AuthUsername.String != "" {
+		if err := dialect.Authenticate(tp, m.AuthUsername.String, m.AuthPassword.String); err != nil {
+			return fail(fmt.Sprintf("authentication failed: %v", err))
+		}
+	}
+
+	dialect.Quit(tp)
+
+	result.ResponseTime = time.Since(start)
+
+	return result, nil
+}
+
+func splitCapabilities(s string) []string {
+	var capabilities []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c != "" {
+			capabilities = append(capabilities, c)
+		}
+	}
+	return capabilities
+}
+
+func hasCapability(capabilities []string, required string) bool {
+	for _, c := range capabilities {
+		if strings.EqualFold(c, required) {
+			return true
+		}
+		if fields := strings.Fields(c); len(fields) > 0 && strings.EqualFold(fields[0], required) {
+			return true
+		}
+	}
+	return false
+}
+
+type smtpDialect struct{}
+
+func (smtpDialect) cmd(tp *textproto.Conn, expectCode int, format string, args ...any) (int, string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+
+	return tp.ReadResponse(expectCode)
+}
+
+func (smtpDialect) Greeting(tp *textproto.Conn) (int, string, error) {
+	return tp.ReadResponse(220)
+}
+
+func (d smtpDialect) Capabilities(tp *textproto.Conn) ([]string, error) {
+	_, msg, err := d.cmd(tp, 250, "EHLO %s", "probe.localhost")
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(msg, "\n")
+	if len(lines) <= 1 {
+		return nil, nil
+	}
+
+	return lines[1:], nil
+}
+
+func (d smtpDialect) StartTLS(tp *textproto.Conn) error {
+	_, _, err := d.cmd(tp, 220, "STARTTLS")
+	return err
+}
+
+func (d smtpDialect) Authenticate(tp *textproto.Conn, username string, password string) error {
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+	_, _, err := d.cmd(tp, 235, "AUTH PLAIN %s", credentials)
+	return err
+}
+
+func (d smtpDialect) Quit(tp *textproto.Conn) {
+	d.cmd(tp, 221, "QUIT")
+}
+
+type imapDialect struct {
+	tag int
+}
+
+func (d *imapDialect) cmd(tp *textproto.Conn, format string, args ...any) ([]string, error) {
+	d.tag++
+	tag := fmt.Sprintf("p%d", d.tag)
+
+	if err := tp.PrintfLine(tag+" "+format, args...); err != nil {
+		return nil, err
+	}
+
+	var untagged []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(line, tag+" ") {
+			untagged = append(untagged, line)
+			continue
+		}
+
+		status := strings.TrimPrefix(line, tag+" ")
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return untagged, fmt.Errorf("%s", status)
+		}
+		return untagged, nil
+	}
+}
+
+func (d *imapDialect) Greeting(tp *textproto.Conn) (int, string, error) {
+	line, err := tp.ReadLine()
+	if err != nil {
+		return 0, "", err
+	}
+
+	upper := strings.ToUpper(line)
+	if !strings.HasPrefix(upper, "* OK") && !strings.HasPrefix(upper, "* PREAUTH") {
+		return 0, line, fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	return 0, line, nil
+}
+
+func (d *imapDialect) Capabilities(tp *textproto.Conn) ([]string, error) {
+	untagged, err := d.cmd(tp, "CAPABILITY")
+	if err != nil {
+		return nil, err
+	}
+
+	var capabilities []string
+	for _, line := range untagged {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "*" && strings.EqualFold(fields[1], "CAPABILITY") {
+			capabilities = append(capabilities, fields[2:]...)
+		}
+	}
+
+	return capabilities, nil
+}
+
+func (d *imapDialect) StartTLS(tp *textproto.Conn) error {
+	_, err := d.cmd(tp, "STARTTLS")
+	return err
+}
+
+func (d *imapDialect) Authenticate(tp *textproto.Conn, username string, password string) error {
+	_, err := d.cmd(tp, "LOGIN %s %s", imapQuote(username), imapQuote(password))
+	return err
+}
+
+func (d *imapDialect) Quit(tp *textproto.Conn) {
+	d.cmd(tp, "LOGOUT")
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+type pop3Dialect struct{}
+
+func (pop3Dialect) cmd(tp *textproto.Conn, multiline bool, format string, args ...any) (string, []string, error) {
+	if err := tp.PrintfLine(format, args...); err != nil {
+		return "", nil, err
+	}
+
+	line, err := pop3Status(tp)
+	if err != nil || !multiline {
+		return line, nil, err
+	}
+
+	lines, err := tp.ReadDotLines()
+	if err != nil {
+		return line, nil, err
+	}
+
+	return line, lines, nil
+}
+
+func pop3Status(tp *textproto.Conn) (string, error) {
+	line, err := tp.ReadLine()
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return line, fmt.Errorf("%s", line)
+	}
+
+	return line, nil
+}
+
+func (pop3Dialect) Greeting(tp *textproto.Conn) (int, string, error) {
+	line, err := pop3Status(tp)
+	return 0, line, err
+}
+
+func (d pop3Dialect) Capabilities(tp *textproto.Conn) ([]string, error) {
+	_, lines, err := d.cmd(tp, true, "CAPA")
+	return lines, err
+}
+
+func (d pop3Dialect) StartTLS(tp *textproto.Conn) error {
+	_, _, err := d.cmd(tp, false, "STLS")
+	return err
+}
+
+func (d pop3Dialect) Authenticate(tp *textproto.Conn, username string, password string) error {
+	if _, _, err := d.cmd(tp, false, "USER %s", username); err != nil {
+		return err
+	}
+
+	_, _, err := d.cmd(tp, false, "PASS %s", password)
+	return err
+}
+
+func (d pop3Dialect) Quit(tp *textproto.Conn) {
+	d.cmd(tp, false, "QUIT")
+}
@@ -0,0 +1,314 @@
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverTLS is the stand-in servers' certificate. TestMain makes it the only
+// trusted root, so checks verify it like a real one.
+var serverTLS *tls.Config
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "probe-monitor-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	cfg, err := writeTestCertificate(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	serverTLS = cfg
+	os.Setenv("SSL_CERT_FILE", filepath.Join(dir, "ca.pem"))
+
+	// The stand-in servers listen on loopback, which the default policy
+	// denies.
+	Egress = mustEgressPolicy(EgressConfig{})
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func writeTestCertificate(path string) (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "probe test"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, certPEM, 0o600); err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// mailServer is a scripted stand-in for one protocol. It is handed each
+// command line and returns the reply lines, and whether to switch to TLS or
+// hang up after writing them.
+type mailServer struct {
+	greeting string
+	reply    func(line string, secure bool) (lines []string, startTLS bool, quit bool)
+}
+
+func serveMail(t *testing.T, s mailServer, implicit bool) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, implicit)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func (s mailServer) handle(conn net.Conn, implicit bool) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	secure := implicit
+	if implicit {
+		conn = tls.Server(conn, serverTLS)
+	}
+	tp := textproto.NewConn(conn)
+
+	if s.greeting != "" {
+		tp.PrintfLine("%s", s.greeting)
+	}
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		lines, startTLS, quit := s.reply(line, secure)
+		for _, l := range lines {
+			tp.PrintfLine("%s", l)
+		}
+
+		switch {
+		case quit:
+			return
+		case startTLS:
+			conn = tls.Server(conn, serverTLS)
+			tp = textproto.NewConn(conn)
+			secure = true
+		}
+	}
+}
+
+const (
+	testUser     = "probe"
+	testPassword = "secret"
+)
+
+func smtpServer(greeting string) mailServer {
+	return mailServer{
+		greeting: greeting,
+		reply: func(line string, secure bool) ([]string, bool, bool) {
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO":
+				if secure {
+					return []string{"250-mail.test", "250 AUTH PLAIN"}, false, false
+				}
+				return []string{"250-mail.test", "250-STARTTLS", "250 AUTH PLAIN"}, false, false
+			case "STARTTLS":
+				return []string{"220 ready to start tls"}, true, false
+			case "AUTH":
+				credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + testUser + "\x00" + testPassword))
+				if arg == "PLAIN "+credentials {
+					return []string{"235 authenticated"}, false, false
+				}
+				return []string{"535 authentication credentials invalid"}, false, false
+			case "QUIT":
+				return []string{"221 bye"}, false, true
+			}
+			return []string{"500 unknown command"}, false, false
+		},
+	}
+}
+
+func imapServer() mailServer {
+	return mailServer{
+		greeting: "* OK IMAP4rev1 ready",
+		reply: func(line string, secure bool) ([]string, bool, bool) {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return []string{"* BAD missing tag"}, false, false
+			}
+			tag := fields[0]
+
+			switch strings.ToUpper(fields[1]) {
+			case "CAPABILITY":
+				capabilities := "* CAPABILITY IMAP4rev1 STARTTLS"
+				if secure {
+					capabilities = "* CAPABILITY IMAP4rev1 AUTH=PLAIN"
+				}
+				return []string{capabilities, tag + " OK CAPABILITY completed"}, false, false
+			case "STARTTLS":
+				return []string{tag + " OK begin tls"}, true, false
+			case "LOGIN":
+				if strings.Join(fields[2:], " ") == fmt.Sprintf("%q %q", testUser, testPassword) {
+					return []string{tag + " OK LOGIN completed"}, false, false
+				}
+				return []string{tag + " NO [AUTHENTICATIONFAILED] invalid credentials"}, false, false
+			case "LOGOUT":
+				return []string{"* BYE", tag + " OK LOGOUT completed"}, false, true
+			}
+			return []string{tag + " BAD unknown command"}, false, false
+		},
+	}
+}
+
+func pop3Server() mailServer {
+	return mailServer{
+		greeting: "+OK POP3 ready",
+		reply: func(line string, secure bool) ([]string, bool, bool) {
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "CAPA":
+				if secure {
+					return []string{"+OK", "USER", "."}, false, false
+				}
+				return []string{"+OK", "USER", "STLS", "."}, false, false
+			case "STLS":
+				return []string{"+OK begin tls"}, true, false
+			case "USER":
+				return []string{"+OK"}, false, false
+			case "PASS":
+				if arg == testPassword {
+					return []string{"+OK logged in"}, false, false
+				}
+				return []string{"-ERR invalid credentials"}, false, false
+			case "QUIT":
+				return []string{"+OK bye"}, false, true
+			}
+			return []string{"-ERR unknown command"}, false, false
+		},
+	}
+}
+
+func TestGetMailResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		monitorType  string
+		server       mailServer
+		tlsMode      string
+		username     string
+		password     string
+		pattern      string
+		capabilities string
+		wantStatus   string
+		wantCode     int
+		wantReason   string
+		wantTLS      bool
+	}{
+		{name: "smtp greeting", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeNone, pattern: "ESMTP", wantStatus: "UP", wantCode: 220},
+		{name: "smtp greeting mismatch", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeNone, pattern: "Postfix", wantStatus: "DOWN", wantReason: "did not match expected pattern"},
+		{name: "smtp rejecting greeting", monitorType: MonitorTypeSMTP, server: smtpServer("554 no service"), tlsMode: TLSModeNone, wantStatus: "DOWN", wantReason: "smtp greeting failed"},
+		{name: "smtp starttls auth", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeStartTLS, username: testUser, password: testPassword, capabilities: "AUTH", wantStatus: "UP", wantCode: 220, wantTLS: true},
+		{name: "smtp starttls auth failure", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeStartTLS, username: testUser, password: "wrong", wantStatus: "DOWN", wantReason: "authentication failed"},
+		{name: "smtp implicit tls", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeImplicit, username: testUser, password: testPassword, wantStatus: "UP", wantCode: 220, wantTLS: true},
+		{name: "smtp missing capability", monitorType: MonitorTypeSMTP, server: smtpServer("220 mail.test ESMTP ready"), tlsMode: TLSModeNone, capabilities: "SMTPUTF8", wantStatus: "DOWN", wantReason: "required capability SMTPUTF8"},
+		{name: "imap starttls auth", monitorType: MonitorTypeIMAP, server: imapServer(), tlsMode: TLSModeStartTLS, username: testUser, password: testPassword, capabilities: "AUTH=PLAIN", wantStatus: "UP", wantTLS: true},
+		{name: "imap auth failure", monitorType: MonitorTypeIMAP, server: imapServer(), tlsMode: TLSModeStartTLS, username: testUser, password: "wrong", wantStatus: "DOWN", wantReason: "authentication failed"},
+		{name: "imap implicit tls", monitorType: MonitorTypeIMAP, server: imapServer(), tlsMode: TLSModeImplicit, username: testUser, password: testPassword, wantStatus: "UP", wantTLS: true},
+		{name: "pop3 starttls auth", monitorType: MonitorTypePOP3, server: pop3Server(), tlsMode: TLSModeStartTLS, username: testUser, password: testPassword, wantStatus: "UP", wantTLS: true},
+		{name: "pop3 auth failure", monitorType: MonitorTypePOP3, server: pop3Server(), tlsMode: TLSModeNone, username: testUser, password: "wrong", wantStatus: "DOWN", wantReason: "authentication failed"},
+		{name: "pop3 implicit tls", monitorType: MonitorTypePOP3, server: pop3Server(), tlsMode: TLSModeImplicit, username: testUser, password: testPassword, wantStatus: "UP", wantTLS: true},
+		{name: "silent server times out", monitorType: MonitorTypeSMTP, server: mailServer{reply: smtpServer("").reply}, tlsMode: TLSModeNone, wantStatus: "DOWN", wantReason: "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serveMail(t, tt.server, tt.tlsMode == TLSModeImplicit)
+
+			m := Monitor{
+				Type:                 tt.monitorType,
+				Url:                  addr,
+				TLSMode:              tt.tlsMode,
+				ConnectionTimeout:    sql.NullInt64{Int64: 1, Valid: true},
+				AuthUsername:         sql.NullString{String: tt.username, Valid: tt.username != ""},
+				AuthPassword:         sql.NullString{String: tt.password, Valid: tt.password != ""},
+				ResponsePattern:      sql.NullString{String: tt.pattern, Valid: tt.pattern != ""},
+				RequiredCapabilities: sql.NullString{String: tt.capabilities, Valid: tt.capabilities != ""},
+			}
+
+			res, err := GetMailResult(m)
+			if err != nil {
+				t.Fatalf("GetMailResult: %v", err)
+			}
+
+			if res.Status != tt.wantStatus {
+				t.Fatalf("status = %s (%s), want %s", res.Status, res.Reason, tt.wantStatus)
+			}
+			if !strings.Contains(res.Reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", res.Reason, tt.wantReason)
+			}
+			if tt.wantCode != 0 && res.StatusCode != tt.wantCode {
+				t.Errorf("status code = %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			if tt.wantTLS && res.TLSHandshakeTime <= 0 {
+				t.Errorf("tls handshake time = %v, want it measured", res.TLSHandshakeTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/dhruvthak3r/Probe/config"
+)
+
+const (
+	MaintenancePause    = "pause"
+	MaintenanceSuppress = "suppress"
+)
+
+// MaintenanceWindow is either one-off, active between StartsAt and EndsAt,
+// or recurring, active for DurationSecs after every Cron tick between
+// StartsAt and the optional EndsAt.
+type MaintenanceWindow struct {
+	ID           int64
+	Mode         string
+	StartsAt     time.Time
+	EndsAt       sql.NullTime
+	Cron         sql.NullString
+	DurationSecs sql.NullInt64
+	Timezone     string
+}
+
+func (w *MaintenanceWindow) ActiveAt(t time.Time) bool {
+	if t.Before(w.StartsAt) {
+		return false
+	}
+	if w.EndsAt.Valid && !t.Before(w.EndsAt.Time) {
+		return false
+	}
+	if !w.Cron.Valid || w.Cron.String == "" {
+		return true
+	}
+
+	sched, err := ParseCron(w.Cron.String)
+	if err != nil {
+		return false
+	}
+	loc, err := ParseTimezone(w.Timezone)
+	if err != nil {
+		return false
+	}
+
+	duration := time.Duration(w.DurationSecs.Int64) * time.Second
+	next := sched.Next(t.Add(-duration).In(loc))
+
+	return !next.After(t)
+}
+
+// MaintenanceForMonitors returns the maintenance mode currently in effect for
+// each monitor, either through a window scoped to the monitor or to one of
+// its tags. Windows only apply to monitors of their own organization. Pause
+// wins over suppress when windows overlap.
+func MaintenanceForMonitors(ctx context.Context, db *db.DB, ids []interface{}, placeholders []string) (map[int]string, error) {
+	modes := make(map[int]string)
+	if len(ids) == 0 {
+		return modes, nil
+	}
+
+	// Windows are stored in UTC, so they are compared with the time in UTC
+	// rather than NOW(), which is in the session's time zone.
+	now := time.Now().UTC()
+
+	query := fmt.Sprintf(`
+		SELECT s.monitor_id, w.window_id, w.mode, w.starts_at, w.ends_at, w.cron_expression, w.duration_seconds, w.timezone
+		FROM maintenance_windows w
+		JOIN (
+			SELECT window_id, monitor_id FROM maintenance_window_monitors
+			UNION
+			SELECT wt.window_id, mt.monitor_id
+			FROM maintenance_window_tags wt
+			JOIN monitor_tags mt ON mt.tag_key = wt.tag_key AND mt.tag_value = wt.tag_value
+		) s ON s.window_id = w.window_id
+		JOIN monitor m ON m.monitor_id = s.monitor_id AND m.org_id = w.org_id
+		WHERE s.monitor_id IN (%s)
+		  AND w.starts_at <= ?
+		  AND (w.ends_at IS NULL OR w.ends_at > ?)`, strings.Join(placeholders, ","))
+
+	args := append(append([]interface{}{}, ids...), now, now)
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying maintenance windows: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var monitorID int
+		var w MaintenanceWindow
+
+		if err := rows.Scan(&monitorID, &w.ID, &w.Mode, &w.StartsAt, &w.EndsAt, &w.Cron, &w.DurationSecs, &w.Timezone); err != nil {
+			return nil, fmt.Errorf("error scanning maintenance windows: %v", err)
+		}
+
+		if !w.ActiveAt(now) {
+			continue
+		}
+		if modes[monitorID] != MaintenancePause {
+			modes[monitorID] = w.Mode
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance windows: %v", err)
+	}
+
+	return modes, nil
+}
@@ -2,13 +2,12 @@ package monitor
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 
 	"fmt"
 	"time"
 
-	db "github.com/dhruvthak3r/Probe/config"
-
 	resultq "github.com/dhruvthak3r/Probe/internal/mq"
 )
 
@@ -24,51 +23,12 @@ type Result struct {
 	FirstByteTime    time.Duration `json:"first_byte_time,omitempty"`
 	DownloadTime     time.Duration `json:"download_time,omitempty"`
 	ResponseTime     time.Duration `json:"response_time,omitempty"`
+	HandshakeTime    time.Duration `json:"handshake_time,omitempty"`
+	RoundTripTime    time.Duration `json:"round_trip_time,omitempty"`
 	Throughput       float64       `json:"throughput,omitempty"`
 	Reason           string        `json:"reason,omitempty"`
 }
 
-func (mq *MonitorQueue) PollUrls(ctx context.Context, db *db.DB, rmq *resultq.Publisher) error {
-	for {
-		select {
-		case m, ok := <-mq.UrlsToPoll:
-			if !ok {
-				return nil
-			}
-
-			func(m *Monitor) {
-
-				res, err := GetResult(*m)
-				if err != nil {
-					fmt.Printf("error getting results: %v\n", err)
-					return
-				}
-
-				resconv := ToResultMessage(*res)
-
-				payload, err := json.Marshal(resconv)
-				if err != nil {
-					fmt.Printf("error marshalling monitor data: %v\n", err)
-					return
-				}
-
-				if err := rmq.PublishToQueue(ctx, payload); err != nil {
-					fmt.Printf("error publishing monitor data to queue: %v\n", err)
-					return
-				}
-
-				if err := SetStatusToIdle(ctx, db, m); err != nil {
-					fmt.Printf("error setting monitor status to idle: %v\n", err)
-				}
-
-			}(m)
-
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-}
-
 func ToResultMessage(res Result) *resultq.ResultMessage {
 	return &resultq.ResultMessage{
 		MonitorID:        res.MonitorID,
@@ -82,7 +42,116 @@ func ToResultMessage(res Result) *resultq.ResultMessage {
 		FirstByteTime:    res.FirstByteTime.Milliseconds(),
 		DownloadTime:     res.DownloadTime.Milliseconds(),
 		ResponseTime:     res.ResponseTime.Milliseconds(),
+		HandshakeTime:    res.HandshakeTime.Milliseconds(),
+		RoundTripTime:    res.RoundTripTime.Milliseconds(),
 		Throughput:       res.Throughput,
 		Reason:           res.Reason,
 	}
 }
+
+func ToJobMessage(m Monitor, location string) *resultq.JobMessage {
+	job := &resultq.JobMessage{
+		MonitorID:            m.ID,
+		Type:                 m.Type,
+		Url:                  m.Url,
+		FrequencySecs:        m.FrequencySecs,
+		ResponseFormat:       m.ResponseFormat,
+		HttpMethod:           m.HttpMethod,
+		RequestHeaders:       m.RequestHeaders,
+		ResponseHeaders:      m.ResponseHeaders,
+		AcceptedStatusCodes:  m.AcceptedStatusCodes,
+		ResponsePattern:      m.ResponsePattern.String,
+		ResponseSchema:       m.ResponseSchema.String,
+		TLSMode:              m.TLSMode,
+		AuthUsername:         m.AuthUsername.String,
+		AuthPassword:         m.AuthPassword.String,
+		SkipTLSVerify:        m.SkipTLSVerify,
+		RequiredCapabilities: m.RequiredCapabilities.String,
+		ExpectedResult:       m.ExpectedResult.String,
+		PayloadEncoding:      m.PayloadEncoding,
+		Location:             location,
+		CycleID:              m.CycleID,
+		CycleSize:            len(m.Locations),
+		InMaintenance:        m.InMaintenance,
+	}
+
+	if job.CycleSize == 0 {
+		job.CycleSize = 1
+	}
+
+	if m.ConnectionTimeout.Valid {
+		job.ConnectionTimeout = &m.ConnectionTimeout.Int64
+	}
+	if m.RequestBody.Valid {
+		job.RequestBody = &m.RequestBody.String
+	}
+
+	return job
+}
+
+func FromJobMessage(job *resultq.JobMessage) *Monitor {
+	m := &Monitor{
+		ID:                   job.MonitorID,
+		Type:                 job.Type,
+		Url:                  job.Url,
+		FrequencySecs:        job.FrequencySecs,
+		ResponseFormat:       job.ResponseFormat,
+		HttpMethod:           job.HttpMethod,
+		RequestHeaders:       job.RequestHeaders,
+		ResponseHeaders:      job.ResponseHeaders,
+		AcceptedStatusCodes:  job.AcceptedStatusCodes,
+		ResponsePattern:      sql.NullString{String: job.ResponsePattern, Valid: job.ResponsePattern != ""},
+		ResponseSchema:       sql.NullString{String: job.ResponseSchema, Valid: job.ResponseSchema != ""},
+		TLSMode:              job.TLSMode,
+		AuthUsername:         sql.NullString{String: job.AuthUsername, Valid: job.AuthUsername != ""},
+		AuthPassword:         sql.NullString{String: job.AuthPassword, Valid: job.AuthPassword != ""},
+		SkipTLSVerify:        job.SkipTLSVerify,
+		RequiredCapabilities: sql.NullString{String: job.RequiredCapabilities, Valid: job.RequiredCapabilities != ""},
+		ExpectedResult:       sql.NullString{String: job.ExpectedResult, Valid: job.ExpectedResult != ""},
+		PayloadEncoding:      job.PayloadEncoding,
+		CycleID:              job.CycleID,
+		InMaintenance:        job.InMaintenance,
+	}
+
+	if job.ConnectionTimeout != nil {
+		m.ConnectionTimeout = sql.NullInt64{Int64: *job.ConnectionTimeout, Valid: true}
+	}
+	if job.RequestBody != nil {
+		m.RequestBody = sql.NullString{String: *job.RequestBody, Valid: true}
+	}
+	if len(m.AcceptedStatusCodes) == 0 {
+		m.AcceptedStatusCodes = []int{200}
+	}
+
+	return m
+}
+
+func RunJob(rmq *resultq.Publisher) func(context.Context, *resultq.JobMessage) error {
+	return func(ctx context.Context, job *resultq.JobMessage) error {
+		m := FromJobMessage(job)
+
+		// A check that fails to run is reported as DOWN rather than dropped,
+		// running it again would fail the same way.
+		res, err := GetResult(*m)
+		if err != nil {
+			res = &Result{MonitorID: m.ID, MonitorUrl: m.Url, Status: "DOWN", Reason: err.Error()}
+		}
+
+		resconv := ToResultMessage(*res)
+		resconv.Location = job.Location
+		resconv.CycleID = job.CycleID
+		resconv.CycleSize = job.CycleSize
+		resconv.InMaintenance = job.InMaintenance
+
+		payload, err := json.Marshal(resconv)
+		if err != nil {
+			return resultq.Permanent(fmt.Errorf("error marshalling monitor data: %v", err))
+		}
+
+		if err := rmq.PublishToQueue(ctx, payload); err != nil {
+			return fmt.Errorf("error publishing monitor data to queue: %v", err)
+		}
+
+		return nil
+	}
+}
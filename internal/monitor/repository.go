@@ -5,11 +5,18 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	db "github.com/dhruvthak3r/Probe/config"
 )
 
-func GetHeadersForMonitor(ctx context.Context, db *db.DB, table string, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
+// Querier is satisfied by both *sql.DB and *sql.Tx, so the child rows of a
+// monitor can be read inside the transaction that changes them.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func GetHeadersForMonitor(ctx context.Context, q Querier, table string, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
 
 	query := fmt.Sprintf(`
 		SELECT monitor_id, name, value
@@ -20,7 +27,7 @@ func GetHeadersForMonitor(ctx context.Context, db *db.DB, table string, ids []in
 		strings.Join(placeholders, ","),
 	)
 
-	rows, err := db.Pool.QueryContext(ctx, query, ids...)
+	rows, err := q.QueryContext(ctx, query, ids...)
 	if err != nil {
 		return nil, fmt.Errorf("failed getting headers from %s: %w", table, err)
 	}
@@ -50,31 +57,31 @@ func GetHeadersForMonitor(ctx context.Context, db *db.DB, table string, ids []in
 	return headersByMonitor, nil
 }
 
-func GetRequestHeadersForMonitor(ctx context.Context, db *db.DB, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
+func GetRequestHeadersForMonitor(ctx context.Context, q Querier, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
 
 	return GetHeadersForMonitor(
 		ctx,
-		db,
+		q,
 		"monitor_request_headers",
 		ids,
 		placeholders,
 	)
 }
 
-func GetResponseHeadersForMonitor(ctx context.Context, db *db.DB, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
+func GetResponseHeadersForMonitor(ctx context.Context, q Querier, ids []interface{}, placeholders []string) (map[int]map[string][]string, error) {
 
 	return GetHeadersForMonitor(
 		ctx,
-		db,
+		q,
 		"monitor_response_headers",
 		ids,
 		placeholders,
 	)
 }
 
-func GetAcceptedStatusCodeForMonitor(ctx context.Context, db *db.DB, ids []interface{}, placeholders []string) (map[int][]int, error) {
+func GetAcceptedStatusCodeForMonitor(ctx context.Context, q Querier, ids []interface{}, placeholders []string) (map[int][]int, error) {
 	acceptedstatuscodesQuery := fmt.Sprintf(`SELECT monitor_id,status_code FROM monitor_accepted_status_codes WHERE monitor_id IN (%s)`, strings.Join(placeholders, ","))
-	statuscodesRows, err := db.Pool.QueryContext(ctx, acceptedstatuscodesQuery, ids...)
+	statuscodesRows, err := q.QueryContext(ctx, acceptedstatuscodesQuery, ids...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed getting status codes..%w", err)
@@ -103,27 +110,139 @@ func GetAcceptedStatusCodeForMonitor(ctx context.Context, db *db.DB, ids []inter
 
 }
 
-func UpdateMonitorStatus(ctx context.Context, tx *sql.Tx, placeholders []string, ids []interface{}) error {
-	updateq := fmt.Sprintf(`
+func GetLocationsForMonitor(ctx context.Context, q Querier, ids []interface{}, placeholders []string) (map[int][]string, error) {
+	query := fmt.Sprintf(`
+		SELECT ml.monitor_id, l.name
+		FROM monitor_locations ml
+		JOIN locations l ON l.loc_id = ml.loc_id
+		WHERE ml.monitor_id IN (%s)
+	`, strings.Join(placeholders, ","))
+
+	rows, err := q.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting locations: %w", err)
+	}
+	defer rows.Close()
+
+	locationsByMonitor := make(map[int][]string)
+
+	for rows.Next() {
+		var monitorID int
+		var name string
+
+		if err := rows.Scan(&monitorID, &name); err != nil {
+			return nil, err
+		}
+
+		locationsByMonitor[monitorID] = append(locationsByMonitor[monitorID], name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locationsByMonitor, nil
+}
+
+func UpdateMonitorStatus(ctx context.Context, tx *sql.Tx, monitors []*Monitor, now time.Time) error {
+	updateq := `
         UPDATE monitor
 		set last_run_at = NOW(),
-        next_run_at = DATE_ADD(NOW(), INTERVAL frequency_seconds SECOND),
+        next_run_at = DATE_ADD(NOW(), INTERVAL ? SECOND),
         status = 'running'
-        WHERE monitor_id IN (%s)
-        `, strings.Join(placeholders, ","))
+        WHERE monitor_id = ?`
 
-	if _, err := tx.ExecContext(ctx, updateq, ids...); err != nil {
-		return fmt.Errorf("update failed: %w", err)
+	stmt, err := tx.PrepareContext(ctx, updateq)
+	if err != nil {
+		return fmt.Errorf("error preparing update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, m := range monitors {
+		var delay time.Duration
+
+		sched, err := m.Schedule()
+		if err != nil {
+			fmt.Printf("invalid schedule for monitor_id=%d, falling back to frequency: %v\n", m.ID, err)
+			delay = NextRunDelay(m.ID, m.FrequencySecs, m.JitterPercent, now)
+		} else {
+			delay = sched.Next(now)
+		}
+
+		seconds := int64((delay + time.Second - 1) / time.Second)
+		if _, err := stmt.ExecContext(ctx, seconds, m.ID); err != nil {
+			return fmt.Errorf("update failed: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// ParseTag splits a "key:value" tag. A tag without a colon has an empty value.
+func ParseTag(tag string) (string, string) {
+	key, value, _ := strings.Cut(tag, ":")
+	return strings.TrimSpace(key), strings.TrimSpace(value)
+}
+
+// NormalizeGroup cleans a folder style group path such as "prod/payments",
+// dropping empty segments and surrounding whitespace.
+func NormalizeGroup(group string) string {
+	segments := make([]string, 0, 4)
+	for _, segment := range strings.Split(group, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func FormatTag(key string, value string) string {
+	if value == "" {
+		return key
+	}
+	return key + ":" + value
+}
+
+func GetTagsForMonitor(ctx context.Context, q Querier, ids []interface{}, placeholders []string) (map[int][]string, error) {
+	query := fmt.Sprintf(`
+		SELECT monitor_id, tag_key, tag_value
+		FROM monitor_tags
+		WHERE monitor_id IN (%s)
+		ORDER BY tag_key
+	`, strings.Join(placeholders, ","))
+
+	rows, err := q.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting tags: %w", err)
+	}
+	defer rows.Close()
+
+	tagsByMonitor := make(map[int][]string)
+
+	for rows.Next() {
+		var monitorID int
+		var key, value string
+
+		if err := rows.Scan(&monitorID, &key, &value); err != nil {
+			return nil, fmt.Errorf("failed scanning tags: %w", err)
+		}
+
+		tagsByMonitor[monitorID] = append(tagsByMonitor[monitorID], FormatTag(key, value))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating tags: %w", err)
+	}
+
+	return tagsByMonitor, nil
+}
+
 func GetNextMonitors(ctx context.Context, tx *sql.Tx) ([]*Monitor, []interface{}, error) {
 
-	query := `SELECT monitor_id, url, frequency_seconds, last_run_at, next_run_at, response_format, request_body, http_method, connection_timeout
+	query := `SELECT monitor_id, url, frequency_seconds, last_run_at, next_run_at, response_format, request_body, http_method, connection_timeout, monitor_type, response_pattern, tls_mode, auth_username, auth_password, required_capabilities, expected_result, payload_encoding, jitter_percent, cron_expression, timezone, window_days, window_start, window_end, off_peak_frequency_seconds, skip_tls_verify, response_schema
 	          FROM monitor
               WHERE is_active = 1
+              AND monitor_type <> 'heartbeat'
               AND (
                 (status = 'idle' AND next_run_at <= NOW())
                 OR
@@ -161,14 +280,48 @@ func GetNextMonitors(ctx context.Context, tx *sql.Tx) ([]*Monitor, []interface{}
 		var RequestBody sql.NullString
 		var HttpMethod string
 		var ConnectionTimeout sql.NullInt64
-
-		err := rows.Scan(&ID, &Url, &FrequencySecs, &LastRunAt, &NextRunAt, &ResponseFormat, &RequestBody, &HttpMethod, &ConnectionTimeout)
+		var MonitorType string
+		var ResponsePattern sql.NullString
+		var TLSMode string
+		var AuthUsername sql.NullString
+		var AuthPassword sql.NullString
+		var RequiredCapabilities sql.NullString
+		var ExpectedResult sql.NullString
+		var PayloadEncoding string
+		var JitterPercent int
+		var CronExpression sql.NullString
+		var Timezone string
+		var WindowDays sql.NullString
+		var WindowStart sql.NullString
+		var WindowEnd sql.NullString
+		var OffPeakFrequencySecs sql.NullInt64
+		var SkipTLSVerify bool
+		var ResponseSchema sql.NullString
+
+		err := rows.Scan(&ID, &Url, &FrequencySecs, &LastRunAt, &NextRunAt, &ResponseFormat, &RequestBody, &HttpMethod, &ConnectionTimeout, &MonitorType, &ResponsePattern, &TLSMode, &AuthUsername, &AuthPassword, &RequiredCapabilities, &ExpectedResult, &PayloadEncoding, &JitterPercent, &CronExpression, &Timezone, &WindowDays, &WindowStart, &WindowEnd, &OffPeakFrequencySecs, &SkipTLSVerify, &ResponseSchema)
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("error scanning rows: %v\n", err)
 		}
 
 		m := NewMonitor(ID, Url, FrequencySecs, LastRunAt, NextRunAt, ResponseFormat, RequestBody, HttpMethod, ConnectionTimeout)
+		m.Type = MonitorType
+		m.ResponsePattern = ResponsePattern
+		m.ResponseSchema = ResponseSchema
+		m.TLSMode = TLSMode
+		m.AuthUsername = AuthUsername
+		m.AuthPassword = AuthPassword
+		m.RequiredCapabilities = RequiredCapabilities
+		m.ExpectedResult = ExpectedResult
+		m.PayloadEncoding = PayloadEncoding
+		m.JitterPercent = JitterPercent
+		m.CronExpression = CronExpression
+		m.Timezone = Timezone
+		m.WindowDays = WindowDays
+		m.WindowStart = WindowStart
+		m.WindowEnd = WindowEnd
+		m.OffPeakFrequencySecs = OffPeakFrequencySecs
+		m.SkipTLSVerify = SkipTLSVerify
 
 		monitors = append(monitors, m)
 		ids = append(ids, ID)
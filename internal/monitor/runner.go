@@ -10,12 +10,27 @@ import (
 func (mq *MonitorQueue) RunScheduler(
 	ctx context.Context,
 	db *db.DB,
+	leader *Leader,
 ) func() {
 
 	return func() {
 
-		if err := mq.EnqueueNextMonitorsToChan(ctx, db); err != nil {
+		if leader != nil {
+			isLeader, err := leader.Acquire(ctx)
+			if err != nil {
+				fmt.Printf("leader election error: %v\n", err)
+			}
+			if !isLeader {
+				return
+			}
+		}
+
+		if err := mq.EnqueueNextMonitors(ctx, db); err != nil {
 			fmt.Printf("scheduler error: %v\n", err)
 		}
+
+		if err := EvaluateMissedHeartbeats(ctx, db); err != nil {
+			fmt.Printf("heartbeat evaluation error: %v\n", err)
+		}
 	}
 }
@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// neverRuns parks a monitor whose cron expression matches no date, until its
+// schedule is changed.
+const neverRuns = 100 * 365 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule decides when a monitor runs next. A cron expression takes
+// precedence; otherwise the monitor runs every FrequencySecs inside its
+// window and every OffPeakFrequencySecs outside it, or not at all outside
+// the window when no off-peak frequency is set.
+type Schedule struct {
+	MonitorID            int
+	FrequencySecs        int
+	OffPeakFrequencySecs int
+	JitterPercent        int
+	Cron                 cron.Schedule
+	Location             *time.Location
+	Days                 map[time.Weekday]bool
+	WindowStart          int
+	WindowEnd            int
+}
+
+func ParseCron(expr string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cron expression %q: %v", ErrInvalidSchedule, expr, err)
+	}
+	return sched, nil
+}
+
+// MinCronInterval returns the shortest gap between the next samples ticks
+// of a cron schedule, which is how often it can fire at most.
+func MinCronInterval(sched cron.Schedule, from time.Time, samples int) time.Duration {
+	var shortest time.Duration
+
+	prev := sched.Next(from)
+	for i := 0; i < samples && !prev.IsZero(); i++ {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(prev); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		prev = next
+	}
+
+	return shortest
+}
+
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timezone %q: %v", ErrInvalidSchedule, name, err)
+	}
+	return loc, nil
+}
+
+func ParseWindowDays(s string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		day, ok := weekdays[part]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidSchedule, part)
+		}
+		days[day] = true
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%w: at least one window day is required", ErrInvalidSchedule)
+	}
+
+	return days, nil
+}
+
+// ParseClock parses an HH:MM time of day into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%w: time of day %q must be HH:MM", ErrInvalidSchedule, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (m *Monitor) Schedule() (*Schedule, error) {
+	s := &Schedule{
+		MonitorID:     m.ID,
+		FrequencySecs: m.FrequencySecs,
+		JitterPercent: m.JitterPercent,
+	}
+
+	loc, err := ParseTimezone(m.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	s.Location = loc
+
+	if m.CronExpression.Valid && m.CronExpression.String != "" {
+		s.Cron, err = ParseCron(m.CronExpression.String)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if m.WindowDays.Valid && m.WindowDays.String != "" {
+		if s.Days, err = ParseWindowDays(m.WindowDays.String); err != nil {
+			return nil, err
+		}
+		if s.WindowStart, err = ParseClock(m.WindowStart.String); err != nil {
+			return nil, err
+		}
+		if s.WindowEnd, err = ParseClock(m.WindowEnd.String); err != nil {
+			return nil, err
+		}
+		if s.WindowStart == s.WindowEnd {
+			return nil, fmt.Errorf("%w: window start and end must differ", ErrInvalidSchedule)
+		}
+	}
+
+	if m.OffPeakFrequencySecs.Valid {
+		s.OffPeakFrequencySecs = int(m.OffPeakFrequencySecs.Int64)
+	}
+
+	return s, nil
+}
+
+func (s *Schedule) hasWindow() bool {
+	return len(s.Days) > 0
+}
+
+// InWindow reports whether t falls inside the schedule's window. Windows that
+// end before they start run past midnight and belong to the day they start on.
+func (s *Schedule) InWindow(t time.Time) bool {
+	if !s.hasWindow() {
+		return true
+	}
+
+	t = t.In(s.Location)
+	minute := t.Hour()*60 + t.Minute()
+
+	if s.WindowStart < s.WindowEnd {
+		return s.Days[t.Weekday()] && minute >= s.WindowStart && minute < s.WindowEnd
+	}
+
+	yesterday := t.AddDate(0, 0, -1).Weekday()
+	return (s.Days[t.Weekday()] && minute >= s.WindowStart) || (s.Days[yesterday] && minute < s.WindowEnd)
+}
+
+func (s *Schedule) NextWindowStart(t time.Time) time.Time {
+	local := t.In(s.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		if !s.Days[day.Weekday()] {
+			continue
+		}
+
+		// Built from the wall clock, so the window opens at the same local
+		// time on days the clocks change.
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, s.WindowStart, 0, 0, s.Location)
+		if start.After(t) {
+			return start
+		}
+	}
+
+	return t.Add(24 * time.Hour)
+}
+
+// Next returns the delay from now until the monitor should run again.
+func (s *Schedule) Next(now time.Time) time.Duration {
+	if s.Cron != nil {
+		// Start a second later so a run claimed just before its slot does
+		// not get scheduled for that same slot again.
+		next := s.Cron.Next(now.Add(time.Second).In(s.Location))
+		if next.IsZero() {
+			return neverRuns
+		}
+		return max(next.Sub(now), time.Second)
+	}
+
+	if !s.hasWindow() {
+		return NextRunDelay(s.MonitorID, s.FrequencySecs, s.JitterPercent, now)
+	}
+
+	if s.InWindow(now) {
+		delay := NextRunDelay(s.MonitorID, s.FrequencySecs, s.JitterPercent, now)
+		if s.OffPeakFrequencySecs > 0 || s.InWindow(now.Add(delay)) {
+			return delay
+		}
+		return max(s.NextWindowStart(now).Sub(now), time.Second)
+	}
+
+	untilWindow := max(s.NextWindowStart(now).Sub(now), time.Second)
+	if s.OffPeakFrequencySecs > 0 {
+		return min(NextRunDelay(s.MonitorID, s.OffPeakFrequencySecs, s.JitterPercent, now), untilWindow)
+	}
+
+	return untilWindow
+}
+
+// FirstRun returns the delay before the first check of a monitor that was
+// just created, rescheduled or resumed: none, unless its schedule does not run
+// it now.
+func (s *Schedule) FirstRun(now time.Time) time.Duration {
+	switch {
+	case s.Cron != nil:
+		return s.Next(now)
+	case s.hasWindow() && s.OffPeakFrequencySecs == 0 && !s.InWindow(now):
+		return max(s.NextWindowStart(now).Sub(now), time.Second)
+	}
+	return 0
+}
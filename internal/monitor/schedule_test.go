@@ -0,0 +1,329 @@
+package monitor
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func windowMonitor(days, start, end string, offPeak int64) *Monitor {
+	return &Monitor{
+		ID:                   7,
+		FrequencySecs:        60,
+		Timezone:             "America/New_York",
+		WindowDays:           sql.NullString{String: days, Valid: true},
+		WindowStart:          sql.NullString{String: start, Valid: true},
+		WindowEnd:            sql.NullString{String: end, Valid: true},
+		OffPeakFrequencySecs: sql.NullInt64{Int64: offPeak, Valid: offPeak > 0},
+	}
+}
+
+func mustSchedule(t *testing.T, m *Monitor) *Schedule {
+	t.Helper()
+
+	s, err := m.Schedule()
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	return s
+}
+
+func TestParseWindowDays(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []time.Weekday
+		wantErr bool
+	}{
+		{in: "mon,tue,wed,thu,fri", want: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{in: " Sat , SUN ", want: []time.Weekday{time.Saturday, time.Sunday}},
+		{in: "mon,,mon", want: []time.Weekday{time.Monday}},
+		{in: "", wantErr: true},
+		{in: " , ", wantErr: true},
+		{in: "mon,funday", wantErr: true},
+		{in: "monday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		days, err := ParseWindowDays(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("ParseWindowDays(%q) error = %v, want ErrInvalidSchedule", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseWindowDays(%q) error = %v", tt.in, err)
+			continue
+		}
+		if len(days) != len(tt.want) {
+			t.Errorf("ParseWindowDays(%q) = %v, want %v", tt.in, days, tt.want)
+		}
+		for _, day := range tt.want {
+			if !days[day] {
+				t.Errorf("ParseWindowDays(%q) is missing %v", tt.in, day)
+			}
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "00:00", want: 0},
+		{in: "09:30", want: 570},
+		{in: " 23:59 ", want: 1439},
+		{in: "9am", wantErr: true},
+		{in: "24:00", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseClock(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("ParseClock(%q) error = %v, want ErrInvalidSchedule", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseClock(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMonitorSchedule(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          *Monitor
+		wantErr    bool
+		wantCron   bool
+		wantWindow bool
+	}{
+		{name: "plain frequency", m: &Monitor{FrequencySecs: 60}},
+		{name: "cron", m: &Monitor{CronExpression: sql.NullString{String: "*/5 * * * *", Valid: true}}, wantCron: true},
+		{name: "cron wins over a window", m: func() *Monitor {
+			m := windowMonitor("mon", "09:00", "17:00", 0)
+			m.CronExpression = sql.NullString{String: "0 9 * * *", Valid: true}
+			return m
+		}(), wantCron: true},
+		{name: "window", m: windowMonitor("mon,fri", "09:00", "17:00", 0), wantWindow: true},
+		{name: "empty window days", m: windowMonitor("", "09:00", "17:00", 0)},
+		{name: "null window days", m: &Monitor{FrequencySecs: 60, WindowStart: sql.NullString{String: "09:00", Valid: true}}},
+		{name: "bad cron", m: &Monitor{CronExpression: sql.NullString{String: "every minute", Valid: true}}, wantErr: true},
+		{name: "bad timezone", m: &Monitor{Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "bad window day", m: windowMonitor("funday", "09:00", "17:00", 0), wantErr: true},
+		{name: "bad window start", m: windowMonitor("mon", "9am", "17:00", 0), wantErr: true},
+		{name: "window without length", m: windowMonitor("mon", "09:00", "09:00", 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.m.Schedule()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSchedule) {
+					t.Fatalf("Schedule() error = %v, want ErrInvalidSchedule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Schedule() error = %v", err)
+			}
+			if got := s.Cron != nil; got != tt.wantCron {
+				t.Errorf("has cron = %v, want %v", got, tt.wantCron)
+			}
+			if got := s.hasWindow(); got != tt.wantWindow {
+				t.Errorf("has window = %v, want %v", got, tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	at := func(day, hour, minute int) time.Time {
+		// March 2026 starts on a Sunday.
+		return time.Date(2026, 3, day, hour, minute, 0, 0, ny)
+	}
+
+	office := mustSchedule(t, windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 0))
+	overnight := mustSchedule(t, windowMonitor("fri", "22:00", "02:00", 0))
+	always := mustSchedule(t, windowMonitor("", "09:00", "17:00", 0))
+
+	tests := []struct {
+		name string
+		s    *Schedule
+		t    time.Time
+		want bool
+	}{
+		{name: "office hours", s: office, t: at(2, 9, 0), want: true},
+		{name: "before office hours", s: office, t: at(2, 8, 59)},
+		{name: "end is exclusive", s: office, t: at(2, 17, 0)},
+		{name: "weekend", s: office, t: at(7, 12, 0)},
+		{name: "office hours in another zone", s: office, t: at(2, 9, 0).UTC(), want: true},
+		{name: "office hours on the day clocks change", s: office, t: at(9, 9, 30), want: true},
+		{name: "overnight before midnight", s: overnight, t: at(6, 23, 0), want: true},
+		{name: "overnight after midnight", s: overnight, t: at(7, 1, 59), want: true},
+		{name: "overnight after it ends", s: overnight, t: at(7, 2, 0)},
+		{name: "overnight starting on a day without a window", s: overnight, t: at(7, 22, 30)},
+		{name: "overnight after midnight of a day without a window", s: overnight, t: at(6, 1, 0)},
+		{name: "no window days", s: always, t: at(7, 3, 0), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.InWindow(tt.t); got != tt.want {
+				t.Errorf("InWindow(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWindowStart(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+
+	daily := mustSchedule(t, windowMonitor("sun,mon,tue,wed,thu,fri,sat", "09:00", "17:00", 0))
+	weekly := mustSchedule(t, windowMonitor("mon", "09:00", "17:00", 0))
+
+	tests := []struct {
+		name string
+		s    *Schedule
+		from time.Time
+		want time.Time
+	}{
+		{name: "later the same day", s: daily, from: time.Date(2026, 3, 2, 6, 0, 0, 0, ny), want: time.Date(2026, 3, 2, 9, 0, 0, 0, ny)},
+		{name: "next day", s: daily, from: time.Date(2026, 3, 2, 9, 0, 0, 0, ny), want: time.Date(2026, 3, 3, 9, 0, 0, 0, ny)},
+		{name: "across spring forward", s: daily, from: time.Date(2026, 3, 7, 18, 0, 0, 0, ny), want: time.Date(2026, 3, 8, 9, 0, 0, 0, ny)},
+		{name: "across fall back", s: daily, from: time.Date(2026, 10, 31, 18, 0, 0, 0, ny), want: time.Date(2026, 11, 1, 9, 0, 0, 0, ny)},
+		{name: "a week ahead", s: weekly, from: time.Date(2026, 3, 2, 10, 0, 0, 0, ny), want: time.Date(2026, 3, 9, 9, 0, 0, 0, ny)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.NextWindowStart(tt.from); !got.Equal(tt.want) {
+				t.Errorf("NextWindowStart(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextCron(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+
+	cronMonitor := func(expr string) *Schedule {
+		return mustSchedule(t, &Monitor{
+			ID:             7,
+			Timezone:       "America/New_York",
+			CronExpression: sql.NullString{String: expr, Valid: true},
+		})
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		now  time.Time
+		want time.Duration
+	}{
+		{name: "later today", expr: "0 9 * * *", now: time.Date(2026, 3, 2, 3, 0, 0, 0, ny), want: 6 * time.Hour},
+		// 09:00 to 09:00 is 23 hours when clocks spring forward and 25
+		// hours when they fall back.
+		{name: "across spring forward", expr: "0 9 * * *", now: time.Date(2026, 3, 7, 9, 0, 0, 0, ny), want: 23 * time.Hour},
+		{name: "across fall back", expr: "0 9 * * *", now: time.Date(2026, 10, 31, 9, 0, 0, 0, ny), want: 25 * time.Hour},
+		{name: "slot just claimed", expr: "*/5 * * * *", now: time.Date(2026, 3, 2, 10, 5, 0, 0, ny), want: 5 * time.Minute},
+		{name: "weekdays only", expr: "0 9 * * 1-5", now: time.Date(2026, 2, 6, 10, 0, 0, 0, ny), want: 71 * time.Hour},
+		{name: "never fires", expr: "0 0 30 2 *", now: time.Date(2026, 3, 2, 0, 0, 0, 0, ny), want: neverRuns},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := cronMonitor(tt.expr)
+			if got := s.Next(tt.now); got != tt.want {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+			if got := s.FirstRun(tt.now); got != tt.want {
+				t.Errorf("FirstRun(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextWindow(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, ny)
+	}
+
+	tests := []struct {
+		name     string
+		m        *Monitor
+		now      time.Time
+		min, max time.Duration
+		firstRun time.Duration
+	}{
+		{
+			name: "inside the window", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 0),
+			now: at(3, 2, 12, 0), min: time.Second, max: time.Minute,
+		},
+		{
+			name: "outside the window waits for it", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 0),
+			now: at(3, 2, 18, 0), min: 15 * time.Hour, max: 15 * time.Hour, firstRun: 15 * time.Hour,
+		},
+		{
+			name: "weekend waits for monday", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 0),
+			now: at(3, 7, 9, 0), min: 47 * time.Hour, max: 47 * time.Hour, firstRun: 47 * time.Hour,
+		},
+		{
+			name: "last run of the window", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 0),
+			now: at(3, 2, 16, 59).Add(30 * time.Second), min: 16 * time.Hour, max: 16*time.Hour + time.Minute,
+		},
+		{
+			name: "outside the window with off-peak", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 600),
+			now: at(3, 2, 18, 0), min: time.Second, max: 10 * time.Minute,
+		},
+		{
+			name: "off-peak does not overshoot the window", m: windowMonitor("mon,tue,wed,thu,fri", "09:00", "17:00", 3600),
+			now: at(3, 3, 8, 59), min: time.Second, max: time.Minute,
+		},
+		{
+			name: "overnight window after midnight", m: windowMonitor("fri", "22:00", "02:00", 0),
+			now: at(2, 7, 1, 0), min: time.Second, max: time.Minute,
+		},
+		{
+			name: "overnight window after it ends", m: windowMonitor("fri", "22:00", "02:00", 0),
+			now: at(2, 7, 2, 0), min: 6*24*time.Hour + 20*time.Hour, max: 6*24*time.Hour + 20*time.Hour, firstRun: 6*24*time.Hour + 20*time.Hour,
+		},
+		{
+			name: "waiting across spring forward", m: windowMonitor("sun", "09:00", "17:00", 0),
+			now: at(3, 7, 9, 0), min: 23 * time.Hour, max: 23 * time.Hour, firstRun: 23 * time.Hour,
+		},
+		{
+			name: "no window days", m: windowMonitor("", "09:00", "17:00", 0),
+			now: at(3, 7, 3, 0), min: time.Second, max: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mustSchedule(t, tt.m)
+			if got := s.Next(tt.now); got < tt.min || got > tt.max {
+				t.Errorf("Next(%v) = %v, want within [%v, %v]", tt.now, got, tt.min, tt.max)
+			}
+			if got := s.FirstRun(tt.now); got != tt.firstRun {
+				t.Errorf("FirstRun(%v) = %v, want %v", tt.now, got, tt.firstRun)
+			}
+		})
+	}
+}
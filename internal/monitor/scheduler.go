@@ -3,28 +3,64 @@ package monitor
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	db "github.com/dhruvthak3r/Probe/config"
+	resultq "github.com/dhruvthak3r/Probe/internal/mq"
+)
+
+const (
+	MonitorTypeHTTP      = "http"
+	MonitorTypeWebSocket = "websocket"
+	MonitorTypeSMTP      = "smtp"
+	MonitorTypeIMAP      = "imap"
+	MonitorTypePOP3      = "pop3"
+	MonitorTypeMySQL     = "mysql"
+	MonitorTypePostgres  = "postgres"
+	MonitorTypeRedis     = "redis"
+	MonitorTypeHeartbeat = "heartbeat"
+	MonitorTypeUDP       = "udp"
 )
 
 type Monitor struct {
-	ID                  int
-	Url                 string
-	FrequencySecs       int
-	LastRunAt           sql.NullTime
-	NextRunAt           sql.NullTime
-	ResponseFormat      string
-	HttpMethod          string
-	ConnectionTimeout   sql.NullInt64
-	RequestHeaders      map[string][]string
-	ResponseHeaders     map[string][]string
-	AcceptedStatusCodes []int
-	RequestBody         sql.NullString
+	ID                   int
+	Type                 string
+	Url                  string
+	FrequencySecs        int
+	LastRunAt            sql.NullTime
+	NextRunAt            sql.NullTime
+	ResponseFormat       string
+	HttpMethod           string
+	ConnectionTimeout    sql.NullInt64
+	RequestHeaders       map[string][]string
+	ResponseHeaders      map[string][]string
+	AcceptedStatusCodes  []int
+	RequestBody          sql.NullString
+	ResponsePattern      sql.NullString
+	ResponseSchema       sql.NullString
+	TLSMode              string
+	AuthUsername         sql.NullString
+	AuthPassword         sql.NullString
+	SkipTLSVerify        bool
+	RequiredCapabilities sql.NullString
+	ExpectedResult       sql.NullString
+	PayloadEncoding      string
+	JitterPercent        int
+	CronExpression       sql.NullString
+	Timezone             string
+	WindowDays           sql.NullString
+	WindowStart          sql.NullString
+	WindowEnd            sql.NullString
+	OffPeakFrequencySecs sql.NullInt64
+	Locations            []string
+	CycleID              string
+	InMaintenance        bool
 }
 
 type MonitorQueue struct {
-	UrlsToPoll chan *Monitor
+	Jobs *resultq.JobPublisher
 }
 
 func NewMonitor(ID int, Url string, FrequencySecs int, LastRunAt sql.NullTime, NextRunAt sql.NullTime, ResponseFormat string, RequestBody sql.NullString, HttpMethod string, ConnectionTimeout sql.NullInt64) *Monitor {
@@ -41,14 +77,14 @@ func NewMonitor(ID int, Url string, FrequencySecs int, LastRunAt sql.NullTime, N
 	}
 }
 
-func NewMonitorQueue() *MonitorQueue {
+func NewMonitorQueue(jobs *resultq.JobPublisher) *MonitorQueue {
 
 	return &MonitorQueue{
-		UrlsToPoll: make(chan *Monitor, 2000),
+		Jobs: jobs,
 	}
 }
 
-func (mq *MonitorQueue) EnqueueNextMonitorsToChan(ctx context.Context, db *db.DB) error {
+func (mq *MonitorQueue) EnqueueNextMonitors(ctx context.Context, db *db.DB) error {
 
 	tx, err := db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
 
@@ -72,7 +108,7 @@ func (mq *MonitorQueue) EnqueueNextMonitorsToChan(ctx context.Context, db *db.DB
 		placeholders[i] = "?"
 	}
 
-	err = UpdateMonitorStatus(ctx, tx, placeholders, ids)
+	err = UpdateMonitorStatus(ctx, tx, monitors, time.Now())
 	if err != nil {
 		return fmt.Errorf("updating monitor status failed: %w", err)
 	}
@@ -81,22 +117,42 @@ func (mq *MonitorQueue) EnqueueNextMonitorsToChan(ctx context.Context, db *db.DB
 		return err
 	}
 
-	requestheadersByMonitor, err := GetRequestHeadersForMonitor(ctx, db, ids, placeholders)
+	requestheadersByMonitor, err := GetRequestHeadersForMonitor(ctx, db.Pool, ids, placeholders)
 	if err != nil {
 		return fmt.Errorf("failed getting request headers..%w", err)
 	}
 
-	responseheadersByMonitor, err := GetResponseHeadersForMonitor(ctx, db, ids, placeholders)
+	responseheadersByMonitor, err := GetResponseHeadersForMonitor(ctx, db.Pool, ids, placeholders)
 	if err != nil {
 		return fmt.Errorf("failed getting response headers..%w", err)
 	}
 
-	acceptedCodesByMonitor, err := GetAcceptedStatusCodeForMonitor(ctx, db, ids, placeholders)
+	acceptedCodesByMonitor, err := GetAcceptedStatusCodeForMonitor(ctx, db.Pool, ids, placeholders)
 	if err != nil {
 		return fmt.Errorf("failed getting status codes..%w", err)
 	}
 
+	locationsByMonitor, err := GetLocationsForMonitor(ctx, db.Pool, ids, placeholders)
+	if err != nil {
+		return fmt.Errorf("failed getting locations..%w", err)
+	}
+
+	maintenanceByMonitor, err := MaintenanceForMonitors(ctx, db, ids, placeholders)
+	if err != nil {
+		return fmt.Errorf("failed getting maintenance windows..%w", err)
+	}
+
 	for _, m := range monitors {
+		switch maintenanceByMonitor[m.ID] {
+		case MaintenancePause:
+			if err := SetStatusToIdle(ctx, db, m); err != nil {
+				fmt.Printf("error releasing paused monitor_id=%d: %v\n", m.ID, err)
+			}
+			continue
+		case MaintenanceSuppress:
+			m.InMaintenance = true
+		}
+
 		m.RequestHeaders = requestheadersByMonitor[m.ID]
 		if m.RequestHeaders == nil {
 			m.RequestHeaders = map[string][]string{}
@@ -112,12 +168,59 @@ func (mq *MonitorQueue) EnqueueNextMonitorsToChan(ctx context.Context, db *db.DB
 			m.AcceptedStatusCodes = []int{200}
 		}
 
-		select {
-		case mq.UrlsToPoll <- m:
-		case <-ctx.Done():
-			return fmt.Errorf("oops error with enqueuing: %v\n", ctx.Err())
+		m.CycleID, err = NewCycleID()
+		if err != nil {
+			return fmt.Errorf("error generating cycle id: %w", err)
+		}
+
+		m.Locations = locationsByMonitor[m.ID]
+		if err := mq.DispatchJobs(ctx, db, m); err != nil {
+			fmt.Printf("error dispatching monitor_id=%d: %v\n", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (mq *MonitorQueue) DispatchJobs(ctx context.Context, db *db.DB, m *Monitor) error {
+	if mq.Jobs == nil {
+		return fmt.Errorf("no job publisher configured")
+	}
+
+	ttl := time.Duration(m.FrequencySecs) * time.Second
+
+	// A monitor whose jobs were not all routed stays running and is picked up
+	// again once it is stale.
+
+	if len(m.Locations) == 0 {
+		if err := mq.publishJob(ctx, ToJobMessage(*m, ""), resultq.DefaultJobsLocation, ttl); err != nil {
+			return err
+		}
+		return SetStatusToIdle(ctx, db, m)
+	}
+
+	for _, location := range m.Locations {
+		if err := mq.publishJob(ctx, ToJobMessage(*m, location), location, ttl); err != nil {
+			return err
 		}
 	}
 
+	return SetStatusToIdle(ctx, db, m)
+}
+
+func (mq *MonitorQueue) publishJob(ctx context.Context, job *resultq.JobMessage, route string, ttl time.Duration) error {
+	if ttl > 0 {
+		job.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("error marshalling job: %v", err)
+	}
+
+	if err := mq.Jobs.PublishJob(ctx, route, payload, ttl); err != nil {
+		return fmt.Errorf("error publishing job for location %s: %v", route, err)
+	}
+
 	return nil
 }
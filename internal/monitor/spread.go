@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+const MaxJitterPercent = 50
+
+func ClampJitter(percent int) int {
+	return min(max(percent, 0), MaxJitterPercent)
+}
+
+// PhaseOffset gives every monitor a fixed slot inside its interval, so
+// monitors created together do not keep firing in the same second.
+func PhaseOffset(monitorID int, frequencySecs int) int64 {
+	if frequencySecs <= 1 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(monitorID)))
+
+	return int64(h.Sum32() % uint32(frequencySecs))
+}
+
+// NextRunDelay returns how long until the monitor's next phase slot after now,
+// moved by up to jitterPercent of the interval in either direction.
+func NextRunDelay(monitorID int, frequencySecs int, jitterPercent int, now time.Time) time.Duration {
+	freq := int64(max(frequencySecs, 1))
+
+	elapsed := ((now.Unix()-PhaseOffset(monitorID, frequencySecs))%freq + freq) % freq
+	delay := freq - elapsed
+
+	if spread := freq * int64(ClampJitter(jitterPercent)) / 100; spread > 0 {
+		delay += rand.Int64N(2*spread+1) - spread
+	}
+
+	return time.Duration(max(delay, 1)) * time.Second
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClampJitter(t *testing.T) {
+	tests := []struct {
+		percent int
+		want    int
+	}{
+		{percent: -10, want: 0},
+		{percent: 0, want: 0},
+		{percent: 20, want: 20},
+		{percent: MaxJitterPercent, want: MaxJitterPercent},
+		{percent: 200, want: MaxJitterPercent},
+	}
+
+	for _, tt := range tests {
+		if got := ClampJitter(tt.percent); got != tt.want {
+			t.Errorf("ClampJitter(%d) = %d, want %d", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestPhaseOffset(t *testing.T) {
+	for _, freq := range []int{-1, 0, 1} {
+		if got := PhaseOffset(42, freq); got != 0 {
+			t.Errorf("PhaseOffset(42, %d) = %d, want 0", freq, got)
+		}
+	}
+
+	for id := 1; id <= 500; id++ {
+		got := PhaseOffset(id, 60)
+		if got < 0 || got >= 60 {
+			t.Fatalf("PhaseOffset(%d, 60) = %d, want within [0, 60)", id, got)
+		}
+		if again := PhaseOffset(id, 60); again != got {
+			t.Fatalf("PhaseOffset(%d, 60) = %d then %d, want a fixed slot", id, got, again)
+		}
+	}
+}
+
+func TestNextRunDelay(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		monitorID     int
+		frequencySecs int
+		jitterPercent int
+	}{
+		{name: "no jitter", monitorID: 7, frequencySecs: 60},
+		{name: "10 percent", monitorID: 7, frequencySecs: 60, jitterPercent: 10},
+		{name: "maximum jitter", monitorID: 11, frequencySecs: 300, jitterPercent: MaxJitterPercent},
+		{name: "jitter above the maximum", monitorID: 11, frequencySecs: 300, jitterPercent: 90},
+		{name: "negative jitter", monitorID: 3, frequencySecs: 30, jitterPercent: -20},
+		{name: "one second interval", monitorID: 5, frequencySecs: 1, jitterPercent: MaxJitterPercent},
+		{name: "zero interval", monitorID: 5, frequencySecs: 0, jitterPercent: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freq := int64(max(tt.frequencySecs, 1))
+			elapsed := ((now.Unix()-PhaseOffset(tt.monitorID, tt.frequencySecs))%freq + freq) % freq
+			base := freq - elapsed
+			spread := freq * int64(ClampJitter(tt.jitterPercent)) / 100
+
+			lowest := time.Duration(max(base-spread, 1)) * time.Second
+			highest := time.Duration(base+spread) * time.Second
+
+			for i := 0; i < 1000; i++ {
+				got := NextRunDelay(tt.monitorID, tt.frequencySecs, tt.jitterPercent, now)
+				if got <= 0 {
+					t.Fatalf("delay = %v, want positive", got)
+				}
+				if got < lowest || got > highest {
+					t.Fatalf("delay = %v, want within [%v, %v]", got, lowest, highest)
+				}
+				if spread == 0 && got != time.Duration(base)*time.Second {
+					t.Fatalf("delay = %v without jitter, want %v", got, time.Duration(base)*time.Second)
+				}
+			}
+		})
+	}
+}
+
+func TestNextRunDelayLandsOnPhaseSlot(t *testing.T) {
+	const id, freq = 9, 120
+	offset := PhaseOffset(id, freq)
+
+	for _, sec := range []int64{0, 1, 59, 119, 120, 3601} {
+		now := time.Unix(1_700_000_000+sec, 0)
+		next := now.Add(NextRunDelay(id, freq, 0, now))
+		if got := ((next.Unix()-offset)%freq + freq) % freq; got != 0 {
+			t.Errorf("now=%d: next run is %ds past the phase slot, want 0", now.Unix(), got)
+		}
+		if !next.After(now) {
+			t.Errorf("now=%d: next run %d is not after now", now.Unix(), next.Unix())
+		}
+	}
+}
+
+func TestNextRunDelayNeverBelowOneSecond(t *testing.T) {
+	const id, freq = 9, 120
+	// One second before the monitor's slot, so most jitter would land in
+	// the past.
+	now := time.Unix(1_700_000_000/freq*freq+PhaseOffset(id, freq)-1, 0)
+
+	clamped := 0
+	for i := 0; i < 1000; i++ {
+		got := NextRunDelay(id, freq, MaxJitterPercent, now)
+		if got < time.Second {
+			t.Fatalf("delay = %v, want at least 1s", got)
+		}
+		if got == time.Second {
+			clamped++
+		}
+	}
+	if clamped == 0 {
+		t.Errorf("no delay was clamped to 1s, want jitter to reach back past now")
+	}
+}
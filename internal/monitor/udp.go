@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	PayloadEncodingText = "text"
+	PayloadEncodingHex  = "hex"
+
+	udpMaxReplyBytes = 65535
+)
+
+func GetUDPResult(m Monitor) (*Result, error) {
+	host, port, err := SplitTarget(m.Url, "")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the udp target %v", err)
+	}
+
+	payload, err := decodePayload(m.RequestBody.String, m.PayloadEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding the udp payload %v", err)
+	}
+
+	timeout := connectionTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := &Result{
+		MonitorID:  m.ID,
+		MonitorUrl: m.Url,
+		Status:     "UP",
+	}
+
+	start := time.Now()
+
+	fail := func(reason string) (*Result, error) {
+		result.Status = "DOWN"
+		result.Reason = reason
+		result.ResponseTime = time.Since(start)
+		return result, nil
+	}
+
+	if err := Egress.CheckHost(host, port); err != nil {
+		return fail(err.Error())
+	}
+
+	ip, dnsTime, err := ResolveHost(ctx, host)
+	if err != nil {
+		return fail(fmt.Sprintf("dns lookup failed: %v", err))
+	}
+	result.ResolvedIp = ip
+	result.DNSResponseTime = dnsTime
+
+	dialer := Egress.Dialer(timeout)
+
+	connectStart := time.Now()
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(ip, port))
+	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return fail(reason)
+		}
+		return fail(fmt.Sprintf("error opening udp socket: %v", err))
+	}
+	defer conn.Close()
+	result.ConnectionTime = time.Since(connectStart)
+
+	conn.SetDeadline(start.Add(timeout))
+
+	sent := time.Now()
+	if _, err := conn.Write(payload); err != nil {
+		return fail(fmt.Sprintf("error sending udp payload: %v", err))
+	}
+
+	reply := make([]byte, udpMaxReplyBytes)
+	n, err := conn.Read(reply)
+	if err != nil {
+		if isTimeout(err) {
+			return fail("timed out waiting for udp reply")
+		}
+		return fail(fmt.Sprintf("error reading udp reply: %v", err))
+	}
+	reply = reply[:n]
+
+	result.RoundTripTime = time.Since(sent)
+
+	if reason := assertUDPReply(m, reply); reason != "" {
+		return fail(reason)
+	}
+
+	result.ResponseTime = time.Since(start)
+
+	return result, nil
+}
+
+func decodePayload(payload string, encoding string) ([]byte, error) {
+	if encoding != PayloadEncodingHex {
+		return []byte(payload), nil
+	}
+
+	cleaned := strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(payload)
+	cleaned = strings.TrimPrefix(strings.TrimPrefix(cleaned, "0x"), "0X")
+
+	return hex.DecodeString(cleaned)
+}
+
+func assertUDPReply(m Monitor, reply []byte) string {
+	if !m.ResponsePattern.Valid || m.ResponsePattern.String == "" {
+		return ""
+	}
+
+	if m.PayloadEncoding == PayloadEncodingHex {
+		expected, err := decodePayload(m.ResponsePattern.String, PayloadEncodingHex)
+		if err != nil {
+			return fmt.Sprintf("invalid expected reply bytes: %v", err)
+		}
+		if !bytes.Contains(reply, expected) {
+			return fmt.Sprintf("udp reply %x did not contain expected bytes %x", reply, expected)
+		}
+		return ""
+	}
+
+	matched, err := MatchResponsePattern(m.ResponsePattern.String, reply)
+	if err != nil {
+		return err.Error()
+	}
+	if !matched {
+		return "udp reply did not match expected pattern"
+	}
+
+	return ""
+}
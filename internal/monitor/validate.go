@@ -1,8 +1,11 @@
 package monitor
 
 import (
+	"fmt"
 	"net/http"
 	"net/textproto"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -47,3 +50,62 @@ func ValidateResponseHeaders(expected map[string][]string, respHeaders http.Head
 
 	return true
 }
+
+func MatchResponsePattern(pattern string, data []byte) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid response pattern: %w", err)
+	}
+
+	return re.Match(data), nil
+}
+
+func CompareScalar(value string, expected string) (bool, error) {
+	expected = strings.TrimSpace(expected)
+	value = strings.TrimSpace(value)
+
+	op := "="
+	for _, candidate := range []string{"<=", ">=", "!=", "<", ">", "="} {
+		if strings.HasPrefix(expected, candidate) {
+			op = candidate
+			expected = strings.TrimSpace(strings.TrimPrefix(expected, candidate))
+			break
+		}
+	}
+
+	actualNum, actualErr := strconv.ParseFloat(value, 64)
+	expectedNum, expectedErr := strconv.ParseFloat(expected, 64)
+	numeric := actualErr == nil && expectedErr == nil
+
+	switch op {
+	case "=":
+		if numeric {
+			return actualNum == expectedNum, nil
+		}
+		return value == expected, nil
+	case "!=":
+		if numeric {
+			return actualNum != expectedNum, nil
+		}
+		return value != expected, nil
+	}
+
+	if !numeric {
+		return false, fmt.Errorf("assertion %s %s requires numeric values, got %q", op, expected, value)
+	}
+
+	switch op {
+	case "<":
+		return actualNum < expectedNum, nil
+	case "<=":
+		return actualNum <= expectedNum, nil
+	case ">":
+		return actualNum > expectedNum, nil
+	default:
+		return actualNum >= expectedNum, nil
+	}
+}
@@ -0,0 +1,358 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+
+	wsAcceptGUID      = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessageBytes = 1 << 20
+)
+
+var errWebSocketClosed = errors.New("websocket closed by server")
+
+func GetWebSocketResult(m Monitor) (*Result, error) {
+
+	var (
+		dnsStart, dnsEnd         time.Time
+		connectStart, connectEnd time.Time
+		tlsStart, tlsEnd         time.Time
+		firstByte                time.Time
+		wroteRequest             time.Time
+		resolvedIP               string
+	)
+
+	timeout := connectionTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	trace := BuildTrace(&dnsStart, &dnsEnd, &resolvedIP, &connectStart, &connectEnd, &tlsStart, &tlsEnd, &wroteRequest, &firstByte)
+
+	key, err := newWebSocketKey()
+	if err != nil {
+		return nil, fmt.Errorf("error generating websocket key %v", err)
+	}
+
+	req, err := BuildWebSocketReq(ctx, m, key, trace)
+	if err != nil {
+		return nil, fmt.Errorf("error building the websocket request %v", err)
+	}
+
+	client := BuildClient(m)
+
+	start := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if reason, ok := EgressReason(err); ok {
+			return &Result{
+				MonitorID:  m.ID,
+				MonitorUrl: m.Url,
+				Status:     "DOWN",
+				Reason:     reason,
+			}, nil
+		}
+		if isTimeout(err) {
+			return &Result{
+				MonitorID:  m.ID,
+				MonitorUrl: m.Url,
+				Status:     "DOWN",
+				Reason:     "websocket handshake timed out",
+			}, nil
+		}
+		return nil, fmt.Errorf("error getting the response from client.Do %v", err)
+	}
+
+	handshakeEnd := time.Now()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return &Result{
+			MonitorID:  m.ID,
+			MonitorUrl: m.Url,
+			StatusCode: resp.StatusCode,
+			Status:     "DOWN",
+			Reason:     fmt.Sprintf("websocket handshake failed with status code %d", resp.StatusCode),
+		}, nil
+	}
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("upgraded response body is not writable")
+	}
+	defer conn.Close()
+
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return &Result{
+			MonitorID:  m.ID,
+			MonitorUrl: m.Url,
+			StatusCode: resp.StatusCode,
+			Status:     "DOWN",
+			Reason:     "websocket handshake returned an invalid Sec-WebSocket-Accept header",
+		}, nil
+	}
+
+	result := &Result{
+		MonitorID:  m.ID,
+		MonitorUrl: m.Url,
+		StatusCode: resp.StatusCode,
+		Status:     "UP",
+		ResolvedIp: resolvedIP,
+
+		DNSResponseTime:  durationOrZero(dnsStart, dnsEnd),
+		ConnectionTime:   durationOrZero(connectStart, connectEnd),
+		TLSHandshakeTime: durationOrZero(tlsStart, tlsEnd),
+		FirstByteTime:    durationOrZero(wroteRequest, firstByte),
+		HandshakeTime:    handshakeEnd.Sub(start),
+	}
+
+	message := m.RequestBody.Valid && m.RequestBody.String != ""
+	pattern := m.ResponsePattern.Valid && m.ResponsePattern.String != ""
+
+	if !message && !pattern {
+		writeWebSocketFrame(conn, wsOpClose, nil)
+		result.ResponseTime = time.Since(start)
+		return result, nil
+	}
+
+	var timedOut atomic.Bool
+	remaining := time.Until(start.Add(timeout))
+	if remaining <= 0 {
+		remaining = time.Millisecond
+	}
+	timer := time.AfterFunc(remaining, func() {
+		timedOut.Store(true)
+		conn.Close()
+	})
+	defer timer.Stop()
+
+	sent := time.Now()
+
+	if message {
+		if err := writeWebSocketFrame(conn, wsOpText, []byte(m.RequestBody.String)); err != nil {
+			result.Status = "DOWN"
+			result.Reason = fmt.Sprintf("error sending websocket message: %v", err)
+			return result, nil
+		}
+	}
+
+	br := bufio.NewReader(conn)
+
+	for {
+		data, err := readWebSocketMessage(br, conn)
+		if err != nil {
+			result.Status = "DOWN"
+			switch {
+			case timedOut.Load():
+				result.Reason = "timed out waiting for a matching websocket message"
+			case errors.Is(err, errWebSocketClosed):
+				result.Reason = "websocket closed by server before a matching message was received"
+			default:
+				result.Reason = fmt.Sprintf("error reading websocket message: %v", err)
+			}
+			return result, nil
+		}
+
+		matched, err := MatchResponsePattern(m.ResponsePattern.String, data)
+		if err != nil {
+			result.Status = "DOWN"
+			result.Reason = err.Error()
+			return result, nil
+		}
+
+		if matched {
+			break
+		}
+	}
+
+	end := time.Now()
+
+	writeWebSocketFrame(conn, wsOpClose, nil)
+
+	result.RoundTripTime = end.Sub(sent)
+	result.ResponseTime = end.Sub(start)
+
+	return result, nil
+}
+
+func BuildWebSocketReq(ctx context.Context, m Monitor, key string, trace *httptrace.ClientTrace) (*http.Request, error) {
+	u, err := url.Parse(m.Url)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the websocket url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported websocket url scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the request: %w", err)
+	}
+
+	if m.RequestHeaders != nil {
+		setRequestHeaders(m, req)
+	}
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	return req, nil
+}
+
+func newWebSocketKey() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(nonce), nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := w.Write(frame)
+	return err
+}
+
+func readWebSocketFrame(r io.Reader) (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > wsMaxMessageBytes {
+		return false, 0, nil, fmt.Errorf("websocket frame of %d bytes exceeds limit", length)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func readWebSocketMessage(r io.Reader, w io.Writer) ([]byte, error) {
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := readWebSocketFrame(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := writeWebSocketFrame(w, wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return nil, errWebSocketClosed
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			message = append(message, payload...)
+			if len(message) > wsMaxMessageBytes {
+				return nil, fmt.Errorf("websocket message exceeds %d bytes", wsMaxMessageBytes)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected websocket opcode %d", opcode)
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
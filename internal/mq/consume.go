@@ -2,10 +2,14 @@ package mq
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	db "github.com/dhruvthak3r/Probe/config"
+	"github.com/go-sql-driver/mysql"
 )
 
 func (c *Consumer) ConsumeFromQueue(ctx context.Context, db *db.DB) error {
@@ -29,7 +33,7 @@ func (c *Consumer) ConsumeFromQueue(ctx context.Context, db *db.DB) error {
 				return fmt.Errorf("failed to unmarshal message body: %v", err)
 			}
 
-			err = InsertResults(ctx, db, &res)
+			err = RecordResult(ctx, db, &res)
 			if err != nil {
 
 				nack := m.Nack(false, true)
@@ -52,10 +56,14 @@ func (c *Consumer) ConsumeFromQueue(ctx context.Context, db *db.DB) error {
 
 func InsertResults(ctx context.Context, db *db.DB, res *ResultMessage) error {
 
-	InsertQuery := `INSERT INTO results (monitor_id, status_code, status, dns_response_time, connection_time, tls_handshake_time, resolved_ip, first_byte_time, download_time, response_time, throughput, reason) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// Results take their org from the monitor, so a result for a monitor
+	// deleted while its check was running is dropped.
+	InsertQuery := `INSERT INTO results (monitor_id, status_code, status, dns_response_time, connection_time, tls_handshake_time, resolved_ip, first_byte_time, download_time, response_time, handshake_time, round_trip_time, throughput, reason, loc_id, cycle_id, in_maintenance, org_id)
+	SELECT m.monitor_id, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT loc_id FROM locations WHERE name = ?), ?, ?, m.org_id
+	FROM monitor m
+	WHERE m.monitor_id = ?`
 
 	values := []interface{}{
-		res.MonitorID,
 		res.StatusCode,
 		res.Status,
 		res.DNSResponseTime,
@@ -65,8 +73,14 @@ func InsertResults(ctx context.Context, db *db.DB, res *ResultMessage) error {
 		res.FirstByteTime,
 		res.DownloadTime,
 		res.ResponseTime,
+		res.HandshakeTime,
+		res.RoundTripTime,
 		res.Throughput,
 		res.Reason,
+		res.Location,
+		nullCycleID(res.CycleID),
+		res.InMaintenance,
+		res.MonitorID,
 	}
 	_, err := db.Pool.ExecContext(ctx, InsertQuery, values...)
 
@@ -77,3 +91,175 @@ func InsertResults(ctx context.Context, db *db.DB, res *ResultMessage) error {
 	return nil
 
 }
+
+func nullCycleID(cycleID string) sql.NullString {
+	return sql.NullString{String: cycleID, Valid: cycleID != ""}
+}
+
+const jobRetryDelay = time.Second
+
+// PermanentError marks a job failure that running the job again cannot fix.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (c *JobConsumer) ConsumeJobs(ctx context.Context, handle func(context.Context, *JobMessage) error) error {
+
+	mssgs, err := c.ch.Consume(c.queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("error consuming jobs from rabbitmq: %v", err)
+	}
+
+	for {
+		select {
+		case m, ok := <-mssgs:
+			if !ok {
+				return fmt.Errorf("job channel closed")
+			}
+
+			job := JobMessage{}
+
+			if err := json.Unmarshal(m.Body, &job); err != nil {
+				fmt.Printf("dropping malformed job: %v\n", err)
+				if nack := m.Nack(false, false); nack != nil {
+					fmt.Printf("error nacking job: %v", nack)
+				}
+				continue
+			}
+
+			if !job.ExpiresAt.IsZero() && time.Now().After(job.ExpiresAt) {
+				fmt.Printf("dropping stale job for monitor_id=%d cycle_id=%s\n", job.MonitorID, job.CycleID)
+				if ack := m.Ack(false); ack != nil {
+					fmt.Printf("error acknowledging job: %v", ack)
+				}
+				continue
+			}
+
+			if err := handle(ctx, &job); err != nil {
+				var permanent *PermanentError
+				if errors.As(err, &permanent) {
+					fmt.Printf("dropping job for monitor_id=%d: %v\n", job.MonitorID, err)
+					if nack := m.Nack(false, false); nack != nil {
+						fmt.Printf("error nacking job: %v", nack)
+					}
+					continue
+				}
+
+				// The job goes back on the queue until it expires, after a
+				// pause so a broken dependency is not retried in a tight loop.
+				fmt.Printf("error running job for monitor_id=%d, requeueing: %v\n", job.MonitorID, err)
+				select {
+				case <-time.After(jobRetryDelay):
+				case <-ctx.Done():
+				}
+				if nack := m.Nack(false, true); nack != nil {
+					fmt.Printf("error nacking job: %v", nack)
+				}
+				continue
+			}
+
+			if ack := m.Ack(false); ack != nil {
+				fmt.Printf("error acknowledging job: %v", ack)
+			}
+
+		case <-ctx.Done():
+			return fmt.Errorf("stopping job consumer %v", ctx.Err())
+		}
+	}
+}
+
+func (c *Consumer) ConsumeRegistrations(ctx context.Context, db *db.DB) error {
+
+	mssgs, err := c.ch.Consume(c.queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("error consuming registrations from rabbitmq: %v", err)
+	}
+
+	for {
+		select {
+		case m, ok := <-mssgs:
+			if !ok {
+				return fmt.Errorf("registration channel closed")
+			}
+
+			reg := AgentRegistration{}
+
+			if err := json.Unmarshal(m.Body, &reg); err != nil {
+				fmt.Printf("dropping malformed agent registration: %v\n", err)
+				if nack := m.Nack(false, false); nack != nil {
+					fmt.Printf("error nacking registration: %v", nack)
+				}
+				continue
+			}
+
+			if err := ValidateLocation(reg.Location); err != nil {
+				fmt.Printf("dropping agent registration from agent_id=%s: %v\n", reg.AgentID, err)
+				if nack := m.Nack(false, false); nack != nil {
+					fmt.Printf("error nacking registration: %v", nack)
+				}
+				continue
+			}
+
+			if err := UpsertLocation(ctx, db, &reg); err != nil {
+				if isPermanentDBError(err) {
+					fmt.Printf("dropping agent registration for location %s: %v\n", reg.Location, err)
+					if nack := m.Nack(false, false); nack != nil {
+						fmt.Printf("error nacking registration: %v", nack)
+					}
+					continue
+				}
+
+				if nack := m.Nack(false, true); nack != nil {
+					fmt.Printf("error nacking registration: %v", nack)
+				}
+				return err
+			}
+
+			if ack := m.Ack(false); ack != nil {
+				fmt.Printf("error acknowledging registration: %v", ack)
+			}
+
+		case <-ctx.Done():
+			return fmt.Errorf("stopping registration consumer %v", ctx.Err())
+		}
+	}
+}
+
+func UpsertLocation(ctx context.Context, db *db.DB, reg *AgentRegistration) error {
+
+	query := `INSERT INTO locations (name, last_agent_id, last_hostname, last_seen_at) VALUES (?, ?, ?, NOW())
+	ON DUPLICATE KEY UPDATE last_agent_id = VALUES(last_agent_id), last_hostname = VALUES(last_hostname), last_seen_at = NOW()`
+
+	_, err := db.Pool.ExecContext(ctx, query, reg.Location, reg.AgentID, reg.Hostname)
+	if err != nil {
+		return fmt.Errorf("error registering location %s: %w", reg.Location, err)
+	}
+
+	return nil
+}
+
+// isPermanentDBError reports whether MySQL rejected a statement for its data,
+// so retrying the message that produced it cannot succeed.
+func isPermanentDBError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1205, 1213: // lock wait timeout, deadlock
+		return false
+	}
+	return true
+}
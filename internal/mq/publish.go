@@ -3,6 +3,8 @@ package mq
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
@@ -19,8 +21,48 @@ type ResultMessage struct {
 	FirstByteTime    int64   `json:"first_byte_time_ms,omitempty"`
 	DownloadTime     int64   `json:"download_time_ms,omitempty"`
 	ResponseTime     int64   `json:"response_time_ms,omitempty"`
+	HandshakeTime    int64   `json:"handshake_time_ms,omitempty"`
+	RoundTripTime    int64   `json:"round_trip_time_ms,omitempty"`
 	Throughput       float64 `json:"throughput,omitempty"`
 	Reason           string  `json:"reason,omitempty"`
+	Location         string  `json:"location,omitempty"`
+	CycleID          string  `json:"cycle_id,omitempty"`
+	CycleSize        int     `json:"cycle_size,omitempty"`
+	InMaintenance    bool    `json:"in_maintenance,omitempty"`
+}
+
+type JobMessage struct {
+	MonitorID            int                 `json:"monitor_id"`
+	Type                 string              `json:"monitor_type"`
+	Url                  string              `json:"url"`
+	FrequencySecs        int                 `json:"frequency_secs"`
+	ResponseFormat       string              `json:"response_format"`
+	HttpMethod           string              `json:"http_method"`
+	ConnectionTimeout    *int64              `json:"connection_timeout,omitempty"`
+	RequestHeaders       map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders      map[string][]string `json:"response_headers,omitempty"`
+	AcceptedStatusCodes  []int               `json:"accepted_status_codes,omitempty"`
+	RequestBody          *string             `json:"request_body,omitempty"`
+	ResponsePattern      string              `json:"response_pattern,omitempty"`
+	ResponseSchema       string              `json:"response_schema,omitempty"`
+	TLSMode              string              `json:"tls_mode,omitempty"`
+	AuthUsername         string              `json:"auth_username,omitempty"`
+	AuthPassword         string              `json:"auth_password,omitempty"`
+	SkipTLSVerify        bool                `json:"skip_tls_verify,omitempty"`
+	RequiredCapabilities string              `json:"required_capabilities,omitempty"`
+	ExpectedResult       string              `json:"expected_result,omitempty"`
+	PayloadEncoding      string              `json:"payload_encoding,omitempty"`
+	Location             string              `json:"location"`
+	CycleID              string              `json:"cycle_id"`
+	CycleSize            int                 `json:"cycle_size"`
+	ExpiresAt            time.Time           `json:"expires_at,omitempty"`
+	InMaintenance        bool                `json:"in_maintenance,omitempty"`
+}
+
+type AgentRegistration struct {
+	Location string `json:"location"`
+	AgentID  string `json:"agent_id"`
+	Hostname string `json:"hostname"`
 }
 
 func (rmq *Publisher) PublishToQueue(ctx context.Context, payload []byte) error {
@@ -35,8 +77,50 @@ func (rmq *Publisher) PublishToQueue(ctx context.Context, payload []byte) error
 		return fmt.Errorf("error publishing message to rabbitmq: %v", err)
 	}
 
+	return waitForConfirm(ctx, rmq.confirms)
+}
+
+// PublishJob returns once RabbitMQ has confirmed the job and routed it to the
+// location's queue.
+func (p *JobPublisher) PublishJob(ctx context.Context, location string, payload []byte, ttl time.Duration) error {
+
+	if err := p.declareQueue(location); err != nil {
+		return err
+	}
+
+	msg := amqp091.Publishing{
+		ContentType:  "application/json",
+		Body:         payload,
+		DeliveryMode: amqp091.Persistent,
+		Timestamp:    time.Now(),
+	}
+	if ttl > 0 {
+		msg.Expiration = strconv.FormatInt(ttl.Milliseconds(), 10)
+	}
+
+	err := p.ch.PublishWithContext(ctx, JobsExchange, location, true, false, msg)
+
+	if err != nil {
+		return fmt.Errorf("error publishing job to rabbitmq: %v", err)
+	}
+
+	if err := waitForConfirm(ctx, p.confirms); err != nil {
+		return err
+	}
+
+	// RabbitMQ returns an unroutable mandatory message before confirming it.
+	select {
+	case ret := <-p.returns:
+		return fmt.Errorf("job for location %s was not routed: %s", location, ret.ReplyText)
+	default:
+	}
+
+	return nil
+}
+
+func waitForConfirm(ctx context.Context, confirms chan amqp091.Confirmation) error {
 	select {
-	case confirm := <-rmq.confirms:
+	case confirm := <-confirms:
 		if !confirm.Ack {
 			return fmt.Errorf("message not acknowledged by rabbitmq")
 		}
@@ -2,12 +2,22 @@ package mq
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	config "github.com/dhruvthak3r/Probe/config"
 	"github.com/rabbitmq/amqp091-go"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	ResultsQueue       = "monitor_results"
+	RegistrationsQueue = "agent_registrations"
+	JobsExchange       = "monitor_jobs"
+
+	DefaultJobsLocation = "default"
+)
+
 type Publisher struct {
 	ch       *amqp.Channel
 	queue    amqp.Queue
@@ -19,15 +29,78 @@ type Consumer struct {
 	queue amqp.Queue
 }
 
-func NewRabbitMQQueue(ch *amqp.Channel) (amqp.Queue, error) {
-	q, err := ch.QueueDeclare("monitor_results", true, false, false, false, nil)
+type JobPublisher struct {
+	ch       *amqp.Channel
+	confirms chan amqp091.Confirmation
+	returns  chan amqp091.Return
+	declared map[string]bool
+}
+
+type JobConsumer struct {
+	ch       *amqp.Channel
+	queue    amqp.Queue
+	location string
+}
+
+// maxLocationLength matches the locations.name column.
+const maxLocationLength = 64
+
+// Location names are routing keys and part of queue names.
+var locationPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func ValidateLocation(name string) error {
+	if strings.EqualFold(name, DefaultJobsLocation) {
+		return fmt.Errorf("location %q is reserved for probe's own workers", DefaultJobsLocation)
+	}
+	if len(name) > maxLocationLength {
+		return fmt.Errorf("location must be at most %d characters", maxLocationLength)
+	}
+	if !locationPattern.MatchString(name) {
+		return fmt.Errorf("location %q must start with a letter or digit and contain only letters, digits, dots, hyphens and underscores", name)
+	}
+	return nil
+}
+
+func NewRabbitMQQueue(ch *amqp.Channel, name string) (amqp.Queue, error) {
+	q, err := ch.QueueDeclare(name, true, false, false, false, nil)
 	if err != nil {
 		return amqp.Queue{}, fmt.Errorf("error declaring rabbitmq queue: %v", err)
 	}
 	return q, nil
 }
 
+func NewJobsExchange(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(JobsExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring rabbitmq exchange: %v", err)
+	}
+	return nil
+}
+
+func JobsQueueName(location string) string {
+	return JobsExchange + "." + location
+}
+
+// declareJobsQueue declares the durable queue of a location and binds it to
+// the jobs exchange. Publishers declare it too, so jobs wait for an agent that
+// has not started yet instead of being dropped by the exchange.
+func declareJobsQueue(ch *amqp.Channel, location string) (amqp.Queue, error) {
+	q, err := NewRabbitMQQueue(ch, JobsQueueName(location))
+	if err != nil {
+		return amqp.Queue{}, fmt.Errorf("error creating rabbitmq queue: %v", err)
+	}
+
+	if err := ch.QueueBind(q.Name, location, JobsExchange, false, nil); err != nil {
+		return amqp.Queue{}, fmt.Errorf("error binding rabbitmq queue: %v", err)
+	}
+
+	return q, nil
+}
+
 func NewRabbitMQPublisher(rmq config.RabbitMQ) (*Publisher, error) {
+	return NewQueuePublisher(rmq, ResultsQueue)
+}
+
+func NewQueuePublisher(rmq config.RabbitMQ, queueName string) (*Publisher, error) {
 	ch, err := rmq.NewRabbitMQChannel()
 	if err != nil {
 		return nil, fmt.Errorf("error creating rabbitmq channel: %v", err)
@@ -40,7 +113,7 @@ func NewRabbitMQPublisher(rmq config.RabbitMQ) (*Publisher, error) {
 
 	confirms := ch.NotifyPublish(make(chan amqp091.Confirmation, 1000))
 
-	q, err := NewRabbitMQQueue(ch)
+	q, err := NewRabbitMQQueue(ch, queueName)
 	if err != nil {
 		ch.Close()
 		return nil, fmt.Errorf("error creating rabbitmq queue: %v", err)
@@ -61,12 +134,16 @@ func (p *Publisher) Close() error {
 }
 
 func NewConsumer(rmq config.RabbitMQ) (*Consumer, error) {
+	return NewQueueConsumer(rmq, ResultsQueue)
+}
+
+func NewQueueConsumer(rmq config.RabbitMQ, queueName string) (*Consumer, error) {
 	ch, err := rmq.NewRabbitMQChannel()
 	if err != nil {
 		return nil, fmt.Errorf("error creating rabbitmq channel: %v", err)
 	}
 
-	q, err := NewRabbitMQQueue(ch)
+	q, err := NewRabbitMQQueue(ch, queueName)
 	if err != nil {
 		ch.Close()
 		return nil, fmt.Errorf("error creating rabbitmq queue: %v", err)
@@ -84,3 +161,97 @@ func (c *Consumer) Close() error {
 	}
 	return c.ch.Close()
 }
+
+func NewJobPublisher(rmq config.RabbitMQ) (*JobPublisher, error) {
+	ch, err := rmq.NewRabbitMQChannel()
+	if err != nil {
+		return nil, fmt.Errorf("error creating rabbitmq channel: %v", err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("error putting rabbitmq channel in confirm mode: %v", err)
+	}
+
+	confirms := ch.NotifyPublish(make(chan amqp091.Confirmation, 1000))
+	returns := ch.NotifyReturn(make(chan amqp091.Return, 1))
+
+	if err := NewJobsExchange(ch); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	p := &JobPublisher{
+		ch:       ch,
+		confirms: confirms,
+		returns:  returns,
+		declared: make(map[string]bool),
+	}
+
+	if err := p.declareQueue(DefaultJobsLocation); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *JobPublisher) declareQueue(location string) error {
+	if p.declared[location] {
+		return nil
+	}
+	if _, err := declareJobsQueue(p.ch, location); err != nil {
+		return err
+	}
+	p.declared[location] = true
+	return nil
+}
+
+func (p *JobPublisher) Close() error {
+	if p == nil || p.ch == nil {
+		return nil
+	}
+	return p.ch.Close()
+}
+
+func NewJobConsumer(rmq config.RabbitMQ, location string, prefetch int) (*JobConsumer, error) {
+	ch, err := rmq.NewRabbitMQChannel()
+	if err != nil {
+		return nil, fmt.Errorf("error creating rabbitmq channel: %v", err)
+	}
+
+	if err := NewJobsExchange(ch); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	q, err := declareJobsQueue(ch, location)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	if prefetch > 0 {
+		if err := ch.Qos(prefetch, 0, false); err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("error setting rabbitmq prefetch: %v", err)
+		}
+	}
+
+	return &JobConsumer{
+		ch:       ch,
+		queue:    q,
+		location: location,
+	}, nil
+}
+
+func (c *JobConsumer) Location() string {
+	return c.location
+}
+
+func (c *JobConsumer) Close() error {
+	if c == nil || c.ch == nil {
+		return nil
+	}
+	return c.ch.Close()
+}
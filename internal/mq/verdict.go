@@ -0,0 +1,119 @@
+package mq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	db "github.com/dhruvthak3r/Probe/config"
+)
+
+func RecordResult(ctx context.Context, db *db.DB, res *ResultMessage) error {
+	if err := InsertResults(ctx, db, res); err != nil {
+		return err
+	}
+
+	if err := markChecked(ctx, db, res); err != nil {
+		fmt.Printf("error updating current status for monitor_id=%d: %v\n", res.MonitorID, err)
+	}
+
+	if err := EvaluateVerdict(ctx, db, res); err != nil {
+		fmt.Printf("error evaluating verdict for monitor_id=%d cycle_id=%s: %v\n", res.MonitorID, res.CycleID, err)
+	}
+
+	return nil
+}
+
+// RecordManualResult stores the result of a check run on demand. It is not
+// part of a cycle, so the monitor's current status is left to the verdict
+// of its scheduled checks.
+func RecordManualResult(ctx context.Context, db *db.DB, res *ResultMessage) error {
+	if err := InsertResults(ctx, db, res); err != nil {
+		return err
+	}
+
+	if _, err := db.Pool.ExecContext(ctx, `UPDATE monitor SET last_checked_at = NOW() WHERE monitor_id = ?`, res.MonitorID); err != nil {
+		fmt.Printf("error updating last checked time for monitor_id=%d: %v\n", res.MonitorID, err)
+	}
+
+	return nil
+}
+
+func EvaluateVerdict(ctx context.Context, db *db.DB, res *ResultMessage) error {
+	if res.CycleID == "" || res.InMaintenance {
+		return nil
+	}
+
+	var quorum int
+	err := db.Pool.QueryRowContext(ctx, `SELECT quorum_failures FROM monitor WHERE monitor_id = ?`, res.MonitorID).Scan(&quorum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting quorum policy: %v", err)
+	}
+
+	total := res.CycleSize
+	if total < 1 {
+		total = 1
+	}
+	if quorum < 1 {
+		quorum = 1
+	}
+	if quorum > total {
+		quorum = total
+	}
+
+	var reported, failed int
+	countQuery := `SELECT COUNT(*), COALESCE(SUM(status = 'DOWN'), 0) FROM results WHERE monitor_id = ? AND cycle_id = ?`
+	if err := db.Pool.QueryRowContext(ctx, countQuery, res.MonitorID, res.CycleID).Scan(&reported, &failed); err != nil {
+		return fmt.Errorf("error counting cycle results: %v", err)
+	}
+
+	var status, reason string
+	switch {
+	case failed >= quorum:
+		status = "DOWN"
+		reason = fmt.Sprintf("%d of %d locations failed, quorum is %d", failed, total, quorum)
+		if total == 1 {
+			reason = res.Reason
+		}
+	case reported-failed > total-quorum:
+		status = "UP"
+		reason = fmt.Sprintf("%d of %d locations failed, quorum is %d", failed, total, quorum)
+	default:
+		return nil
+	}
+
+	upsert := `INSERT INTO monitor_verdicts (monitor_id, cycle_id, status, failed_locations, reported_locations, total_locations, quorum, reason)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE failed_locations = VALUES(failed_locations), reported_locations = VALUES(reported_locations)`
+
+	stored, err := db.Pool.ExecContext(ctx, upsert, res.MonitorID, res.CycleID, status, failed, reported, total, quorum, reason)
+	if err != nil {
+		return fmt.Errorf("error storing verdict: %v", err)
+	}
+
+	// Only the first verdict of a cycle is kept, so only it moves the
+	// monitor's current status.
+	if inserted, err := stored.RowsAffected(); err == nil && inserted == 1 {
+		if _, err := db.Pool.ExecContext(ctx, `UPDATE monitor SET current_status = ? WHERE monitor_id = ?`, status, res.MonitorID); err != nil {
+			return fmt.Errorf("error updating current status: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// markChecked records when the monitor was last checked. Results that are
+// part of a cycle leave the current status to the cycle's verdict, and
+// results taken during maintenance do not change it.
+func markChecked(ctx context.Context, db *db.DB, res *ResultMessage) error {
+	if res.CycleID != "" || res.InMaintenance {
+		_, err := db.Pool.ExecContext(ctx, `UPDATE monitor SET last_checked_at = NOW() WHERE monitor_id = ?`, res.MonitorID)
+		return err
+	}
+	_, err := db.Pool.ExecContext(ctx, `UPDATE monitor SET last_checked_at = NOW(), current_status = ? WHERE monitor_id = ?`, res.Status, res.MonitorID)
+	return err
+}
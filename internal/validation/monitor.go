@@ -0,0 +1,349 @@
+package validation
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruvthak3r/Probe/internal/monitor"
+)
+
+var (
+	MonitorTypes     = []string{monitor.MonitorTypeHTTP, monitor.MonitorTypeWebSocket, monitor.MonitorTypeSMTP, monitor.MonitorTypeIMAP, monitor.MonitorTypePOP3, monitor.MonitorTypeMySQL, monitor.MonitorTypePostgres, monitor.MonitorTypeRedis, monitor.MonitorTypeHeartbeat, monitor.MonitorTypeUDP}
+	HTTPMethods      = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
+	ResponseFormats  = []string{"string", "json"}
+	TLSModes         = []string{monitor.TLSModeNone, monitor.TLSModeStartTLS, monitor.TLSModeImplicit}
+	PayloadEncodings = []string{monitor.PayloadEncodingText, monitor.PayloadEncodingHex}
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type BusinessHours struct {
+	Days  []string
+	Start string
+	End   string
+}
+
+// Monitor holds the monitor fields shared by create and update. A nil field
+// was not sent; on create, required fields must not be nil.
+type Monitor struct {
+	Name                 *string
+	Url                  *string
+	FrequencySecs        *int
+	ResponseFormat       *string
+	HttpMethod           *string
+	ConnectionTimeout    *int
+	RequestHeaders       *map[string][]string
+	ResponseHeaders      *map[string][]string
+	AcceptedStatusCodes  *[]int
+	RequestBody          *string
+	MonitorType          *string
+	ResponsePattern      *string
+	ResponseSchema       *string
+	TLSMode              *string
+	AuthUsername         *string
+	AuthPassword         *string
+	RequiredCapabilities *[]string
+	ExpectedResult       *string
+	GraceSeconds         *int
+	PayloadEncoding      *string
+	Locations            *[]string
+	QuorumFailures       *int
+	JitterPercent        *int
+	CronExpression       *string
+	Timezone             *string
+	BusinessHours        *BusinessHours
+	OffPeakFrequencySecs *int
+	Tags                 *[]string
+	Group                *string
+	Slug                 *string
+}
+
+// ValidateMonitor checks a monitor payload. With partial set, as for updates,
+// only the fields present are checked.
+func ValidateMonitor(m Monitor, partial bool) error {
+	var errs Errors
+
+	monitorType := ""
+	if m.MonitorType != nil {
+		monitorType = *m.MonitorType
+		errs.Enum("monitor_type", monitorType, MonitorTypes...)
+	} else if !partial {
+		monitorType = monitor.MonitorTypeHTTP
+	}
+
+	if m.Name == nil && !partial {
+		errs.Add("name", "is required")
+	} else if m.Name != nil {
+		if strings.TrimSpace(*m.Name) == "" {
+			errs.Add("name", "must not be empty")
+		}
+		errs.MaxLen("name", *m.Name, 255)
+	}
+
+	// On create an empty slug is generated from the name.
+	if m.Slug != nil && (*m.Slug != "" || partial) {
+		if !slugPattern.MatchString(*m.Slug) {
+			errs.Add("slug", "must be lowercase letters, digits and single hyphens")
+		}
+		errs.MaxLen("slug", *m.Slug, 100)
+	}
+
+	// Heartbeat monitors are given a generated ping path instead of a url.
+	if monitorType != monitor.MonitorTypeHeartbeat {
+		if m.Url == nil && !partial {
+			errs.Add("url", "is required")
+		} else if m.Url != nil {
+			errs.target("url", monitorType, *m.Url)
+		}
+	}
+
+	if m.FrequencySecs == nil && !partial {
+		errs.Add("frequency_secs", "is required")
+	} else if m.FrequencySecs != nil {
+		errs.Min("frequency_secs", *m.FrequencySecs, 1)
+	}
+
+	if m.ResponseFormat != nil {
+		errs.Enum("response_format", *m.ResponseFormat, ResponseFormats...)
+	}
+	if m.HttpMethod != nil {
+		errs.Enum("http_method", strings.ToUpper(*m.HttpMethod), HTTPMethods...)
+	}
+	if m.ConnectionTimeout != nil {
+		errs.Min("connection_timeout", *m.ConnectionTimeout, 0)
+	}
+	if m.RequestHeaders != nil {
+		errs.headers("request_headers", *m.RequestHeaders)
+	}
+	if m.ResponseHeaders != nil {
+		errs.headers("response_headers", *m.ResponseHeaders)
+	}
+
+	if m.AcceptedStatusCodes != nil {
+		for i, code := range *m.AcceptedStatusCodes {
+			errs.Range(fmt.Sprintf("accepted_status_codes[%d]", i), code, 100, 599)
+		}
+	}
+
+	if m.ResponsePattern != nil {
+		errs.MaxLen("response_pattern", *m.ResponsePattern, 1024)
+		if _, err := regexp.Compile(*m.ResponsePattern); err != nil {
+			errs.Add("response_pattern", "must be a valid regular expression: %v", err)
+		}
+	}
+
+	if m.ResponseSchema != nil && *m.ResponseSchema != "" {
+		errs.MaxLen("response_schema", *m.ResponseSchema, 1<<20)
+		if _, err := monitor.ParseResponseSchema(*m.ResponseSchema); err != nil {
+			errs.Add("response_schema", "must be a valid schema: %v", err)
+		}
+	}
+
+	if m.TLSMode != nil {
+		errs.Enum("tls_mode", *m.TLSMode, TLSModes...)
+	}
+	if m.AuthUsername != nil {
+		errs.MaxLen("auth_username", *m.AuthUsername, 255)
+	}
+	if m.AuthPassword != nil {
+		errs.MaxLen("auth_password", *m.AuthPassword, 1024)
+	}
+
+	if m.RequiredCapabilities != nil {
+		for i, c := range *m.RequiredCapabilities {
+			if strings.TrimSpace(c) == "" || strings.Contains(c, ",") {
+				errs.Add(fmt.Sprintf("required_capabilities[%d]", i), "must be a non-empty capability without commas")
+			}
+		}
+		errs.MaxLen("required_capabilities", strings.Join(*m.RequiredCapabilities, ","), 1024)
+	}
+
+	if m.ExpectedResult != nil {
+		errs.MaxLen("expected_result", *m.ExpectedResult, 255)
+	}
+	if m.GraceSeconds != nil {
+		errs.Min("grace_seconds", *m.GraceSeconds, 0)
+	}
+
+	if m.PayloadEncoding != nil {
+		errs.Enum("payload_encoding", *m.PayloadEncoding, PayloadEncodings...)
+		if *m.PayloadEncoding == monitor.PayloadEncodingHex && m.RequestBody != nil {
+			if _, err := hex.DecodeString(strings.Join(strings.Fields(*m.RequestBody), "")); err != nil {
+				errs.Add("request_body", "must be hex encoded when payload_encoding is hex")
+			}
+		}
+	}
+
+	if m.Locations != nil {
+		for i, loc := range *m.Locations {
+			if strings.TrimSpace(loc) == "" {
+				errs.Add(fmt.Sprintf("locations[%d]", i), "must not be empty")
+			}
+		}
+	}
+
+	if m.QuorumFailures != nil {
+		errs.Min("quorum_failures", *m.QuorumFailures, 0)
+		if m.Locations != nil && len(*m.Locations) > 0 && *m.QuorumFailures > len(*m.Locations) {
+			errs.Add("quorum_failures", "must not exceed the number of locations")
+		}
+	}
+
+	if m.JitterPercent != nil {
+		errs.Range("jitter_percent", *m.JitterPercent, 0, monitor.MaxJitterPercent)
+	}
+
+	errs.schedule(m)
+
+	if m.OffPeakFrequencySecs != nil {
+		errs.Min("off_peak_frequency_secs", *m.OffPeakFrequencySecs, 0)
+	}
+
+	if m.Tags != nil {
+		for i, tag := range *m.Tags {
+			field := fmt.Sprintf("tags[%d]", i)
+			key, value := monitor.ParseTag(tag)
+			if key == "" {
+				errs.Add(field, "must be key or key:value")
+				continue
+			}
+			errs.MaxLen(field, key, 64)
+			errs.MaxLen(field, value, 255)
+		}
+	}
+
+	if m.Group != nil {
+		errs.MaxLen("group", monitor.NormalizeGroup(*m.Group), 255)
+	}
+
+	return errs.Err()
+}
+
+func (e *Errors) target(field string, monitorType string, raw string) {
+	if strings.TrimSpace(raw) == "" {
+		e.Add(field, "must not be empty")
+		return
+	}
+	e.MaxLen(field, raw, 2048)
+
+	switch monitorType {
+	case monitor.MonitorTypeHTTP:
+		e.absoluteURL(field, raw, "http", "https")
+	case monitor.MonitorTypeWebSocket:
+		e.absoluteURL(field, raw, "ws", "wss")
+	case monitor.MonitorTypeUDP:
+		e.hostPort(field, raw, "")
+	case "":
+		// Without a type, only urls that can not be parsed at all are
+		// rejected. Updates pass the stored type.
+		if strings.Contains(raw, "://") {
+			e.absoluteURL(field, raw)
+		}
+	default:
+		// Mail and database checks fall back to their well-known port.
+		e.hostPort(field, raw, "0")
+	}
+}
+
+func (e *Errors) absoluteURL(field string, raw string, schemes ...string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (len(schemes) > 0 && !OneOf(strings.ToLower(u.Scheme), schemes...)) {
+		if len(schemes) == 0 {
+			e.Add(field, "must be an absolute url")
+			return
+		}
+		e.Add(field, "must be an absolute %s url", strings.Join(schemes, " or "))
+	}
+}
+
+func (e *Errors) hostPort(field string, raw string, defaultPort string) {
+	_, port, err := monitor.SplitTarget(raw, defaultPort)
+	if err != nil {
+		if defaultPort == "" {
+			e.Add(field, "must be host:port")
+			return
+		}
+		e.Add(field, "must be a host, host:port or url")
+		return
+	}
+
+	if port == defaultPort {
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		e.Add(field, "port must be between 1 and 65535")
+	}
+}
+
+func (e *Errors) headers(field string, headers map[string][]string) {
+	for name, values := range headers {
+		if !validHeaderName(name) {
+			e.Add(field, "%q is not a valid header name", name)
+		}
+		for _, v := range values {
+			if strings.ContainsAny(v, "\r\n") {
+				e.Add(field, "value of %q must not contain line breaks", name)
+			}
+		}
+	}
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Errors) schedule(m Monitor) {
+	if m.CronExpression != nil && *m.CronExpression != "" {
+		if sched, err := monitor.ParseCron(*m.CronExpression); err != nil {
+			e.Add("cron_expression", "%s", scheduleMessage(err))
+		} else if sched.Next(time.Now()).IsZero() {
+			e.Add("cron_expression", "does not match any date in the next five years")
+		}
+	}
+
+	if m.Timezone != nil {
+		if _, err := monitor.ParseTimezone(*m.Timezone); err != nil {
+			e.Add("timezone", "%s", scheduleMessage(err))
+		}
+	}
+
+	hours := m.BusinessHours
+	if hours == nil || len(hours.Days) == 0 {
+		return
+	}
+
+	if _, err := monitor.ParseWindowDays(strings.Join(hours.Days, ",")); err != nil {
+		e.Add("business_hours.days", "%s", scheduleMessage(err))
+	}
+	start, startErr := monitor.ParseClock(hours.Start)
+	if startErr != nil {
+		e.Add("business_hours.start", "%s", scheduleMessage(startErr))
+	}
+	end, endErr := monitor.ParseClock(hours.End)
+	if endErr != nil {
+		e.Add("business_hours.end", "%s", scheduleMessage(endErr))
+	}
+	if startErr == nil && endErr == nil && start == end {
+		e.Add("business_hours.end", "must differ from start")
+	}
+}
+
+func scheduleMessage(err error) string {
+	return strings.TrimPrefix(err.Error(), monitor.ErrInvalidSchedule.Error()+": ")
+}
@@ -0,0 +1,139 @@
+package validation
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func validCreate() Monitor {
+	return Monitor{
+		Name:          ptr("api"),
+		Url:           ptr("https://api.example.com/health"),
+		FrequencySecs: ptr(60),
+	}
+}
+
+func errorFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	var verrs Errors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("error %v is not validation.Errors", err)
+	}
+
+	fields := make([]string, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = fe.Field
+	}
+	return fields
+}
+
+func TestValidateMonitorCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *Monitor)
+		want   []string
+	}{
+		{name: "valid", modify: func(m *Monitor) {}},
+		{name: "missing required fields", modify: func(m *Monitor) { *m = Monitor{} }, want: []string{"name", "url", "frequency_secs"}},
+		{name: "heartbeat without url", modify: func(m *Monitor) { m.MonitorType = ptr("heartbeat"); m.Url = nil }},
+		{name: "unparseable url", modify: func(m *Monitor) { m.Url = ptr("garbage") }, want: []string{"url"}},
+		{name: "relative url", modify: func(m *Monitor) { m.Url = ptr("/health") }, want: []string{"url"}},
+		{name: "wrong scheme", modify: func(m *Monitor) { m.Url = ptr("ftp://api.example.com") }, want: []string{"url"}},
+		{name: "websocket with http url", modify: func(m *Monitor) { m.MonitorType = ptr("websocket") }, want: []string{"url"}},
+		{name: "udp without port", modify: func(m *Monitor) { m.MonitorType = ptr("udp"); m.Url = ptr("dns.example.com") }, want: []string{"url"}},
+		{name: "smtp host", modify: func(m *Monitor) { m.MonitorType = ptr("smtp"); m.Url = ptr("mail.example.com") }},
+		{name: "unknown monitor type", modify: func(m *Monitor) { m.MonitorType = ptr("gopher") }, want: []string{"monitor_type"}},
+		{name: "http method outside the enum", modify: func(m *Monitor) { m.HttpMethod = ptr("FETCH") }, want: []string{"http_method"}},
+		{name: "lowercase http method", modify: func(m *Monitor) { m.HttpMethod = ptr("post") }},
+		{name: "frequency 0", modify: func(m *Monitor) { m.FrequencySecs = ptr(0) }, want: []string{"frequency_secs"}},
+		{name: "negative connection timeout", modify: func(m *Monitor) { m.ConnectionTimeout = ptr(-1) }, want: []string{"connection_timeout"}},
+		{name: "status code 999", modify: func(m *Monitor) { m.AcceptedStatusCodes = ptr([]int{200, 999}) }, want: []string{"accepted_status_codes[1]"}},
+		{name: "bad request header name", modify: func(m *Monitor) { m.RequestHeaders = ptr(map[string][]string{"Bad Header": {"x"}}) }, want: []string{"request_headers"}},
+		{name: "header value with line break", modify: func(m *Monitor) {
+			m.ResponseHeaders = ptr(map[string][]string{"X-Ok": {"a\r\nInjected: b"}})
+		}, want: []string{"response_headers"}},
+		{name: "bad cron", modify: func(m *Monitor) { m.CronExpression = ptr("every minute") }, want: []string{"cron_expression"}},
+		{name: "cron that never fires", modify: func(m *Monitor) { m.CronExpression = ptr("0 0 30 2 *") }, want: []string{"cron_expression"}},
+		{name: "bad timezone", modify: func(m *Monitor) { m.Timezone = ptr("Mars/Olympus") }, want: []string{"timezone"}},
+		{name: "bad business hours", modify: func(m *Monitor) {
+			m.BusinessHours = &BusinessHours{Days: []string{"mon", "funday"}, Start: "9am", End: "17:00"}
+		}, want: []string{"business_hours.days", "business_hours.start"}},
+		{name: "hex body that does not decode", modify: func(m *Monitor) {
+			m.MonitorType = ptr("udp")
+			m.Url = ptr("10.0.0.1:53")
+			m.PayloadEncoding = ptr("hex")
+			m.RequestBody = ptr("zz 01")
+		}, want: []string{"request_body"}},
+		{name: "hex body with spaces", modify: func(m *Monitor) {
+			m.MonitorType = ptr("udp")
+			m.Url = ptr("10.0.0.1:53")
+			m.PayloadEncoding = ptr("hex")
+			m.RequestBody = ptr("de ad be ef")
+		}},
+		{name: "invalid response pattern", modify: func(m *Monitor) { m.ResponsePattern = ptr("(") }, want: []string{"response_pattern"}},
+		{name: "quorum above locations", modify: func(m *Monitor) {
+			m.Locations = ptr([]string{"eu-west"})
+			m.QuorumFailures = ptr(2)
+		}, want: []string{"quorum_failures"}},
+		{name: "bad slug", modify: func(m *Monitor) { m.Slug = ptr("Not A Slug") }, want: []string{"slug"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := validCreate()
+			tt.modify(&m)
+
+			got := errorFields(t, ValidateMonitor(m, false))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("error fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMonitorUpdate(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Monitor
+		want []string
+	}{
+		{name: "nothing sent", m: Monitor{}},
+		{name: "name only", m: Monitor{Name: ptr("renamed")}},
+		{name: "empty name", m: Monitor{Name: ptr(" ")}, want: []string{"name"}},
+		{name: "empty slug", m: Monitor{Slug: ptr("")}, want: []string{"slug"}},
+		{name: "unparseable url of an http monitor", m: Monitor{MonitorType: ptr("http"), Url: ptr("garbage")}, want: []string{"url"}},
+		{name: "relative url of an http monitor", m: Monitor{MonitorType: ptr("http"), Url: ptr("/health")}, want: []string{"url"}},
+		{name: "ftp url of an http monitor", m: Monitor{MonitorType: ptr("http"), Url: ptr("ftp://x")}, want: []string{"url"}},
+		{name: "switch to websocket keeping an http url", m: Monitor{MonitorType: ptr("websocket"), Url: ptr("http://api.example.com")}, want: []string{"url"}},
+		{name: "switch to heartbeat keeping the stored url", m: Monitor{MonitorType: ptr("heartbeat"), Url: ptr("https://api.example.com")}},
+		{name: "switch from heartbeat keeping the ping path", m: Monitor{MonitorType: ptr("http"), Url: ptr("/heartbeat/abc")}, want: []string{"url"}},
+		{name: "url without a type", m: Monitor{Url: ptr("ftp//broken://")}, want: []string{"url"}},
+		{name: "http method outside the enum", m: Monitor{HttpMethod: ptr("FETCH")}, want: []string{"http_method"}},
+		{name: "frequency 0", m: Monitor{FrequencySecs: ptr(0)}, want: []string{"frequency_secs"}},
+		{name: "negative connection timeout", m: Monitor{ConnectionTimeout: ptr(-5)}, want: []string{"connection_timeout"}},
+		{name: "status code 999", m: Monitor{AcceptedStatusCodes: ptr([]int{999})}, want: []string{"accepted_status_codes[0]"}},
+		{name: "bad header name", m: Monitor{RequestHeaders: ptr(map[string][]string{"X(Bad)": {"1"}})}, want: []string{"request_headers"}},
+		{name: "bad cron", m: Monitor{CronExpression: ptr("61 * * * *")}, want: []string{"cron_expression"}},
+		{name: "removing cron", m: Monitor{CronExpression: ptr("")}},
+		{name: "bad timezone", m: Monitor{Timezone: ptr("Europe/Nowhere")}, want: []string{"timezone"}},
+		{name: "hex body that does not decode", m: Monitor{PayloadEncoding: ptr("hex"), RequestBody: ptr("0g")}, want: []string{"request_body"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorFields(t, ValidateMonitor(tt.m, true))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("error fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
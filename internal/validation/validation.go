@@ -0,0 +1,68 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors collects every problem found in a payload, so clients can fix them
+// all at once instead of one request at a time.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *Errors) Add(field string, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns nil when nothing was added, so callers can return it directly.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func OneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Errors) MaxLen(field string, value string, max int) {
+	if len(value) > max {
+		e.Add(field, "must be at most %d characters", max)
+	}
+}
+
+func (e *Errors) Range(field string, value int, min int, max int) {
+	if value < min || value > max {
+		e.Add(field, "must be between %d and %d", min, max)
+	}
+}
+
+func (e *Errors) Min(field string, value int, min int) {
+	if value < min {
+		e.Add(field, "must be at least %d", min)
+	}
+}
+
+func (e *Errors) Enum(field string, value string, allowed ...string) {
+	if !OneOf(value, allowed...) {
+		e.Add(field, "must be one of %s", strings.Join(allowed, ", "))
+	}
+}
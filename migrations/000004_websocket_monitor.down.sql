@@ -0,0 +1,7 @@
+ALTER TABLE results
+DROP COLUMN round_trip_time,
+DROP COLUMN handshake_time;
+
+ALTER TABLE monitor
+DROP COLUMN response_pattern,
+DROP COLUMN monitor_type;
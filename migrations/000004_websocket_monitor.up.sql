@@ -0,0 +1,7 @@
+ALTER TABLE monitor
+ADD COLUMN monitor_type enum('http','websocket') NOT NULL DEFAULT 'http',
+ADD COLUMN response_pattern varchar(1024) DEFAULT NULL;
+
+ALTER TABLE results
+ADD COLUMN handshake_time bigint NOT NULL DEFAULT 0,
+ADD COLUMN round_trip_time bigint NOT NULL DEFAULT 0;
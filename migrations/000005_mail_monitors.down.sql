@@ -0,0 +1,6 @@
+ALTER TABLE monitor
+DROP COLUMN required_capabilities,
+DROP COLUMN auth_password,
+DROP COLUMN auth_username,
+DROP COLUMN tls_mode,
+MODIFY monitor_type enum('http','websocket') NOT NULL DEFAULT 'http';
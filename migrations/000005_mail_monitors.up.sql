@@ -0,0 +1,6 @@
+ALTER TABLE monitor
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3') NOT NULL DEFAULT 'http',
+ADD COLUMN tls_mode enum('none','starttls','implicit') NOT NULL DEFAULT 'none',
+ADD COLUMN auth_username varchar(255) DEFAULT NULL,
+ADD COLUMN auth_password varchar(1024) DEFAULT NULL,
+ADD COLUMN required_capabilities varchar(1024) DEFAULT NULL;
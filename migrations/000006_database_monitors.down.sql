@@ -0,0 +1,3 @@
+ALTER TABLE monitor
+DROP COLUMN expected_result,
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3') NOT NULL DEFAULT 'http';
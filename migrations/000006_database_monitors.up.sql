@@ -0,0 +1,3 @@
+ALTER TABLE monitor
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3','mysql','postgres','redis') NOT NULL DEFAULT 'http',
+ADD COLUMN expected_result varchar(255) DEFAULT NULL;
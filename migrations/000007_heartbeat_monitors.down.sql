@@ -0,0 +1,8 @@
+ALTER TABLE monitor
+DROP KEY uniq_monitor_heartbeat_token,
+DROP COLUMN last_start_at,
+DROP COLUMN last_ping_at,
+DROP COLUMN heartbeat_state,
+DROP COLUMN grace_seconds,
+DROP COLUMN heartbeat_token,
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3','mysql','postgres','redis') NOT NULL DEFAULT 'http';
@@ -0,0 +1,8 @@
+ALTER TABLE monitor
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3','mysql','postgres','redis','heartbeat') NOT NULL DEFAULT 'http',
+ADD COLUMN heartbeat_token varchar(64) DEFAULT NULL,
+ADD COLUMN grace_seconds int NOT NULL DEFAULT 0,
+ADD COLUMN heartbeat_state enum('new','up','down') NOT NULL DEFAULT 'new',
+ADD COLUMN last_ping_at datetime(3) DEFAULT NULL,
+ADD COLUMN last_start_at datetime(3) DEFAULT NULL,
+ADD UNIQUE KEY uniq_monitor_heartbeat_token (heartbeat_token);
@@ -0,0 +1,3 @@
+ALTER TABLE monitor
+DROP COLUMN payload_encoding,
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3','mysql','postgres','redis','heartbeat') NOT NULL DEFAULT 'http';
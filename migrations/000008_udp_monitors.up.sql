@@ -0,0 +1,3 @@
+ALTER TABLE monitor
+MODIFY monitor_type enum('http','websocket','smtp','imap','pop3','mysql','postgres','redis','heartbeat','udp') NOT NULL DEFAULT 'http',
+ADD COLUMN payload_encoding enum('text','hex') NOT NULL DEFAULT 'text';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `monitor_locations`;
+
+DROP TABLE IF EXISTS `locations`;
@@ -0,0 +1,21 @@
+CREATE TABLE `locations` (
+  `loc_id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(64) NOT NULL,
+  `last_agent_id` varchar(64) DEFAULT NULL,
+  `last_hostname` varchar(255) DEFAULT NULL,
+  `registered_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_seen_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`loc_id`),
+  UNIQUE KEY `uniq_locations_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `monitor_locations` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `monitor_id` bigint NOT NULL,
+  `loc_id` int NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_monitor_locations` (`monitor_id`, `loc_id`),
+  KEY `loc_id` (`loc_id`),
+  CONSTRAINT `monitor_locations_ibfk_1` FOREIGN KEY (`monitor_id`) REFERENCES `monitor` (`monitor_id`),
+  CONSTRAINT `monitor_locations_ibfk_2` FOREIGN KEY (`loc_id`) REFERENCES `locations` (`loc_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `monitor_verdicts`;
+
+ALTER TABLE results
+DROP KEY idx_results_monitor_cycle,
+DROP COLUMN cycle_id;
+
+ALTER TABLE monitor
+DROP COLUMN quorum_failures;
@@ -0,0 +1,23 @@
+ALTER TABLE monitor
+ADD COLUMN quorum_failures int NOT NULL DEFAULT 1;
+
+ALTER TABLE results
+ADD COLUMN cycle_id varchar(32) DEFAULT NULL,
+ADD KEY idx_results_monitor_cycle (monitor_id, cycle_id);
+
+CREATE TABLE `monitor_verdicts` (
+  `verdict_id` bigint NOT NULL AUTO_INCREMENT,
+  `monitor_id` bigint NOT NULL,
+  `cycle_id` varchar(32) NOT NULL,
+  `status` enum('DOWN','UP') NOT NULL,
+  `failed_locations` int NOT NULL,
+  `reported_locations` int NOT NULL,
+  `total_locations` int NOT NULL,
+  `quorum` int NOT NULL,
+  `reason` text,
+  `decided_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`verdict_id`),
+  UNIQUE KEY `uniq_monitor_verdicts_cycle` (`monitor_id`, `cycle_id`),
+  KEY `idx_monitor_verdicts_time` (`monitor_id`, `decided_at`),
+  CONSTRAINT `monitor_verdicts_ibfk_1` FOREIGN KEY (`monitor_id`) REFERENCES `monitor` (`monitor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
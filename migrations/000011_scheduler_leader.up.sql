@@ -0,0 +1,8 @@
+CREATE TABLE `scheduler_leader` (
+  `id` tinyint NOT NULL DEFAULT 1,
+  `instance_id` varchar(64) NOT NULL,
+  `hostname` varchar(255) DEFAULT NULL,
+  `acquired_at` datetime(3) NOT NULL,
+  `renewed_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
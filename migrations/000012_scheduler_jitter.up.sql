@@ -0,0 +1,2 @@
+ALTER TABLE monitor
+ADD COLUMN jitter_percent int NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE monitor
+DROP COLUMN off_peak_frequency_seconds,
+DROP COLUMN window_end,
+DROP COLUMN window_start,
+DROP COLUMN window_days,
+DROP COLUMN timezone,
+DROP COLUMN cron_expression;
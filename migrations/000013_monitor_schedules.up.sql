@@ -0,0 +1,7 @@
+ALTER TABLE monitor
+ADD COLUMN cron_expression varchar(255) DEFAULT NULL,
+ADD COLUMN timezone varchar(64) NOT NULL DEFAULT 'UTC',
+ADD COLUMN window_days varchar(32) DEFAULT NULL,
+ADD COLUMN window_start char(5) DEFAULT NULL,
+ADD COLUMN window_end char(5) DEFAULT NULL,
+ADD COLUMN off_peak_frequency_seconds int DEFAULT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE results
+DROP COLUMN in_maintenance;
+
+DROP TABLE IF EXISTS `maintenance_window_tags`;
+DROP TABLE IF EXISTS `maintenance_window_monitors`;
+DROP TABLE IF EXISTS `maintenance_windows`;
+DROP TABLE IF EXISTS `monitor_tags`;
@@ -0,0 +1,48 @@
+CREATE TABLE `monitor_tags` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `monitor_id` bigint NOT NULL,
+  `tag_key` varchar(64) NOT NULL,
+  `tag_value` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_monitor_tags` (`monitor_id`, `tag_key`),
+  KEY `idx_monitor_tags_tag` (`tag_key`, `tag_value`),
+  CONSTRAINT `monitor_tags_ibfk_1` FOREIGN KEY (`monitor_id`) REFERENCES `monitor` (`monitor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `maintenance_windows` (
+  `window_id` bigint NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `mode` enum('pause','suppress') NOT NULL DEFAULT 'suppress',
+  `starts_at` datetime NOT NULL,
+  `ends_at` datetime DEFAULT NULL,
+  `cron_expression` varchar(255) DEFAULT NULL,
+  `duration_seconds` int DEFAULT NULL,
+  `timezone` varchar(64) NOT NULL DEFAULT 'UTC',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`window_id`),
+  KEY `idx_maintenance_windows_range` (`starts_at`, `ends_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `maintenance_window_monitors` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `window_id` bigint NOT NULL,
+  `monitor_id` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_maintenance_window_monitors` (`window_id`, `monitor_id`),
+  KEY `monitor_id` (`monitor_id`),
+  CONSTRAINT `maintenance_window_monitors_ibfk_1` FOREIGN KEY (`window_id`) REFERENCES `maintenance_windows` (`window_id`) ON DELETE CASCADE,
+  CONSTRAINT `maintenance_window_monitors_ibfk_2` FOREIGN KEY (`monitor_id`) REFERENCES `monitor` (`monitor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `maintenance_window_tags` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `window_id` bigint NOT NULL,
+  `tag_key` varchar(64) NOT NULL,
+  `tag_value` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_maintenance_window_tags` (`window_id`, `tag_key`, `tag_value`),
+  CONSTRAINT `maintenance_window_tags_ibfk_1` FOREIGN KEY (`window_id`) REFERENCES `maintenance_windows` (`window_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ALTER TABLE results
+ADD COLUMN in_maintenance tinyint(1) NOT NULL DEFAULT 0;
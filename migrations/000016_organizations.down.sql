@@ -0,0 +1,20 @@
+ALTER TABLE api_keys
+DROP KEY `idx_api_keys_org`,
+DROP COLUMN user_id,
+DROP COLUMN org_id;
+
+ALTER TABLE maintenance_windows
+DROP KEY `idx_maintenance_windows_org`,
+DROP COLUMN org_id;
+
+ALTER TABLE results
+DROP KEY `idx_results_org_monitor`,
+DROP COLUMN org_id;
+
+ALTER TABLE monitor
+DROP KEY `idx_monitor_org`,
+DROP COLUMN org_id;
+
+DROP TABLE IF EXISTS `memberships`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `organizations`;
@@ -0,0 +1,55 @@
+CREATE TABLE `organizations` (
+  `org_id` bigint NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `max_monitors` int NOT NULL DEFAULT 0,
+  `min_frequency_seconds` int NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`org_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT INTO organizations (org_id, name) VALUES (1, 'Default');
+
+CREATE TABLE `users` (
+  `user_id` bigint NOT NULL AUTO_INCREMENT,
+  `email` varchar(255) NOT NULL,
+  `name` varchar(255) NOT NULL DEFAULT '',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`),
+  UNIQUE KEY `uniq_users_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `memberships` (
+  `membership_id` bigint NOT NULL AUTO_INCREMENT,
+  `org_id` bigint NOT NULL,
+  `user_id` bigint NOT NULL,
+  `role` enum('owner','editor','viewer') NOT NULL DEFAULT 'viewer',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`membership_id`),
+  UNIQUE KEY `uniq_memberships_org_user` (`org_id`, `user_id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `memberships_ibfk_1` FOREIGN KEY (`org_id`) REFERENCES `organizations` (`org_id`),
+  CONSTRAINT `memberships_ibfk_2` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Existing rows belong to the default organization; new rows must name
+-- their organization explicitly.
+ALTER TABLE monitor
+ADD COLUMN org_id bigint NOT NULL DEFAULT 1,
+ADD KEY `idx_monitor_org` (`org_id`);
+ALTER TABLE monitor ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE results
+ADD COLUMN org_id bigint NOT NULL DEFAULT 1,
+ADD KEY `idx_results_org_monitor` (`org_id`, `monitor_id`, `created_at`);
+ALTER TABLE results ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE maintenance_windows
+ADD COLUMN org_id bigint NOT NULL DEFAULT 1,
+ADD KEY `idx_maintenance_windows_org` (`org_id`);
+ALTER TABLE maintenance_windows ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE api_keys
+ADD COLUMN org_id bigint NOT NULL DEFAULT 1,
+ADD COLUMN user_id bigint DEFAULT NULL,
+ADD KEY `idx_api_keys_org` (`org_id`);
+ALTER TABLE api_keys ALTER COLUMN org_id DROP DEFAULT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `monitor_revisions`;
+DROP TABLE IF EXISTS `audit_log`;
@@ -0,0 +1,27 @@
+CREATE TABLE `audit_log` (
+  `audit_id` bigint NOT NULL AUTO_INCREMENT,
+  `org_id` bigint NOT NULL,
+  `actor_key_id` bigint DEFAULT NULL,
+  `actor_user_id` bigint DEFAULT NULL,
+  `actor_name` varchar(255) NOT NULL,
+  `action` varchar(64) NOT NULL,
+  `resource_type` varchar(64) NOT NULL,
+  `resource_id` bigint NOT NULL,
+  `changes` json NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`audit_id`),
+  KEY `idx_audit_log_org` (`org_id`, `audit_id`),
+  KEY `idx_audit_log_resource` (`org_id`, `resource_type`, `resource_id`, `audit_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `monitor_revisions` (
+  `monitor_id` bigint NOT NULL,
+  `revision` int NOT NULL,
+  `config` json NOT NULL,
+  `actor_key_id` bigint DEFAULT NULL,
+  `actor_user_id` bigint DEFAULT NULL,
+  `actor_name` varchar(255) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`monitor_id`, `revision`),
+  CONSTRAINT `monitor_revisions_ibfk_1` FOREIGN KEY (`monitor_id`) REFERENCES `monitor` (`monitor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
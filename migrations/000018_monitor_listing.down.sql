@@ -0,0 +1,7 @@
+ALTER TABLE monitor
+DROP KEY `idx_monitor_org_name`,
+DROP KEY `idx_monitor_org_status`,
+DROP KEY `idx_monitor_org_group`,
+DROP COLUMN last_checked_at,
+DROP COLUMN current_status,
+DROP COLUMN group_path;
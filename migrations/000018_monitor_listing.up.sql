@@ -0,0 +1,21 @@
+ALTER TABLE monitor
+ADD COLUMN group_path varchar(255) NOT NULL DEFAULT '',
+ADD COLUMN current_status enum('DOWN','UP') DEFAULT NULL,
+ADD COLUMN last_checked_at datetime DEFAULT NULL,
+ADD KEY `idx_monitor_org_group` (`org_id`, `group_path`),
+ADD KEY `idx_monitor_org_status` (`org_id`, `current_status`),
+ADD KEY `idx_monitor_org_name` (`org_id`, `monitor_name`);
+
+UPDATE monitor m
+JOIN (
+  SELECT monitor_id, MAX(created_at) AS last_checked_at
+  FROM results
+  GROUP BY monitor_id
+) r ON r.monitor_id = m.monitor_id
+SET m.last_checked_at = r.last_checked_at;
+
+UPDATE monitor m
+JOIN results r ON r.result_id = (
+  SELECT MAX(result_id) FROM results WHERE monitor_id = m.monitor_id AND in_maintenance = 0
+)
+SET m.current_status = r.status;
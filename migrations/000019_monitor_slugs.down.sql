@@ -0,0 +1,3 @@
+ALTER TABLE monitor
+DROP KEY `uniq_monitor_org_slug`,
+DROP COLUMN slug;
@@ -0,0 +1,15 @@
+ALTER TABLE monitor
+ADD COLUMN slug varchar(100) DEFAULT NULL;
+
+-- Existing monitors get a slug from their name, suffixed with their id so
+-- that it is unique.
+UPDATE monitor
+SET slug = CONCAT(
+  COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(LOWER(REGEXP_REPLACE(monitor_name, '[^A-Za-z0-9]+', '-')), 80)), ''), 'monitor'),
+  '-',
+  monitor_id
+);
+
+ALTER TABLE monitor
+MODIFY COLUMN slug varchar(100) NOT NULL,
+ADD UNIQUE KEY `uniq_monitor_org_slug` (`org_id`, `slug`);
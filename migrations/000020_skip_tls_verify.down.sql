@@ -0,0 +1,2 @@
+ALTER TABLE monitor
+DROP COLUMN skip_tls_verify;
@@ -0,0 +1,2 @@
+ALTER TABLE monitor
+ADD COLUMN skip_tls_verify tinyint(1) NOT NULL DEFAULT 0;
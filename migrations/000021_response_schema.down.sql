@@ -0,0 +1,2 @@
+ALTER TABLE monitor
+DROP COLUMN response_schema;